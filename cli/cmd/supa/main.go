@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 	"github.com/supabase/supabase-dx/cli/internal/commands"
@@ -20,6 +24,9 @@ func main() {
 		Use:     "supa",
 		Short:   "Supabase DX CLI - experimental developer experience tools",
 		Version: version,
+		// Errors are printed once, below; usage only on --help
+		SilenceErrors: true,
+		SilenceUsage:  true,
 	}
 
 	// Global flags
@@ -29,12 +36,32 @@ func main() {
 
 	// Add commands
 	rootCmd.AddCommand(commands.NewLoginCmd(&jsonOut))
+	rootCmd.AddCommand(commands.NewLogoutCmd(&jsonOut))
+	rootCmd.AddCommand(commands.NewAccountsCmd(&jsonOut))
 	rootCmd.AddCommand(commands.NewProjectsCmd(&jsonOut))
 	rootCmd.AddCommand(commands.NewPullCmd(&profile, &dryRun, &jsonOut))
 	rootCmd.AddCommand(commands.NewPushCmd(&profile, &dryRun, &jsonOut))
+	rootCmd.AddCommand(commands.NewMergeCmd(&profile, &dryRun, &jsonOut))
+	rootCmd.AddCommand(commands.NewBranchesCmd(&profile, &dryRun, &jsonOut))
 	rootCmd.AddCommand(commands.NewWatchCmd(&profile, &jsonOut))
+	rootCmd.AddCommand(commands.NewStatusCmd(&profile, &jsonOut))
+	rootCmd.AddCommand(commands.NewMigrationsCmd(&profile, &jsonOut))
+	rootCmd.AddCommand(commands.NewLintCmd(&profile, &jsonOut))
+	rootCmd.AddCommand(commands.NewSecretsCmd(&profile, &dryRun, &jsonOut))
+	rootCmd.AddCommand(commands.NewDaemonCmd())
+	rootCmd.AddCommand(commands.NewLSPCmd(&profile))
 
-	if err := rootCmd.Execute(); err != nil {
+	// Cancel in-flight requests on Ctrl+C / SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		stop()
+		// Already reported by the command (e.g. as JSON)
+		var exitErr *commands.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
@@ -2,10 +2,14 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"sync"
 	"time"
 )
 
@@ -19,6 +23,13 @@ type Client struct {
 	BaseURL    string
 	HTTPClient *http.Client
 	Token      string
+	Retry      RetryPolicy
+	// OAuth enables automatic token rotation when the API returns 401.
+	// Leave nil for personal access tokens.
+	OAuth *OAuthSession
+
+	mu         sync.Mutex
+	refreshing *tokenRefresh // token rotation in flight, guarded by mu
 }
 
 // NewClient creates a new Management API client
@@ -29,6 +40,7 @@ func NewClient(token string) *Client {
 			Timeout: 30 * time.Second,
 		},
 		Token: token,
+		Retry: DefaultRetryPolicy(),
 	}
 }
 
@@ -57,8 +69,8 @@ type Database struct {
 }
 
 // ListProjects returns all projects accessible to the authenticated user
-func (c *Client) ListProjects() ([]Project, error) {
-	resp, err := c.doRequest("GET", "/v1/projects", nil)
+func (c *Client) ListProjects(ctx context.Context) ([]Project, error) {
+	resp, err := c.doRequest(ctx, "GET", "/v1/projects", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -73,8 +85,8 @@ func (c *Client) ListProjects() ([]Project, error) {
 }
 
 // GetProject returns a specific project by ref
-func (c *Client) GetProject(projectRef string) (*Project, error) {
-	resp, err := c.doRequest("GET", fmt.Sprintf("/v1/projects/%s", projectRef), nil)
+func (c *Client) GetProject(ctx context.Context, projectRef string) (*Project, error) {
+	resp, err := c.doRequest(ctx, "GET", fmt.Sprintf("/v1/projects/%s", projectRef), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -107,8 +119,8 @@ type Branch struct {
 }
 
 // ListBranches returns all branches for a project
-func (c *Client) ListBranches(projectRef string) ([]Branch, error) {
-	resp, err := c.doRequest("GET", fmt.Sprintf("/v1/projects/%s/branches", projectRef), nil)
+func (c *Client) ListBranches(ctx context.Context, projectRef string) ([]Branch, error) {
+	resp, err := c.doRequest(ctx, "GET", fmt.Sprintf("/v1/projects/%s/branches", projectRef), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -123,8 +135,8 @@ func (c *Client) ListBranches(projectRef string) ([]Branch, error) {
 }
 
 // GetBranch returns a specific branch by name
-func (c *Client) GetBranch(projectRef, branchName string) (*Branch, error) {
-	resp, err := c.doRequest("GET", fmt.Sprintf("/v1/projects/%s/branches/%s", projectRef, branchName), nil)
+func (c *Client) GetBranch(ctx context.Context, projectRef, branchName string) (*Branch, error) {
+	resp, err := c.doRequest(ctx, "GET", fmt.Sprintf("/v1/projects/%s/branches/%s", projectRef, branchName), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -146,8 +158,8 @@ type CreateBranchRequest struct {
 }
 
 // CreateBranch creates a new database branch
-func (c *Client) CreateBranch(projectRef string, req CreateBranchRequest) (*Branch, error) {
-	resp, err := c.doRequest("POST", fmt.Sprintf("/v1/projects/%s/branches", projectRef), req)
+func (c *Client) CreateBranch(ctx context.Context, projectRef string, req CreateBranchRequest) (*Branch, error) {
+	resp, err := c.doRequest(ctx, "POST", fmt.Sprintf("/v1/projects/%s/branches", projectRef), req)
 	if err != nil {
 		return nil, err
 	}
@@ -162,8 +174,8 @@ func (c *Client) CreateBranch(projectRef string, req CreateBranchRequest) (*Bran
 }
 
 // DeleteBranch deletes a database branch
-func (c *Client) DeleteBranch(branchRef string) error {
-	resp, err := c.doRequest("DELETE", fmt.Sprintf("/v1/branches/%s", branchRef), nil)
+func (c *Client) DeleteBranch(ctx context.Context, branchRef string) error {
+	resp, err := c.doRequest(ctx, "DELETE", fmt.Sprintf("/v1/branches/%s", branchRef), nil)
 	if err != nil {
 		return err
 	}
@@ -171,6 +183,91 @@ func (c *Client) DeleteBranch(branchRef string) error {
 	return nil
 }
 
+// Branch statuses reported while a branch's workflow runs
+const (
+	BranchCreatingProject   = "CREATING_PROJECT"
+	BranchRunningMigrations = "RUNNING_MIGRATIONS"
+	BranchMigrationsPassed  = "MIGRATIONS_PASSED"
+	BranchMigrationsFailed  = "MIGRATIONS_FAILED"
+	BranchFunctionsDeployed = "FUNCTIONS_DEPLOYED"
+	BranchFunctionsFailed   = "FUNCTIONS_FAILED"
+)
+
+// BranchSettled reports whether a branch status is final, and if so whether
+// the last operation failed
+func BranchSettled(status string) (settled, failed bool) {
+	switch status {
+	case BranchMigrationsPassed, BranchFunctionsDeployed:
+		return true, false
+	case BranchMigrationsFailed, BranchFunctionsFailed:
+		return true, true
+	}
+	return false, false
+}
+
+// GetBranchByID returns a branch by its ID or project ref
+func (c *Client) GetBranchByID(ctx context.Context, branchID string) (*Branch, error) {
+	resp, err := c.doRequest(ctx, "GET", fmt.Sprintf("/v1/branches/%s", branchID), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var branch Branch
+	if err := json.NewDecoder(resp.Body).Decode(&branch); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &branch, nil
+}
+
+// BranchActionRequest is the request body for merge, push and reset. An
+// empty MigrationVersion means all migrations.
+type BranchActionRequest struct {
+	MigrationVersion string `json:"migration_version,omitempty"`
+}
+
+// BranchActionResponse is returned when a branch operation is started
+type BranchActionResponse struct {
+	WorkflowRunID string `json:"workflow_run_id"`
+	Message       string `json:"message"`
+}
+
+// MergeBranch merges a branch's migrations and functions into its parent
+func (c *Client) MergeBranch(ctx context.Context, branchID string, req BranchActionRequest) (*BranchActionResponse, error) {
+	return c.branchAction(ctx, branchID, "merge", req)
+}
+
+// PushBranch brings a branch up to date with its parent's migrations
+func (c *Client) PushBranch(ctx context.Context, branchID string, req BranchActionRequest) (*BranchActionResponse, error) {
+	return c.branchAction(ctx, branchID, "push", req)
+}
+
+// ResetBranch discards a branch's changes, resetting it to its parent
+func (c *Client) ResetBranch(ctx context.Context, branchID string, req BranchActionRequest) (*BranchActionResponse, error) {
+	return c.branchAction(ctx, branchID, "reset", req)
+}
+
+// RestoreBranch cancels the scheduled deletion of a branch
+func (c *Client) RestoreBranch(ctx context.Context, branchID string) (*BranchActionResponse, error) {
+	return c.branchAction(ctx, branchID, "restore", BranchActionRequest{})
+}
+
+func (c *Client) branchAction(ctx context.Context, branchID, action string, req BranchActionRequest) (*BranchActionResponse, error) {
+	resp, err := c.doRequest(ctx, "POST", fmt.Sprintf("/v1/branches/%s/%s", branchID, action), req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result BranchActionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &result, nil
+}
+
 // =============================================================================
 // TypeScript Types
 // =============================================================================
@@ -181,13 +278,13 @@ type TypescriptResponse struct {
 }
 
 // GetTypescriptTypes generates TypeScript types for the project schema
-func (c *Client) GetTypescriptTypes(projectRef string, schemas string) (*TypescriptResponse, error) {
+func (c *Client) GetTypescriptTypes(ctx context.Context, projectRef string, schemas string) (*TypescriptResponse, error) {
 	path := fmt.Sprintf("/v1/projects/%s/types/typescript", projectRef)
 	if schemas != "" {
 		path += "?included_schemas=" + schemas
 	}
 
-	resp, err := c.doRequest("GET", path, nil)
+	resp, err := c.doRequest(ctx, "GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -207,21 +304,23 @@ func (c *Client) GetTypescriptTypes(projectRef string, schemas string) (*Typescr
 
 // Function represents a Supabase Edge Function
 type Function struct {
-	ID            string `json:"id"`
-	Slug          string `json:"slug"`
-	Name          string `json:"name"`
-	Status        string `json:"status"`
-	Version       int    `json:"version"`
-	CreatedAt     int64  `json:"created_at"`
-	UpdatedAt     int64  `json:"updated_at"`
-	VerifyJWT     bool   `json:"verify_jwt"`
-	ImportMap     bool   `json:"import_map"`
+	ID             string `json:"id"`
+	Slug           string `json:"slug"`
+	Name           string `json:"name"`
+	Status         string `json:"status"`
+	Version        int    `json:"version"`
+	CreatedAt      int64  `json:"created_at"`
+	UpdatedAt      int64  `json:"updated_at"`
+	VerifyJWT      bool   `json:"verify_jwt"`
+	ImportMap      bool   `json:"import_map"`
 	EntrypointPath string `json:"entrypoint_path,omitempty"`
+	ImportMapPath  string `json:"import_map_path,omitempty"`
+	EzbrSha256     string `json:"ezbr_sha256,omitempty"`
 }
 
 // ListFunctions returns all edge functions for a project
-func (c *Client) ListFunctions(projectRef string) ([]Function, error) {
-	resp, err := c.doRequest("GET", fmt.Sprintf("/v1/projects/%s/functions", projectRef), nil)
+func (c *Client) ListFunctions(ctx context.Context, projectRef string) ([]Function, error) {
+	resp, err := c.doRequest(ctx, "GET", fmt.Sprintf("/v1/projects/%s/functions", projectRef), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -236,8 +335,65 @@ func (c *Client) ListFunctions(projectRef string) ([]Function, error) {
 }
 
 // GetFunction returns a specific edge function
-func (c *Client) GetFunction(projectRef, functionSlug string) (*Function, error) {
-	resp, err := c.doRequest("GET", fmt.Sprintf("/v1/projects/%s/functions/%s", projectRef, functionSlug), nil)
+func (c *Client) GetFunction(ctx context.Context, projectRef, functionSlug string) (*Function, error) {
+	resp, err := c.doRequest(ctx, "GET", fmt.Sprintf("/v1/projects/%s/functions/%s", projectRef, functionSlug), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var fn Function
+	if err := json.NewDecoder(resp.Body).Decode(&fn); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &fn, nil
+}
+
+// DeployFunctionMetadata describes how to run a deployed function. Paths
+// refer to the names of the uploaded files.
+type DeployFunctionMetadata struct {
+	EntrypointPath string   `json:"entrypoint_path"`
+	ImportMapPath  string   `json:"import_map_path,omitempty"`
+	StaticPatterns []string `json:"static_patterns,omitempty"`
+	VerifyJWT      *bool    `json:"verify_jwt,omitempty"`
+	Name           string   `json:"name,omitempty"`
+}
+
+// FunctionFile is a source file uploaded with a deploy
+type FunctionFile struct {
+	Name    string
+	Content []byte
+}
+
+// DeployFunction uploads function source files and deploys them, creating
+// the function if it doesn't exist. The server bundles the files.
+func (c *Client) DeployFunction(ctx context.Context, projectRef, slug string, metadata DeployFunctionMetadata, files []FunctionFile) (*Function, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+
+	meta, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+	if err := w.WriteField("metadata", string(meta)); err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		part, err := w.CreateFormFile("file", f.Name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := part.Write(f.Content); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	path := fmt.Sprintf("/v1/projects/%s/functions/deploy?slug=%s", projectRef, url.QueryEscape(slug))
+	resp, err := c.send(ctx, "POST", path, w.FormDataContentType(), body.Bytes())
 	if err != nil {
 		return nil, err
 	}
@@ -251,9 +407,26 @@ func (c *Client) GetFunction(projectRef, functionSlug string) (*Function, error)
 	return &fn, nil
 }
 
+// GetFunctionBody downloads a deployed function's source. The body is either
+// multipart/form-data or an eszip archive, as told by the content type.
+func (c *Client) GetFunctionBody(ctx context.Context, projectRef, functionSlug string) (string, []byte, error) {
+	resp, err := c.doRequest(ctx, "GET", fmt.Sprintf("/v1/projects/%s/functions/%s/body", projectRef, functionSlug), nil)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return resp.Header.Get("Content-Type"), body, nil
+}
+
 // DeleteFunction deletes an edge function
-func (c *Client) DeleteFunction(projectRef, functionSlug string) error {
-	resp, err := c.doRequest("DELETE", fmt.Sprintf("/v1/projects/%s/functions/%s", projectRef, functionSlug), nil)
+func (c *Client) DeleteFunction(ctx context.Context, projectRef, functionSlug string) error {
+	resp, err := c.doRequest(ctx, "DELETE", fmt.Sprintf("/v1/projects/%s/functions/%s", projectRef, functionSlug), nil)
 	if err != nil {
 		return err
 	}
@@ -273,8 +446,8 @@ type Secret struct {
 }
 
 // ListSecrets returns all secrets for a project
-func (c *Client) ListSecrets(projectRef string) ([]Secret, error) {
-	resp, err := c.doRequest("GET", fmt.Sprintf("/v1/projects/%s/secrets", projectRef), nil)
+func (c *Client) ListSecrets(ctx context.Context, projectRef string) ([]Secret, error) {
+	resp, err := c.doRequest(ctx, "GET", fmt.Sprintf("/v1/projects/%s/secrets", projectRef), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -289,8 +462,8 @@ func (c *Client) ListSecrets(projectRef string) ([]Secret, error) {
 }
 
 // CreateSecrets creates multiple secrets
-func (c *Client) CreateSecrets(projectRef string, secrets []Secret) error {
-	resp, err := c.doRequest("POST", fmt.Sprintf("/v1/projects/%s/secrets", projectRef), secrets)
+func (c *Client) CreateSecrets(ctx context.Context, projectRef string, secrets []Secret) error {
+	resp, err := c.doRequest(ctx, "POST", fmt.Sprintf("/v1/projects/%s/secrets", projectRef), secrets)
 	if err != nil {
 		return err
 	}
@@ -299,8 +472,8 @@ func (c *Client) CreateSecrets(projectRef string, secrets []Secret) error {
 }
 
 // DeleteSecrets deletes secrets by name
-func (c *Client) DeleteSecrets(projectRef string, names []string) error {
-	resp, err := c.doRequest("DELETE", fmt.Sprintf("/v1/projects/%s/secrets", projectRef), names)
+func (c *Client) DeleteSecrets(ctx context.Context, projectRef string, names []string) error {
+	resp, err := c.doRequest(ctx, "DELETE", fmt.Sprintf("/v1/projects/%s/secrets", projectRef), names)
 	if err != nil {
 		return err
 	}
@@ -319,8 +492,8 @@ type Migration struct {
 }
 
 // ListMigrations returns applied migrations for a project
-func (c *Client) ListMigrations(projectRef string) ([]Migration, error) {
-	resp, err := c.doRequest("GET", fmt.Sprintf("/v1/projects/%s/database/migrations", projectRef), nil)
+func (c *Client) ListMigrations(ctx context.Context, projectRef string) ([]Migration, error) {
+	resp, err := c.doRequest(ctx, "GET", fmt.Sprintf("/v1/projects/%s/database/migrations", projectRef), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -334,6 +507,29 @@ func (c *Client) ListMigrations(projectRef string) ([]Migration, error) {
 	return migrations, nil
 }
 
+// MigrationDetail is an applied migration with the SQL it ran
+type MigrationDetail struct {
+	Version    string   `json:"version"`
+	Name       string   `json:"name,omitempty"`
+	Statements []string `json:"statements,omitempty"`
+}
+
+// GetMigration returns an applied migration, including its statements
+func (c *Client) GetMigration(ctx context.Context, projectRef, version string) (*MigrationDetail, error) {
+	resp, err := c.doRequest(ctx, "GET", fmt.Sprintf("/v1/projects/%s/database/migrations/%s", projectRef, version), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var migration MigrationDetail
+	if err := json.NewDecoder(resp.Body).Decode(&migration); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &migration, nil
+}
+
 // ApplyMigrationRequest is the request body for applying a migration
 type ApplyMigrationRequest struct {
 	Query    string `json:"query"`
@@ -342,8 +538,8 @@ type ApplyMigrationRequest struct {
 }
 
 // ApplyMigration applies a database migration
-func (c *Client) ApplyMigration(projectRef string, req ApplyMigrationRequest) error {
-	resp, err := c.doRequest("POST", fmt.Sprintf("/v1/projects/%s/database/migrations", projectRef), req)
+func (c *Client) ApplyMigration(ctx context.Context, projectRef string, req ApplyMigrationRequest) error {
+	resp, err := c.doRequest(ctx, "POST", fmt.Sprintf("/v1/projects/%s/database/migrations", projectRef), req)
 	if err != nil {
 		return err
 	}
@@ -361,9 +557,28 @@ type RunQueryRequest struct {
 }
 
 // RunQuery runs a SQL query against the project database
-func (c *Client) RunQuery(projectRef string, query string) (json.RawMessage, error) {
+func (c *Client) RunQuery(ctx context.Context, projectRef string, query string) (json.RawMessage, error) {
+	req := RunQueryRequest{Query: query}
+	resp, err := c.doRequest(ctx, "POST", fmt.Sprintf("/v1/projects/%s/database/query", projectRef), req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return result, nil
+}
+
+// RunReadOnlyQuery runs a SQL query as supabase_read_only_user, which can
+// read the catalog and data but change nothing. Entity references must be
+// schema qualified.
+func (c *Client) RunReadOnlyQuery(ctx context.Context, projectRef string, query string) (json.RawMessage, error) {
 	req := RunQueryRequest{Query: query}
-	resp, err := c.doRequest("POST", fmt.Sprintf("/v1/projects/%s/database/query", projectRef), req)
+	resp, err := c.doRequest(ctx, "POST", fmt.Sprintf("/v1/projects/%s/database/query/read-only", projectRef), req)
 	if err != nil {
 		return nil, err
 	}
@@ -382,13 +597,13 @@ func (c *Client) RunQuery(projectRef string, query string) (json.RawMessage, err
 // =============================================================================
 
 // GetBranchDiff returns the schema diff for a branch
-func (c *Client) GetBranchDiff(branchRef string, includedSchemas string) (string, error) {
+func (c *Client) GetBranchDiff(ctx context.Context, branchRef string, includedSchemas string) (string, error) {
 	path := fmt.Sprintf("/v1/branches/%s/diff", branchRef)
 	if includedSchemas != "" {
 		path += "?included_schemas=" + includedSchemas
 	}
 
-	resp, err := c.doRequest("GET", path, nil)
+	resp, err := c.doRequest(ctx, "GET", path, nil)
 	if err != nil {
 		return "", err
 	}
@@ -414,8 +629,8 @@ type Organization struct {
 }
 
 // ListOrganizations returns all organizations the user belongs to
-func (c *Client) ListOrganizations() ([]Organization, error) {
-	resp, err := c.doRequest("GET", "/v1/organizations", nil)
+func (c *Client) ListOrganizations(ctx context.Context) ([]Organization, error) {
+	resp, err := c.doRequest(ctx, "GET", "/v1/organizations", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -441,10 +656,10 @@ type ServiceHealth struct {
 }
 
 // GetHealth returns health status of project services
-func (c *Client) GetHealth(projectRef string, services []string) ([]ServiceHealth, error) {
+func (c *Client) GetHealth(ctx context.Context, projectRef string, services []string) ([]ServiceHealth, error) {
 	path := fmt.Sprintf("/v1/projects/%s/health?services=%s", projectRef, joinStrings(services, ","))
 
-	resp, err := c.doRequest("GET", path, nil)
+	resp, err := c.doRequest(ctx, "GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -462,37 +677,92 @@ func (c *Client) GetHealth(projectRef string, services []string) ([]ServiceHealt
 // HTTP Client
 // =============================================================================
 
-// doRequest performs an authenticated HTTP request
-func (c *Client) doRequest(method, path string, body interface{}) (*http.Response, error) {
-	var bodyReader io.Reader
+// doRequest performs an authenticated JSON request
+func (c *Client) doRequest(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	var payload []byte
 	if body != nil {
 		jsonBody, err := json.Marshal(body)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal request body: %w", err)
 		}
-		bodyReader = bytes.NewReader(jsonBody)
+		payload = jsonBody
 	}
 
-	req, err := http.NewRequest(method, c.BaseURL+path, bodyReader)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Authorization", "Bearer "+c.Token)
-	req.Header.Set("Content-Type", "application/json")
+	return c.send(ctx, method, path, "application/json", payload)
+}
 
-	resp, err := c.HTTPClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+// send performs an authenticated HTTP request. When the client holds an OAuth
+// session, a 401 triggers a token refresh and a single replay of the request.
+func (c *Client) send(ctx context.Context, method, path, contentType string, body []byte) (*http.Response, error) {
+	token := c.currentToken()
+	resp, err := c.sendWithRetry(ctx, method, path, contentType, body, token)
+	if err == nil || !IsUnauthorized(err) || !c.canRefresh() {
+		return resp, err
 	}
 
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+	if rerr := c.refresh(ctx, token); rerr != nil {
+		return nil, fmt.Errorf("%w (token refresh failed: %v)", err, rerr)
 	}
 
-	return resp, nil
+	return c.sendWithRetry(ctx, method, path, contentType, body, c.currentToken())
+}
+
+// sendWithRetry performs an HTTP request, retrying transient failures
+// according to the client's retry policy. The body is buffered so it can be
+// replayed on every attempt. An empty token sends no Authorization header.
+func (c *Client) sendWithRetry(ctx context.Context, method, path, contentType string, body []byte, token string) (*http.Response, error) {
+	policy := c.Retry
+	retryable := policy.allowsMethod(method)
+
+	for attempt := 0; ; attempt++ {
+		var bodyReader io.Reader
+		if body != nil {
+			bodyReader = bytes.NewReader(body)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, bodyReader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+
+		resp, err := c.HTTPClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if retryable && attempt < policy.MaxRetries {
+				if err := sleep(ctx, policy.backoff(attempt)); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			return nil, fmt.Errorf("request failed: %w", err)
+		}
+
+		if resp.StatusCode >= 400 {
+			respBody, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			apiErr := newError(req, resp, respBody)
+			if retryable && attempt < policy.MaxRetries && isRetryableStatus(resp.StatusCode) {
+				if err := sleep(ctx, policy.wait(apiErr.RetryAfter, attempt)); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			return nil, apiErr
+		}
+
+		return resp, nil
+	}
 }
 
 // joinStrings joins strings with a separator
@@ -1,9 +1,11 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -59,7 +61,7 @@ func TestListProjects(t *testing.T) {
 	client.BaseURL = server.URL
 
 	// Test
-	projects, err := client.ListProjects()
+	projects, err := client.ListProjects(context.Background())
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -95,7 +97,7 @@ func TestGetProject(t *testing.T) {
 	client := NewClient("test-token")
 	client.BaseURL = server.URL
 
-	project, err := client.GetProject("abcdefghijklmnopqrst")
+	project, err := client.GetProject(context.Background(), "abcdefghijklmnopqrst")
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -135,7 +137,7 @@ func TestListBranches(t *testing.T) {
 	client := NewClient("test-token")
 	client.BaseURL = server.URL
 
-	branches, err := client.ListBranches("abcdefghijklmnopqrst")
+	branches, err := client.ListBranches(context.Background(), "abcdefghijklmnopqrst")
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -173,7 +175,7 @@ func TestListFunctions(t *testing.T) {
 	client := NewClient("test-token")
 	client.BaseURL = server.URL
 
-	functions, err := client.ListFunctions("abcdefghijklmnopqrst")
+	functions, err := client.ListFunctions(context.Background(), "abcdefghijklmnopqrst")
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -205,7 +207,7 @@ func TestGetTypescriptTypes(t *testing.T) {
 	client := NewClient("test-token")
 	client.BaseURL = server.URL
 
-	types, err := client.GetTypescriptTypes("abcdefghijklmnopqrst", "public")
+	types, err := client.GetTypescriptTypes(context.Background(), "abcdefghijklmnopqrst", "public")
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -225,8 +227,206 @@ func TestAPIError(t *testing.T) {
 	client := NewClient("bad-token")
 	client.BaseURL = server.URL
 
-	_, err := client.ListProjects()
+	_, err := client.ListProjects(context.Background())
 	if err == nil {
-		t.Error("expected error for 401 response")
+		t.Fatal("expected error for 401 response")
+	}
+
+	if !IsUnauthorized(err) {
+		t.Errorf("expected IsUnauthorized, got %v", err)
+	}
+}
+
+func TestDeployFunction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/projects/ref/functions/deploy" {
+			t.Errorf("expected deploy path, got %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("slug") != "hello" {
+			t.Errorf("expected slug hello, got %q", r.URL.Query().Get("slug"))
+		}
+
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatalf("expected multipart body, got %v", err)
+		}
+
+		var meta DeployFunctionMetadata
+		if err := json.Unmarshal([]byte(r.FormValue("metadata")), &meta); err != nil {
+			t.Fatalf("failed to parse metadata: %v", err)
+		}
+		if meta.EntrypointPath != "supabase/functions/hello/index.ts" || meta.VerifyJWT == nil || *meta.VerifyJWT {
+			t.Errorf("unexpected metadata: %+v", meta)
+		}
+
+		// FileHeader.Filename drops directories, so check the raw header
+		files := r.MultipartForm.File["file"]
+		if len(files) != 2 || !strings.Contains(files[0].Header.Get("Content-Disposition"), `filename="supabase/functions/hello/index.ts"`) {
+			t.Errorf("expected 2 files with full paths, got %d", len(files))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(Function{Slug: "hello", Version: 3, EzbrSha256: "abc"})
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.BaseURL = server.URL
+
+	verifyJWT := false
+	fn, err := client.DeployFunction(context.Background(), "ref", "hello", DeployFunctionMetadata{
+		EntrypointPath: "supabase/functions/hello/index.ts",
+		VerifyJWT:      &verifyJWT,
+	}, []FunctionFile{
+		{Name: "supabase/functions/hello/index.ts", Content: []byte("Deno.serve(() => new Response())")},
+		{Name: "supabase/functions/_shared/cors.ts", Content: []byte("export {}")},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if fn.Version != 3 || fn.EzbrSha256 != "abc" {
+		t.Errorf("unexpected function: %+v", fn)
+	}
+}
+
+func TestGetFunctionBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/projects/ref/functions/hello/body" {
+			t.Errorf("expected body path, got %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write([]byte("ESZIP2.2"))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.BaseURL = server.URL
+
+	contentType, body, err := client.GetFunctionBody(context.Background(), "ref", "hello")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if contentType != "application/octet-stream" || string(body) != "ESZIP2.2" {
+		t.Errorf("unexpected body %q (%s)", body, contentType)
+	}
+}
+
+func TestMergeBranch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/v1/branches/branch-id/merge" {
+			t.Errorf("expected POST /v1/branches/branch-id/merge, got %s %s", r.Method, r.URL.Path)
+		}
+		var req BranchActionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.MigrationVersion != "20240101000000" {
+			t.Errorf("expected migration version in body, got %+v", req)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(BranchActionResponse{WorkflowRunID: "run-1"})
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.BaseURL = server.URL
+
+	resp, err := client.MergeBranch(context.Background(), "branch-id", BranchActionRequest{MigrationVersion: "20240101000000"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if resp.WorkflowRunID != "run-1" {
+		t.Errorf("expected workflow run id, got %+v", resp)
+	}
+}
+
+func TestBranchSettled(t *testing.T) {
+	tests := []struct {
+		status  string
+		settled bool
+		failed  bool
+	}{
+		{BranchRunningMigrations, false, false},
+		{BranchMigrationsPassed, true, false},
+		{BranchFunctionsDeployed, true, false},
+		{BranchMigrationsFailed, true, true},
+		{BranchFunctionsFailed, true, true},
+	}
+
+	for _, tt := range tests {
+		settled, failed := BranchSettled(tt.status)
+		if settled != tt.settled || failed != tt.failed {
+			t.Errorf("BranchSettled(%s) = %v, %v, want %v, %v", tt.status, settled, failed, tt.settled, tt.failed)
+		}
+	}
+}
+
+func TestGetBranchByID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/branches/branch-id" {
+			t.Errorf("expected /v1/branches/branch-id, got %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Branch{ID: "branch-id", Status: BranchMigrationsPassed})
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.BaseURL = server.URL
+
+	branch, err := client.GetBranchByID(context.Background(), "branch-id")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if branch.Status != BranchMigrationsPassed {
+		t.Errorf("expected status %s, got %s", BranchMigrationsPassed, branch.Status)
+	}
+}
+
+func TestGetMigration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/projects/test-ref/database/migrations/20240101000000" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"version":"20240101000000","name":"init","statements":["create table a ()","create table b ()"]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.BaseURL = server.URL
+
+	migration, err := client.GetMigration(context.Background(), "test-ref", "20240101000000")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if migration.Name != "init" || len(migration.Statements) != 2 {
+		t.Errorf("unexpected migration %+v", migration)
+	}
+}
+
+func TestRunReadOnlyQuery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/v1/projects/test-ref/database/query/read-only" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var req RunQueryRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Query != "select 1 as n" {
+			t.Errorf("expected the query in the body, got %q", req.Query)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"n":1}]`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.BaseURL = server.URL
+
+	rows, err := client.RunReadOnlyQuery(context.Background(), "test-ref", "select 1 as n")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if string(rows) != `[{"n":1}]` {
+		t.Errorf("unexpected rows %s", rows)
 	}
 }
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Error is returned for any non-2xx Management API response
+type Error struct {
+	StatusCode int
+	Method     string
+	Path       string
+	// Code is the machine-readable error code from the response body, if any
+	Code string
+	// Message is the human-readable message from the response body, falling
+	// back to the raw body when it isn't JSON
+	Message   string
+	RequestID string
+	// RetryAfter is the server-requested delay for 429/503 responses
+	RetryAfter time.Duration
+	Body       []byte
+}
+
+// errorBody covers the shapes the Management API uses for error payloads
+type errorBody struct {
+	Message string `json:"message"`
+	Error   string `json:"error"`
+	Msg     string `json:"msg"`
+	Code    string `json:"code"`
+}
+
+// newError builds an Error from a failed response and its already-read body
+func newError(req *http.Request, resp *http.Response, body []byte) *Error {
+	e := &Error{
+		StatusCode: resp.StatusCode,
+		Method:     req.Method,
+		Path:       req.URL.Path,
+		RequestID:  resp.Header.Get("X-Request-Id"),
+		Body:       body,
+	}
+	if e.RequestID == "" {
+		e.RequestID = resp.Header.Get("Sb-Request-Id")
+	}
+	if d, ok := retryAfter(resp.Header, time.Now()); ok {
+		e.RetryAfter = d
+	}
+
+	var parsed errorBody
+	if err := json.Unmarshal(body, &parsed); err == nil {
+		e.Code = parsed.Code
+		switch {
+		case parsed.Message != "":
+			e.Message = parsed.Message
+		case parsed.Msg != "":
+			e.Message = parsed.Msg
+		case parsed.Error != "":
+			e.Message = parsed.Error
+		}
+	}
+	if e.Message == "" {
+		e.Message = strings.TrimSpace(string(body))
+	}
+
+	return e
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s: %d %s", e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Message != "" {
+		b.WriteString(": " + e.Message)
+	}
+	if e.RequestID != "" {
+		b.WriteString(" (request id: " + e.RequestID + ")")
+	}
+	return b.String()
+}
+
+// ErrorCode returns a stable, machine-readable code for err, suitable for
+// --json output. It prefers the code sent by the API and otherwise derives
+// one from the HTTP status. Errors from other packages can supply their own
+// code by implementing ErrorCode() string. Returns "" otherwise.
+func ErrorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		if apiErr.Code != "" {
+			return apiErr.Code
+		}
+		return statusCode(apiErr.StatusCode)
+	}
+
+	var coded interface{ ErrorCode() string }
+	if errors.As(err, &coded) {
+		return coded.ErrorCode()
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "cancelled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	}
+	return ""
+}
+
+func statusCode(status int) string {
+	switch {
+	case status == http.StatusBadRequest:
+		return "bad_request"
+	case status == http.StatusUnauthorized:
+		return "unauthorized"
+	case status == http.StatusForbidden:
+		return "forbidden"
+	case status == http.StatusNotFound:
+		return "not_found"
+	case status == http.StatusConflict:
+		return "conflict"
+	case status == http.StatusTooManyRequests:
+		return "rate_limited"
+	case status >= 500:
+		return "server_error"
+	default:
+		return fmt.Sprintf("http_%d", status)
+	}
+}
+
+// StatusCode returns the HTTP status of an API error, or 0 for other errors
+func StatusCode(err error) int {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+// IsNotFound reports whether err is a 404 from the API
+func IsNotFound(err error) bool {
+	return StatusCode(err) == http.StatusNotFound
+}
+
+// IsUnauthorized reports whether err is a 401 from the API
+func IsUnauthorized(err error) bool {
+	return StatusCode(err) == http.StatusUnauthorized
+}
+
+// IsForbidden reports whether err is a 403 from the API
+func IsForbidden(err error) bool {
+	return StatusCode(err) == http.StatusForbidden
+}
+
+// IsConflict reports whether err is a 409 from the API
+func IsConflict(err error) bool {
+	return StatusCode(err) == http.StatusConflict
+}
+
+// IsRateLimited reports whether err is a 429 from the API
+func IsRateLimited(err error) bool {
+	return StatusCode(err) == http.StatusTooManyRequests
+}
+
+// IsServerError reports whether err is a 5xx from the API
+func IsServerError(err error) bool {
+	return StatusCode(err) >= 500
+}
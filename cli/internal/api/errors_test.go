@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestErrorFields(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-123")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message": "Project not found", "code": "project_not_found"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.BaseURL = server.URL
+
+	_, err := client.GetProject(context.Background(), "abcdefghijklmnopqrst")
+	if err == nil {
+		t.Fatal("expected error for 404 response")
+	}
+
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *Error, got %T", err)
+	}
+
+	if apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", apiErr.StatusCode)
+	}
+	if apiErr.Method != "GET" {
+		t.Errorf("expected method GET, got %s", apiErr.Method)
+	}
+	if apiErr.Path != "/v1/projects/abcdefghijklmnopqrst" {
+		t.Errorf("unexpected path %s", apiErr.Path)
+	}
+	if apiErr.Message != "Project not found" {
+		t.Errorf("expected parsed message, got %q", apiErr.Message)
+	}
+	if apiErr.RequestID != "req-123" {
+		t.Errorf("expected request id req-123, got %q", apiErr.RequestID)
+	}
+	if !IsNotFound(err) {
+		t.Error("expected IsNotFound")
+	}
+	if ErrorCode(err) != "project_not_found" {
+		t.Errorf("expected code from body, got %q", ErrorCode(err))
+	}
+}
+
+func TestErrorNonJSONBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("forbidden\n"))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.BaseURL = server.URL
+
+	_, err := client.ListProjects(context.Background())
+
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *Error, got %T", err)
+	}
+	if apiErr.Message != "forbidden" {
+		t.Errorf("expected raw body as message, got %q", apiErr.Message)
+	}
+	if ErrorCode(err) != "forbidden" {
+		t.Errorf("expected derived code, got %q", ErrorCode(err))
+	}
+}
+
+func TestErrorSentinels(t *testing.T) {
+	wrap := func(status int) error {
+		return fmt.Errorf("wrapped: %w", &Error{StatusCode: status})
+	}
+
+	if !IsRateLimited(wrap(http.StatusTooManyRequests)) {
+		t.Error("expected IsRateLimited through wrapping")
+	}
+	if !IsUnauthorized(wrap(http.StatusUnauthorized)) {
+		t.Error("expected IsUnauthorized")
+	}
+	if !IsServerError(wrap(http.StatusBadGateway)) {
+		t.Error("expected IsServerError")
+	}
+	if IsNotFound(errors.New("plain")) {
+		t.Error("expected plain error not to match")
+	}
+
+	tests := map[int]string{
+		http.StatusNotFound:        "not_found",
+		http.StatusTooManyRequests: "rate_limited",
+		http.StatusTeapot:          "http_418",
+	}
+	for status, want := range tests {
+		if got := ErrorCode(wrap(status)); got != want {
+			t.Errorf("ErrorCode(%d) = %q, want %q", status, got, want)
+		}
+	}
+
+	if got := ErrorCode(context.Canceled); got != "cancelled" {
+		t.Errorf("expected cancelled, got %q", got)
+	}
+
+	if got := ErrorCode(fmt.Errorf("push: %w", codedError{})); got != "custom_code" {
+		t.Errorf("expected code from wrapped error, got %q", got)
+	}
+}
+
+type codedError struct{}
+
+func (codedError) Error() string     { return "coded" }
+func (codedError) ErrorCode() string { return "custom_code" }
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// =============================================================================
+// OAuth
+// =============================================================================
+
+// OAuthApp identifies the OAuth application the CLI authenticates as
+type OAuthApp struct {
+	ClientID     string
+	ClientSecret string
+}
+
+// OAuthToken is the response from the token endpoint
+type OAuthToken struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	TokenType    string `json:"token_type"`
+}
+
+// ExpiresAt returns the absolute expiry time relative to now
+func (t *OAuthToken) ExpiresAt(now time.Time) time.Time {
+	return now.Add(time.Duration(t.ExpiresIn) * time.Second)
+}
+
+// OAuthSession holds the refresh state for a client authenticated via OAuth
+type OAuthSession struct {
+	App          OAuthApp
+	RefreshToken string
+	// OnRefresh is called after the tokens are rotated so callers can
+	// persist them; the token always carries the refresh token to keep.
+	// Its error is ignored so the request still succeeds.
+	OnRefresh func(*OAuthToken) error
+}
+
+// AuthorizeURL builds the URL the user visits to grant access. The challenge
+// is the S256 PKCE code challenge.
+func (c *Client) AuthorizeURL(app OAuthApp, redirectURI, state, challenge string) string {
+	q := url.Values{}
+	q.Set("client_id", app.ClientID)
+	q.Set("response_type", "code")
+	q.Set("redirect_uri", redirectURI)
+	q.Set("state", state)
+	q.Set("code_challenge", challenge)
+	q.Set("code_challenge_method", "S256")
+	return c.BaseURL + "/v1/oauth/authorize?" + q.Encode()
+}
+
+// ExchangeCode trades an authorization code for tokens
+func (c *Client) ExchangeCode(ctx context.Context, app OAuthApp, code, verifier, redirectURI string) (*OAuthToken, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("code_verifier", verifier)
+	form.Set("redirect_uri", redirectURI)
+	return c.requestToken(ctx, app, form)
+}
+
+// RefreshAccessToken trades a refresh token for a new token pair
+func (c *Client) RefreshAccessToken(ctx context.Context, app OAuthApp, refreshToken string) (*OAuthToken, error) {
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", refreshToken)
+	return c.requestToken(ctx, app, form)
+}
+
+func (c *Client) requestToken(ctx context.Context, app OAuthApp, form url.Values) (*OAuthToken, error) {
+	form.Set("client_id", app.ClientID)
+	if app.ClientSecret != "" {
+		form.Set("client_secret", app.ClientSecret)
+	}
+
+	resp, err := c.sendWithRetry(ctx, "POST", "/v1/oauth/token", "application/x-www-form-urlencoded", []byte(form.Encode()), "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var token OAuthToken
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &token, nil
+}
+
+// RevokeTokenRequest is the request body for revoking a refresh token
+type RevokeTokenRequest struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RevokeToken revokes a refresh token and the access tokens issued from it
+func (c *Client) RevokeToken(ctx context.Context, app OAuthApp, refreshToken string) error {
+	body, err := json.Marshal(RevokeTokenRequest{
+		ClientID:     app.ClientID,
+		ClientSecret: app.ClientSecret,
+		RefreshToken: refreshToken,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	resp, err := c.sendWithRetry(ctx, "POST", "/v1/oauth/revoke", "application/json", body, "")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// currentToken returns the access token, safe for concurrent use with refresh
+func (c *Client) currentToken() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Token
+}
+
+// canRefresh reports whether a 401 can be recovered by rotating tokens
+func (c *Client) canRefresh() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.OAuth != nil && c.OAuth.RefreshToken != ""
+}
+
+// tokenRefresh is a token rotation in flight. Requests rejected with the
+// same stale token wait on done instead of starting their own.
+type tokenRefresh struct {
+	done chan struct{}
+	err  error
+}
+
+// refresh rotates the OAuth tokens. stale is the access token that was
+// rejected; if another goroutine already rotated it, refresh is a no-op, and
+// if one is rotating it, refresh waits for that result. The client lock isn't
+// held during the token request, so other requests aren't held up by it.
+func (c *Client) refresh(ctx context.Context, stale string) error {
+	c.mu.Lock()
+	if c.Token != stale {
+		c.mu.Unlock()
+		return nil
+	}
+	if inflight := c.refreshing; inflight != nil {
+		c.mu.Unlock()
+		select {
+		case <-inflight.done:
+			return inflight.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if c.OAuth == nil || c.OAuth.RefreshToken == "" {
+		c.mu.Unlock()
+		return errors.New("no refresh token")
+	}
+	session := *c.OAuth
+	inflight := &tokenRefresh{done: make(chan struct{})}
+	c.refreshing = inflight
+	c.mu.Unlock()
+
+	token, err := c.RefreshAccessToken(ctx, session.App, session.RefreshToken)
+	if err == nil && token.RefreshToken == "" {
+		// The server kept the refresh token rather than rotating it
+		token.RefreshToken = session.RefreshToken
+	}
+
+	c.mu.Lock()
+	if err == nil {
+		c.Token = token.AccessToken
+		c.OAuth.RefreshToken = token.RefreshToken
+	}
+	c.refreshing = nil
+	c.mu.Unlock()
+
+	if err == nil && session.OnRefresh != nil {
+		_ = session.OnRefresh(token)
+	}
+
+	inflight.err = err
+	close(inflight.done)
+	return err
+}
@@ -0,0 +1,254 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExchangeCode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/oauth/token" {
+			t.Errorf("expected path /v1/oauth/token, got %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "" {
+			t.Error("expected no Authorization header on token endpoint")
+		}
+		r.ParseForm()
+		if r.PostForm.Get("grant_type") != "authorization_code" {
+			t.Errorf("unexpected grant_type %q", r.PostForm.Get("grant_type"))
+		}
+		if r.PostForm.Get("code_verifier") != "verifier" {
+			t.Errorf("unexpected code_verifier %q", r.PostForm.Get("code_verifier"))
+		}
+		if r.PostForm.Get("client_id") != "client-1" {
+			t.Errorf("unexpected client_id %q", r.PostForm.Get("client_id"))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(OAuthToken{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 3600, TokenType: "Bearer"})
+	}))
+	defer server.Close()
+
+	client := NewClient("")
+	client.BaseURL = server.URL
+
+	token, err := client.ExchangeCode(context.Background(), OAuthApp{ClientID: "client-1"}, "code", "verifier", "http://127.0.0.1/callback")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if token.AccessToken != "access" || token.RefreshToken != "refresh" {
+		t.Errorf("unexpected token %+v", token)
+	}
+}
+
+func TestRefreshOnUnauthorized(t *testing.T) {
+	var refreshed *OAuthToken
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/oauth/token":
+			r.ParseForm()
+			if r.PostForm.Get("refresh_token") != "old-refresh" {
+				t.Errorf("unexpected refresh_token %q", r.PostForm.Get("refresh_token"))
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(OAuthToken{AccessToken: "new-access", RefreshToken: "new-refresh", ExpiresIn: 3600})
+		case "/v1/projects":
+			if r.Header.Get("Authorization") != "Bearer new-access" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode([]Project{{Name: "Test Project"}})
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("expired-access")
+	client.BaseURL = server.URL
+	client.OAuth = &OAuthSession{
+		App:          OAuthApp{ClientID: "client-1"},
+		RefreshToken: "old-refresh",
+		OnRefresh: func(token *OAuthToken) error {
+			refreshed = token
+			return nil
+		},
+	}
+
+	projects, err := client.ListProjects(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(projects) != 1 {
+		t.Fatalf("expected 1 project, got %d", len(projects))
+	}
+
+	if client.Token != "new-access" || client.OAuth.RefreshToken != "new-refresh" {
+		t.Errorf("expected rotated tokens, got %s / %s", client.Token, client.OAuth.RefreshToken)
+	}
+
+	if refreshed == nil || refreshed.AccessToken != "new-access" {
+		t.Error("expected OnRefresh to receive the new token")
+	}
+}
+
+func TestOnRefreshSavesCurrentRefreshToken(t *testing.T) {
+	tests := []struct {
+		issued string // refresh token in the token response
+		saved  string
+	}{
+		{"r2", "r2"},
+		// A server that doesn't rotate leaves the old one valid
+		{"", "r1"},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/v1/oauth/token":
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(OAuthToken{AccessToken: "new-access", RefreshToken: tt.issued, ExpiresIn: 3600})
+			case "/v1/projects":
+				if r.Header.Get("Authorization") != "Bearer new-access" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode([]Project{})
+			}
+		}))
+
+		var saved string
+		client := NewClient("expired-access")
+		client.BaseURL = server.URL
+		client.OAuth = &OAuthSession{
+			App:          OAuthApp{ClientID: "client-1"},
+			RefreshToken: "r1",
+			OnRefresh: func(token *OAuthToken) error {
+				saved = token.RefreshToken
+				return nil
+			},
+		}
+
+		_, err := client.ListProjects(context.Background())
+		server.Close()
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if saved != tt.saved || client.OAuth.RefreshToken != tt.saved {
+			t.Errorf("issued %q: expected %s to be saved and kept, got %q saved and %q kept", tt.issued, tt.saved, saved, client.OAuth.RefreshToken)
+		}
+	}
+}
+
+func TestConcurrentRefreshSharesOneRequest(t *testing.T) {
+	var tokenCalls atomic.Int32
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/oauth/token":
+			if tokenCalls.Add(1) == 1 {
+				close(entered)
+			}
+			<-release
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(OAuthToken{AccessToken: "new-access", RefreshToken: "new-refresh", ExpiresIn: 3600})
+		case "/v1/projects":
+			if r.Header.Get("Authorization") != "Bearer new-access" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode([]Project{{Name: "Test Project"}})
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("expired-access")
+	client.BaseURL = server.URL
+	client.OAuth = &OAuthSession{App: OAuthApp{ClientID: "client-1"}, RefreshToken: "old-refresh"}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.ListProjects(context.Background())
+			errs <- err
+		}()
+	}
+
+	<-entered
+	// The client stays usable while the token endpoint is slow
+	token := make(chan string)
+	go func() { token <- client.currentToken() }()
+	select {
+	case <-token:
+	case <-time.After(time.Second):
+		t.Fatal("expected the client lock to be free during the refresh")
+	}
+
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	}
+	if n := tokenCalls.Load(); n != 1 {
+		t.Errorf("expected a single refresh, got %d", n)
+	}
+}
+
+func TestNoRefreshWithoutSession(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client := NewClient("pat")
+	client.BaseURL = server.URL
+
+	if _, err := client.ListProjects(context.Background()); !IsUnauthorized(err) {
+		t.Fatalf("expected unauthorized error, got %v", err)
+	}
+
+	if calls != 1 {
+		t.Errorf("expected a single request, got %d", calls)
+	}
+}
+
+func TestAuthorizeURL(t *testing.T) {
+	client := NewClient("")
+
+	raw := client.AuthorizeURL(OAuthApp{ClientID: "client-1"}, "http://127.0.0.1:5555/callback", "state-1", "challenge-1")
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("invalid URL: %v", err)
+	}
+
+	if u.Path != "/v1/oauth/authorize" {
+		t.Errorf("unexpected path %s", u.Path)
+	}
+
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") != "challenge-1" {
+		t.Errorf("expected PKCE parameters, got %s", u.RawQuery)
+	}
+	if q.Get("redirect_uri") != "http://127.0.0.1:5555/callback" {
+		t.Errorf("unexpected redirect_uri %s", q.Get("redirect_uri"))
+	}
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how the client retries transient failures
+// (network errors, 429 and 5xx responses)
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt.
+	// Zero disables retries.
+	MaxRetries int
+	// InitialBackoff is the base delay before the first retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the computed exponential delay, and any Retry-After
+	// the server asks for
+	MaxBackoff time.Duration
+	// RetryNonIdempotent allows retrying POST and PATCH requests. Only enable
+	// this when the server deduplicates requests (e.g. via Idempotency-Key).
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy returns the policy used by NewClient
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:     3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+	}
+}
+
+// NoRetry returns a policy that never retries
+func NoRetry() RetryPolicy {
+	return RetryPolicy{}
+}
+
+// allowsMethod reports whether requests with the given method may be retried
+func (p RetryPolicy) allowsMethod(method string) bool {
+	if p.MaxRetries <= 0 {
+		return false
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return p.RetryNonIdempotent
+}
+
+// backoff returns the delay before retry number attempt (zero-based), using
+// exponential growth with equal jitter
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	if p.InitialBackoff <= 0 {
+		return 0
+	}
+
+	d := p.InitialBackoff
+	for i := 0; i < attempt; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			d = p.MaxBackoff
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+
+	// Equal jitter: pick uniformly from [d/2, d] so retries from many clients
+	// don't line up while still backing off meaningfully
+	half := int64(d / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// wait returns the delay before retry number attempt: the server's
+// Retry-After if it sent one, capped at MaxBackoff, or the backoff
+func (p RetryPolicy) wait(retryAfter time.Duration, attempt int) time.Duration {
+	if retryAfter <= 0 {
+		return p.backoff(attempt)
+	}
+	if p.MaxBackoff > 0 && retryAfter > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return retryAfter
+}
+
+// isRetryableStatus reports whether an HTTP status indicates a transient failure
+func isRetryableStatus(code int) bool {
+	if code == http.StatusTooManyRequests {
+		return true
+	}
+	return code >= 500 && code != http.StatusNotImplemented
+}
+
+// retryAfter parses the Retry-After header, which is either a number of
+// seconds or an HTTP date
+func retryAfter(h http.Header, now time.Time) (time.Duration, bool) {
+	value := h.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+
+	return 0, false
+}
+
+// sleep waits for d or until the context is cancelled
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
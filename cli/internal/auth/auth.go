@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// CallbackPath is the path the loopback server listens on for the redirect
+const CallbackPath = "/callback"
+
+// PKCE holds a code verifier and its S256 challenge (RFC 7636)
+type PKCE struct {
+	Verifier  string
+	Challenge string
+}
+
+// NewPKCE generates a random code verifier and its challenge
+func NewPKCE() (*PKCE, error) {
+	verifier, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	return &PKCE{
+		Verifier:  verifier,
+		Challenge: challengeFor(verifier),
+	}, nil
+}
+
+func challengeFor(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// NewState generates a random value to bind the callback to this login attempt
+func NewState() (string, error) {
+	return randomString(16)
+}
+
+func randomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// =============================================================================
+// Loopback Callback Server
+// =============================================================================
+
+// CallbackServer receives the OAuth redirect on 127.0.0.1
+type CallbackServer struct {
+	listener net.Listener
+	server   *http.Server
+	state    string
+	results  chan callbackResult
+}
+
+type callbackResult struct {
+	code string
+	err  error
+}
+
+// StartCallbackServer listens on a random loopback port and waits for a
+// redirect carrying the given state
+func StartCallbackServer(state string) (*CallbackServer, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start callback server: %w", err)
+	}
+
+	s := &CallbackServer{
+		listener: listener,
+		state:    state,
+		results:  make(chan callbackResult, 1),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(CallbackPath, s.handleCallback)
+	s.server = &http.Server{Handler: mux}
+
+	go s.server.Serve(listener)
+
+	return s, nil
+}
+
+// RedirectURI returns the URI to register as the OAuth redirect
+func (s *CallbackServer) RedirectURI() string {
+	return "http://" + s.listener.Addr().String() + CallbackPath
+}
+
+// Wait blocks until the callback arrives or ctx is cancelled
+func (s *CallbackServer) Wait(ctx context.Context) (string, error) {
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case r := <-s.results:
+		return r.code, r.err
+	}
+}
+
+// Close shuts down the server
+func (s *CallbackServer) Close() error {
+	return s.server.Close()
+}
+
+func (s *CallbackServer) handleCallback(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	// Only the redirect for this login attempt ends the wait - a prefetch
+	// or another local process hitting the port mustn't abort the login
+	q := r.URL.Query()
+	if q.Get("state") != s.state {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, "<h1>Unexpected callback</h1><p>This request doesn't belong to the login in progress.</p>")
+		return
+	}
+
+	code, err := ParseCallback(q, s.state)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "<h1>Login failed</h1><p>%s</p>", htmlEscape(err.Error()))
+	} else {
+		fmt.Fprint(w, "<h1>Logged in</h1><p>You can close this window and return to the terminal.</p>")
+	}
+
+	select {
+	case s.results <- callbackResult{code: code, err: err}:
+	default:
+		// A result was already delivered; ignore duplicate hits
+	}
+}
+
+// ParseCallback extracts the authorization code from redirect query
+// parameters, verifying the state
+func ParseCallback(q url.Values, state string) (string, error) {
+	if e := q.Get("error"); e != "" {
+		if desc := q.Get("error_description"); desc != "" {
+			return "", fmt.Errorf("authorization denied: %s (%s)", e, desc)
+		}
+		return "", fmt.Errorf("authorization denied: %s", e)
+	}
+
+	if q.Get("state") != state {
+		return "", errors.New("state mismatch - the callback does not belong to this login attempt")
+	}
+
+	code := q.Get("code")
+	if code == "" {
+		return "", errors.New("callback is missing the authorization code")
+	}
+
+	return code, nil
+}
+
+// ParseCallbackURL is ParseCallback for a full redirect URL pasted by the user
+func ParseCallbackURL(raw, state string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", fmt.Errorf("invalid callback URL: %w", err)
+	}
+	return ParseCallback(u.Query(), state)
+}
+
+func htmlEscape(s string) string {
+	r := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+	return r.Replace(s)
+}
+
+// =============================================================================
+// Browser
+// =============================================================================
+
+// OpenBrowser opens url in the user's default browser
+func OpenBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
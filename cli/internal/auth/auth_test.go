@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestPKCEChallenge(t *testing.T) {
+	// Test vector from RFC 7636 Appendix B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	if got := challengeFor(verifier); got != want {
+		t.Errorf("expected challenge %s, got %s", want, got)
+	}
+
+	p, err := NewPKCE()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(p.Verifier) < 43 {
+		t.Errorf("verifier too short: %d", len(p.Verifier))
+	}
+
+	if p.Challenge != challengeFor(p.Verifier) {
+		t.Error("challenge does not match verifier")
+	}
+}
+
+func TestCallbackServer(t *testing.T) {
+	server, err := StartCallbackServer("state-1")
+	if err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	defer server.Close()
+
+	go func() {
+		resp, err := http.Get(server.RedirectURI() + "?code=abc&state=state-1")
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	code, err := server.Wait(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if code != "abc" {
+		t.Errorf("expected code abc, got %s", code)
+	}
+}
+
+func TestCallbackServerIgnoresOtherState(t *testing.T) {
+	server, err := StartCallbackServer("state-1")
+	if err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	defer server.Close()
+
+	for _, query := range []string{"", "?code=stray", "?code=abc&state=other", "?error=access_denied"} {
+		resp, err := http.Get(server.RedirectURI() + query)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", query, resp.StatusCode)
+		}
+	}
+
+	go func() {
+		resp, err := http.Get(server.RedirectURI() + "?code=abc&state=state-1")
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	code, err := server.Wait(ctx)
+	if err != nil || code != "abc" {
+		t.Errorf("expected code abc from the matching callback, got %q (%v)", code, err)
+	}
+}
+
+func TestParseCallback(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   url.Values
+		want    string
+		wantErr bool
+	}{
+		{"valid", url.Values{"code": {"abc"}, "state": {"s"}}, "abc", false},
+		{"state mismatch", url.Values{"code": {"abc"}, "state": {"other"}}, "", true},
+		{"denied", url.Values{"error": {"access_denied"}, "state": {"s"}}, "", true},
+		{"missing code", url.Values{"state": {"s"}}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCallback(tt.query, "s")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error=%v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	code, err := ParseCallbackURL("http://127.0.0.1:1234/callback?code=xyz&state=s\n", "s")
+	if err != nil || code != "xyz" {
+		t.Errorf("expected xyz from pasted URL, got %q (%v)", code, err)
+	}
+}
@@ -0,0 +1,79 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/supabase/supabase-dx/cli/internal/api"
+)
+
+// API is a Supabase project (or branch) reached through the Management API
+type API struct {
+	Client     *api.Client
+	ProjectRef string
+}
+
+// NewAPI returns the backend for a project
+func NewAPI(client *api.Client, projectRef string) *API {
+	return &API{Client: client, ProjectRef: projectRef}
+}
+
+func (b *API) Describe() string {
+	return b.ProjectRef
+}
+
+func (b *API) GetProject(ctx context.Context) (*api.Project, error) {
+	return b.Client.GetProject(ctx, b.ProjectRef)
+}
+
+func (b *API) ListBranches(ctx context.Context) ([]api.Branch, error) {
+	return b.Client.ListBranches(ctx, b.ProjectRef)
+}
+
+func (b *API) ListMigrations(ctx context.Context) ([]api.Migration, error) {
+	return b.Client.ListMigrations(ctx, b.ProjectRef)
+}
+
+func (b *API) GetMigration(ctx context.Context, version string) (*api.MigrationDetail, error) {
+	return b.Client.GetMigration(ctx, b.ProjectRef, version)
+}
+
+func (b *API) ApplyMigration(ctx context.Context, req api.ApplyMigrationRequest) error {
+	return b.Client.ApplyMigration(ctx, b.ProjectRef, req)
+}
+
+func (b *API) ListFunctions(ctx context.Context) ([]api.Function, error) {
+	return b.Client.ListFunctions(ctx, b.ProjectRef)
+}
+
+func (b *API) GetFunctionBody(ctx context.Context, slug string) (string, []byte, error) {
+	return b.Client.GetFunctionBody(ctx, b.ProjectRef, slug)
+}
+
+func (b *API) DeployFunction(ctx context.Context, slug string, metadata api.DeployFunctionMetadata, files []api.FunctionFile) (*api.Function, error) {
+	return b.Client.DeployFunction(ctx, b.ProjectRef, slug, metadata, files)
+}
+
+func (b *API) ListSecrets(ctx context.Context) ([]api.Secret, error) {
+	return b.Client.ListSecrets(ctx, b.ProjectRef)
+}
+
+func (b *API) CreateSecrets(ctx context.Context, secrets []api.Secret) error {
+	return b.Client.CreateSecrets(ctx, b.ProjectRef, secrets)
+}
+
+func (b *API) DeleteSecrets(ctx context.Context, names []string) error {
+	return b.Client.DeleteSecrets(ctx, b.ProjectRef, names)
+}
+
+func (b *API) GetTypescriptTypes(ctx context.Context, schemas string) (*api.TypescriptResponse, error) {
+	return b.Client.GetTypescriptTypes(ctx, b.ProjectRef, schemas)
+}
+
+func (b *API) RunQuery(ctx context.Context, query string) (json.RawMessage, error) {
+	return b.Client.RunQuery(ctx, b.ProjectRef, query)
+}
+
+func (b *API) RunReadOnlyQuery(ctx context.Context, query string) (json.RawMessage, error) {
+	return b.Client.RunReadOnlyQuery(ctx, b.ProjectRef, query)
+}
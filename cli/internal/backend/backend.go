@@ -0,0 +1,57 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/supabase/supabase-dx/cli/internal/api"
+)
+
+// Backend is what push, pull and status operate on. The profile picks the
+// implementation: a Supabase project through the Management API (API), a
+// database reached directly (Postgres), or an in-memory Fake in tests.
+type Backend interface {
+	// Describe names the target in output: a project ref, or host:port/dbname
+	Describe() string
+
+	// Migrations
+	ListMigrations(ctx context.Context) ([]api.Migration, error)
+	GetMigration(ctx context.Context, version string) (*api.MigrationDetail, error)
+	ApplyMigration(ctx context.Context, req api.ApplyMigrationRequest) error
+
+	// Edge functions
+	ListFunctions(ctx context.Context) ([]api.Function, error)
+	GetFunctionBody(ctx context.Context, slug string) (contentType string, body []byte, err error)
+	DeployFunction(ctx context.Context, slug string, metadata api.DeployFunctionMetadata, files []api.FunctionFile) (*api.Function, error)
+
+	// Secrets. Listed values are sha256 digests, never the values themselves.
+	ListSecrets(ctx context.Context) ([]api.Secret, error)
+	CreateSecrets(ctx context.Context, secrets []api.Secret) error
+	DeleteSecrets(ctx context.Context, names []string) error
+
+	// Types and queries
+	GetTypescriptTypes(ctx context.Context, schemas string) (*api.TypescriptResponse, error)
+	RunQuery(ctx context.Context, query string) (json.RawMessage, error)
+	// RunReadOnlyQuery runs a query that can't change anything, for
+	// introspection
+	RunReadOnlyQuery(ctx context.Context, query string) (json.RawMessage, error)
+}
+
+// Project is implemented by backends that are a Supabase project, for the
+// project details pull reports
+type Project interface {
+	GetProject(ctx context.Context) (*api.Project, error)
+	ListBranches(ctx context.Context) ([]api.Branch, error)
+}
+
+// ErrUnsupported is returned for operations a backend can't perform, such
+// as deploying functions to a plain database
+var ErrUnsupported = errors.New("not supported by this backend")
+
+var (
+	_ Backend = (*API)(nil)
+	_ Backend = (*Postgres)(nil)
+	_ Backend = (*Fake)(nil)
+	_ Project = (*API)(nil)
+)
@@ -0,0 +1,136 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/supabase/supabase-dx/cli/internal/api"
+	"github.com/supabase/supabase-dx/cli/internal/functions"
+	"github.com/supabase/supabase-dx/cli/internal/secrets"
+)
+
+func TestAPIUsesProjectRef(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/projects/branchref/database/migrations" {
+			t.Errorf("expected path /v1/projects/branchref/database/migrations, got %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]api.Migration{{Version: "20240101000000", Name: "init"}})
+	}))
+	defer server.Close()
+
+	client := api.NewClient("test-token")
+	client.BaseURL = server.URL
+	b := NewAPI(client, "branchref")
+
+	if b.Describe() != "branchref" {
+		t.Errorf("expected branchref, got %s", b.Describe())
+	}
+
+	applied, err := b.ListMigrations(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(applied) != 1 || applied[0].Name != "init" {
+		t.Errorf("expected the init migration, got %+v", applied)
+	}
+}
+
+func TestFakeMigrations(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFake("fake")
+	fake.FailMigrations = map[string]error{"20240102000000_bad": errors.New("syntax error")}
+
+	for _, name := range []string{"20240101000000_init", "20240101000001_users"} {
+		if err := fake.ApplyMigration(ctx, api.ApplyMigrationRequest{Name: name, Query: "select 1;"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := fake.ApplyMigration(ctx, api.ApplyMigrationRequest{Name: "20240102000000_bad"}); err == nil {
+		t.Error("expected the injected failure")
+	}
+
+	applied, _ := fake.ListMigrations(ctx)
+	if len(applied) != 2 {
+		t.Fatalf("expected 2 migrations, got %d", len(applied))
+	}
+	if applied[0].Version == applied[1].Version {
+		t.Errorf("expected unique versions, got %s twice", applied[0].Version)
+	}
+
+	detail, err := fake.GetMigration(ctx, applied[1].Version)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if detail.Name != "20240101000001_users" || len(detail.Statements) != 1 {
+		t.Errorf("expected the users migration with its statement, got %+v", detail)
+	}
+}
+
+func TestFakeFunctionBody(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFake("fake")
+
+	files := []api.FunctionFile{
+		{Name: "supabase/functions/hello/index.ts", Content: []byte("export default 1")},
+		{Name: "supabase/functions/_shared/cors.ts", Content: []byte("export const cors = {}")},
+	}
+	fn, err := fake.DeployFunction(ctx, "hello", api.DeployFunctionMetadata{}, files)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fn.Version != 1 {
+		t.Errorf("expected version 1, got %d", fn.Version)
+	}
+
+	contentType, body, err := fake.GetFunctionBody(ctx, "hello")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	unpacked, err := functions.Unpack("hello", contentType, body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, f := range unpacked {
+		got[f.Name] = string(f.Content)
+	}
+	for _, f := range files {
+		if got[f.Name] != string(f.Content) {
+			t.Errorf("expected %s to round-trip, got %q", f.Name, got[f.Name])
+		}
+	}
+}
+
+func TestFakeSecrets(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFake("fake")
+
+	local := map[string]string{"STRIPE_KEY": "sk_test", "WEBHOOK_SECRET": "whsec"}
+	remote, _ := fake.ListSecrets(ctx)
+	plan := secrets.Diff(".env", local, remote)
+	if _, _, err := plan.Apply(ctx, fake, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Listed digests match the values, so nothing is left to apply
+	remote, _ = fake.ListSecrets(ctx)
+	if plan := secrets.Diff(".env", local, remote); plan.Pending(false) {
+		t.Errorf("expected no pending changes, got %+v", plan)
+	}
+}
+
+func TestPostgresUnsupported(t *testing.T) {
+	b := &Postgres{}
+	if _, err := b.ListFunctions(context.Background()); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+	if err := b.CreateSecrets(context.Background(), nil); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+}
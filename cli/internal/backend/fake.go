@@ -0,0 +1,211 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/supabase/supabase-dx/cli/internal/api"
+)
+
+// Fake is an in-memory backend for tests. Its fields are the remote state;
+// they can be set up front and inspected afterwards.
+type Fake struct {
+	Name string
+
+	Migrations []api.MigrationDetail         // applied, in order
+	Functions  map[string][]api.FunctionFile // deployed source by slug
+	Versions   map[string]int                // deployed version by slug
+	Secrets    map[string]string             // plain values
+	Types      string
+	Queries    []string // every query run, in order
+
+	// Failures to inject: ApplyMigration fails for migrations with these
+	// names, DeployFunction for these slugs
+	FailMigrations map[string]error
+	FailDeploys    map[string]error
+
+	mu sync.Mutex
+}
+
+// NewFake returns an empty fake
+func NewFake(name string) *Fake {
+	return &Fake{
+		Name:      name,
+		Functions: make(map[string][]api.FunctionFile),
+		Versions:  make(map[string]int),
+		Secrets:   make(map[string]string),
+	}
+}
+
+func (f *Fake) Describe() string {
+	return f.Name
+}
+
+func (f *Fake) ListMigrations(ctx context.Context) ([]api.Migration, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	applied := []api.Migration{}
+	for _, m := range f.Migrations {
+		applied = append(applied, api.Migration{Version: m.Version, Name: m.Name})
+	}
+	return applied, nil
+}
+
+func (f *Fake) GetMigration(ctx context.Context, version string) (*api.MigrationDetail, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, m := range f.Migrations {
+		if m.Version == version {
+			return &m, nil
+		}
+	}
+	return nil, fmt.Errorf("migration %s not found", version)
+}
+
+// ApplyMigration records the migration under a new timestamp version, as
+// the Management API does
+func (f *Fake) ApplyMigration(ctx context.Context, req api.ApplyMigrationRequest) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.FailMigrations[req.Name]; err != nil {
+		return err
+	}
+
+	// Versions must stay unique when several are applied within a second
+	version := time.Now().UTC().Format("20060102150405")
+	if n := len(f.Migrations); n > 0 && f.Migrations[n-1].Version >= version {
+		last, _ := strconv.ParseInt(f.Migrations[n-1].Version, 10, 64)
+		version = strconv.FormatInt(last+1, 10)
+	}
+	f.Migrations = append(f.Migrations, api.MigrationDetail{Version: version, Name: req.Name, Statements: []string{req.Query}})
+	return nil
+}
+
+func (f *Fake) ListFunctions(ctx context.Context) ([]api.Function, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	slugs := make([]string, 0, len(f.Functions))
+	for slug := range f.Functions {
+		slugs = append(slugs, slug)
+	}
+	sort.Strings(slugs)
+
+	functions := []api.Function{}
+	for _, slug := range slugs {
+		functions = append(functions, api.Function{Slug: slug, Name: slug, Status: "ACTIVE", Version: f.Versions[slug]})
+	}
+	return functions, nil
+}
+
+// GetFunctionBody returns the deployed files as multipart/form-data
+func (f *Fake) GetFunctionBody(ctx context.Context, slug string) (string, []byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	files, ok := f.Functions[slug]
+	if !ok {
+		return "", nil, fmt.Errorf("function %s not found", slug)
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, file := range files {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, file.Name))
+		part, err := w.CreatePart(h)
+		if err != nil {
+			return "", nil, err
+		}
+		part.Write(file.Content)
+	}
+	if err := w.Close(); err != nil {
+		return "", nil, err
+	}
+	return w.FormDataContentType(), body.Bytes(), nil
+}
+
+func (f *Fake) DeployFunction(ctx context.Context, slug string, metadata api.DeployFunctionMetadata, files []api.FunctionFile) (*api.Function, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.FailDeploys[slug]; err != nil {
+		return nil, err
+	}
+
+	f.Functions[slug] = files
+	f.Versions[slug]++
+	return &api.Function{Slug: slug, Name: slug, Status: "ACTIVE", Version: f.Versions[slug]}, nil
+}
+
+// ListSecrets reports sha256 digests of the values, as the Management API does
+func (f *Fake) ListSecrets(ctx context.Context) ([]api.Secret, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	names := make([]string, 0, len(f.Secrets))
+	for name := range f.Secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	secrets := []api.Secret{}
+	for _, name := range names {
+		sum := sha256.Sum256([]byte(f.Secrets[name]))
+		secrets = append(secrets, api.Secret{Name: name, Value: hex.EncodeToString(sum[:])})
+	}
+	return secrets, nil
+}
+
+func (f *Fake) CreateSecrets(ctx context.Context, secrets []api.Secret) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, s := range secrets {
+		f.Secrets[s.Name] = s.Value
+	}
+	return nil
+}
+
+func (f *Fake) DeleteSecrets(ctx context.Context, names []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, name := range names {
+		delete(f.Secrets, name)
+	}
+	return nil
+}
+
+func (f *Fake) GetTypescriptTypes(ctx context.Context, schemas string) (*api.TypescriptResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return &api.TypescriptResponse{Types: f.Types}, nil
+}
+
+// RunQuery records the query and returns no rows
+func (f *Fake) RunQuery(ctx context.Context, query string) (json.RawMessage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Queries = append(f.Queries, query)
+	return json.RawMessage("[]"), nil
+}
+
+func (f *Fake) RunReadOnlyQuery(ctx context.Context, query string) (json.RawMessage, error) {
+	return f.RunQuery(ctx, query)
+}
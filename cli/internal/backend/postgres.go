@@ -0,0 +1,53 @@
+package backend
+
+import (
+	"context"
+
+	"github.com/supabase/supabase-dx/cli/internal/api"
+	"github.com/supabase/supabase-dx/cli/internal/localdb"
+)
+
+// Postgres is a database reached directly, such as the local stack's.
+// Migrations, types and queries run against it; functions and secrets are
+// unsupported, since the local stack serves them from the working tree.
+type Postgres struct {
+	*localdb.DB
+	URL string
+}
+
+// OpenPostgres connects to the database at url
+func OpenPostgres(ctx context.Context, url string) (*Postgres, error) {
+	db, err := localdb.Connect(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return &Postgres{DB: db, URL: url}, nil
+}
+
+func (b *Postgres) Describe() string {
+	return localdb.Describe(b.URL)
+}
+
+func (b *Postgres) ListFunctions(ctx context.Context) ([]api.Function, error) {
+	return nil, ErrUnsupported
+}
+
+func (b *Postgres) GetFunctionBody(ctx context.Context, slug string) (string, []byte, error) {
+	return "", nil, ErrUnsupported
+}
+
+func (b *Postgres) DeployFunction(ctx context.Context, slug string, metadata api.DeployFunctionMetadata, files []api.FunctionFile) (*api.Function, error) {
+	return nil, ErrUnsupported
+}
+
+func (b *Postgres) ListSecrets(ctx context.Context) ([]api.Secret, error) {
+	return nil, ErrUnsupported
+}
+
+func (b *Postgres) CreateSecrets(ctx context.Context, secrets []api.Secret) error {
+	return ErrUnsupported
+}
+
+func (b *Postgres) DeleteSecrets(ctx context.Context, names []string) error {
+	return ErrUnsupported
+}
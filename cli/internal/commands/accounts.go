@@ -0,0 +1,233 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/supabase/supabase-dx/cli/internal/api"
+	"github.com/supabase/supabase-dx/cli/internal/config"
+)
+
+type AccountInfo struct {
+	Name    string `json:"name"`
+	Current bool   `json:"current"`
+	Type    string `json:"type"` // oauth, pat
+}
+
+type AccountsResult struct {
+	Status    string        `json:"status"`
+	Message   string        `json:"message,omitempty"`
+	Accounts  []AccountInfo `json:"accounts,omitempty"`
+	Store     string        `json:"store,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	ErrorCode string        `json:"error_code,omitempty"`
+}
+
+func NewAccountsCmd(jsonOut *bool) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "accounts",
+		Short: "Manage logged-in Supabase accounts",
+		Long: `Manage the named accounts stored by 'supa login --account <name>'.
+
+The current account is used by default. A profile can pin an account with
+account = "<name>" in supabase/config.toml, so pull and push pick the right
+token for each project automatically.
+
+Tokens are kept in the credential store selected with 'supa accounts migrate':
+file (config.json, the default), keyring (the OS keyring via Secret Service,
+Keychain or Credential Manager) or encrypted (an AES-GCM file unlocked with a
+passphrase, read from SUPABASE_DX_PASSPHRASE or prompted for).`,
+	}
+
+	cmd.AddCommand(newAccountsListCmd(jsonOut))
+	cmd.AddCommand(newAccountsUseCmd(jsonOut))
+	cmd.AddCommand(newAccountsRemoveCmd(jsonOut))
+	cmd.AddCommand(newAccountsMigrateCmd(jsonOut))
+
+	return cmd
+}
+
+func newAccountsListCmd(jsonOut *bool) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List accounts",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return accountsError(*jsonOut, "failed to load config", err)
+			}
+
+			store := cfg.CredentialStore
+			if store == "" {
+				store = config.StoreFile
+			}
+
+			result := AccountsResult{Status: "success", Accounts: []AccountInfo{}, Store: store}
+			for _, name := range cfg.AccountNames() {
+				info := AccountInfo{
+					Name:    name,
+					Current: name == cfg.CurrentAccount,
+					Type:    "pat",
+				}
+				if cfg.Accounts[name].IsOAuth() {
+					info.Type = "oauth"
+				}
+				result.Accounts = append(result.Accounts, info)
+			}
+
+			if *jsonOut {
+				printJSON(result)
+				return nil
+			}
+
+			if len(result.Accounts) == 0 {
+				fmt.Println("No accounts. Run 'supa login' to add one.")
+				return nil
+			}
+
+			for _, a := range result.Accounts {
+				marker := " "
+				if a.Current {
+					marker = "*"
+				}
+				fmt.Printf("  %s %s (%s)\n", marker, a.Name, a.Type)
+			}
+			fmt.Printf("\nCredential store: %s\n", result.Store)
+			return nil
+		},
+	}
+}
+
+func newAccountsUseCmd(jsonOut *bool) *cobra.Command {
+	return &cobra.Command{
+		Use:   "use <name>",
+		Short: "Set the current account",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			cfg, err := config.Load()
+			if err != nil {
+				return accountsError(*jsonOut, "failed to load config", err)
+			}
+
+			if _, ok := cfg.Accounts[name]; !ok {
+				return accountsError(*jsonOut, fmt.Sprintf("account %q not found", name), nil)
+			}
+
+			cfg.CurrentAccount = name
+			if err := config.Save(cfg); err != nil {
+				return accountsError(*jsonOut, "failed to save config", err)
+			}
+
+			return accountsSuccess(*jsonOut, fmt.Sprintf("Now using account %q", name))
+		},
+	}
+}
+
+func newAccountsRemoveCmd(jsonOut *bool) *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove <name>",
+		Short: "Remove an account and revoke its OAuth session",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			cfg, err := config.Load()
+			if err != nil {
+				return accountsError(*jsonOut, "failed to load config", err)
+			}
+
+			if _, err := removeAccount(cmd.Context(), cfg, name, *jsonOut); err != nil {
+				return accountsError(*jsonOut, "failed to remove account", err)
+			}
+
+			return accountsSuccess(*jsonOut, fmt.Sprintf("Removed account %q", name))
+		},
+	}
+}
+
+func newAccountsMigrateCmd(jsonOut *bool) *cobra.Command {
+	return &cobra.Command{
+		Use:       "migrate <file|keyring|encrypted>",
+		Short:     "Move stored credentials to another credential store",
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: config.StoreNames(),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			target := args[0]
+
+			cfg, err := config.Load()
+			if err != nil {
+				return accountsError(*jsonOut, "failed to load config", err)
+			}
+
+			n, err := config.MigrateStore(cfg, target)
+			if err != nil {
+				return accountsError(*jsonOut, "failed to migrate credentials", err)
+			}
+
+			return accountsSuccess(*jsonOut, fmt.Sprintf("Moved %d account(s) to the %s store", n, target))
+		},
+	}
+}
+
+// removeAccount revokes the account's OAuth session (if any) and deletes it
+// from the config and credential store. A failed revoke is reported but doesn't block removal.
+func removeAccount(ctx context.Context, cfg *config.GlobalConfig, name string, jsonOut bool) (revokeErr error, err error) {
+	meta, ok := cfg.Accounts[name]
+	if !ok {
+		return nil, fmt.Errorf("account %q not found", name)
+	}
+
+	if meta.IsOAuth() {
+		// Tokens may live outside config.json, so go through the store
+		acct, _, err := cfg.GetAccount(name)
+		if err != nil {
+			revokeErr = err
+		} else {
+			_, secret := config.GetOAuthClient()
+			app := api.OAuthApp{ClientID: acct.ClientID, ClientSecret: secret}
+			revokeErr = api.NewClient("").RevokeToken(ctx, app, acct.RefreshToken)
+		}
+		if revokeErr != nil && !jsonOut {
+			fmt.Printf("  ⚠ Could not revoke token for %q: %v\n", name, revokeErr)
+		}
+	}
+
+	if err := cfg.ForgetAccount(name); err != nil {
+		return revokeErr, err
+	}
+
+	return revokeErr, nil
+}
+
+func accountsSuccess(jsonOut bool, message string) error {
+	if jsonOut {
+		printJSON(AccountsResult{Status: "success", Message: message})
+		return nil
+	}
+	fmt.Println("✓ " + message)
+	return nil
+}
+
+func accountsError(jsonOut bool, message string, err error) error {
+	if jsonOut {
+		result := AccountsResult{
+			Status:  "error",
+			Message: message,
+		}
+		if err != nil {
+			result.Error = err.Error()
+			result.ErrorCode = api.ErrorCode(err)
+		}
+		printJSON(result)
+		return &ExitError{Code: 1, Err: fmt.Errorf("%s", message)}
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", message, err)
+	}
+	return fmt.Errorf("%s", message)
+}
@@ -0,0 +1,459 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+	"github.com/supabase/supabase-dx/cli/internal/api"
+	"github.com/supabase/supabase-dx/cli/internal/git"
+	"github.com/supabase/supabase-dx/cli/internal/profiles"
+	"github.com/supabase/supabase-dx/cli/internal/tui"
+	"golang.org/x/term"
+)
+
+type BranchesResult struct {
+	Status     string       `json:"status"`
+	Message    string       `json:"message"`
+	ProjectRef string       `json:"project_ref,omitempty"`
+	DryRun     bool         `json:"dry_run,omitempty"`
+	Branches   []api.Branch `json:"branches,omitempty"`
+	Branch     *api.Branch  `json:"branch,omitempty"`
+	Diff       *string      `json:"diff,omitempty"`
+	Error      string       `json:"error,omitempty"`
+	ErrorCode  string       `json:"error_code,omitempty"`
+}
+
+// branchOps holds what every branches subcommand needs: the main project
+// and a client for its account
+type branchOps struct {
+	client     *api.Client
+	projectRef string
+	jsonOut    bool
+	dryRun     bool
+}
+
+func NewBranchesCmd(profile *string, dryRun *bool, jsonOut *bool) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "branches",
+		Short: "Manage preview branches of the main project",
+		Long: `Branches are preview copies of the main project ([project] id in
+supabase/config.toml, or the selected profile's project). Branches can be
+referred to by name, git branch, or ref.`,
+	}
+
+	// setup resolves the project and client for a subcommand
+	setup := func() (*branchOps, error) {
+		return newBranchOps(*profile, *dryRun, *jsonOut)
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List branches",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := setup()
+			if err != nil {
+				return err
+			}
+			return b.list(cmd.Context())
+		},
+	})
+
+	var gitBranch, region string
+	var noWait bool
+	createCmd := &cobra.Command{
+		Use:   "create [name]",
+		Short: "Create a branch and wait until it's ready",
+		Long: `Creates a preview branch. The name defaults to --git-branch, and the
+git branch it tracks to the current git branch. Waits until the branch's
+migrations have run unless --no-wait is given.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := setup()
+			if err != nil {
+				return err
+			}
+			name := ""
+			if len(args) > 0 {
+				name = args[0]
+			}
+			return b.create(cmd.Context(), name, gitBranch, region, !noWait)
+		},
+	}
+	createCmd.Flags().StringVar(&gitBranch, "git-branch", "", "Git branch to track (default: current git branch)")
+	createCmd.Flags().StringVar(&region, "region", "", "Region for the branch (default: the project's region)")
+	createCmd.Flags().BoolVar(&noWait, "no-wait", false, "Don't wait for the branch to be ready")
+	cmd.AddCommand(createCmd)
+
+	var yes bool
+	deleteCmd := &cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete a branch",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := setup()
+			if err != nil {
+				return err
+			}
+			return b.delete(cmd.Context(), args[0], yes)
+		},
+	}
+	deleteCmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip confirmation prompt")
+	cmd.AddCommand(deleteCmd)
+
+	var resetYes bool
+	resetCmd := &cobra.Command{
+		Use:   "reset <name>",
+		Short: "Reset a branch to its parent, discarding its changes",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := setup()
+			if err != nil {
+				return err
+			}
+			return b.reset(cmd.Context(), args[0], resetYes)
+		},
+	}
+	resetCmd.Flags().BoolVarP(&resetYes, "yes", "y", false, "Skip confirmation prompt")
+	cmd.AddCommand(resetCmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "restore <name>",
+		Short: "Restore a branch that is scheduled for deletion",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := setup()
+			if err != nil {
+				return err
+			}
+			return b.restore(cmd.Context(), args[0])
+		},
+	})
+
+	var schemas string
+	diffCmd := &cobra.Command{
+		Use:   "diff <name>",
+		Short: "Show the schema diff between a branch and its parent",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := setup()
+			if err != nil {
+				return err
+			}
+			return b.diff(cmd.Context(), args[0], schemas)
+		},
+	}
+	diffCmd.Flags().StringVar(&schemas, "schemas", "", "Schemas to include (default: all)")
+	cmd.AddCommand(diffCmd)
+
+	return cmd
+}
+
+func newBranchOps(profileName string, dryRun, jsonOut bool) (*branchOps, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, branchesError(jsonOut, "failed to get working directory", err)
+	}
+
+	cfg, err := profiles.LoadConfig(cwd)
+	if err != nil {
+		return nil, branchesError(jsonOut, "failed to load config", err)
+	}
+
+	currentBranch, _ := git.GetCurrentBranch(cwd)
+
+	profile, _, err := cfg.GetProfileOrAuto(profileName, currentBranch)
+	if err != nil {
+		return nil, branchesError(jsonOut, "failed to get profile", err)
+	}
+
+	// Branches hang off the main project, not a branch profile's own ref
+	projectRef := cfg.Project.ID
+	if projectRef == "" {
+		projectRef = profile.GetProjectRef(cfg)
+	}
+	if projectRef == "" {
+		return nil, branchesError(jsonOut, "no project ref configured", nil)
+	}
+
+	client, err := newAPIClient(profile.Account)
+	if err != nil {
+		return nil, branchesError(jsonOut, "authentication required", err)
+	}
+
+	return &branchOps{client: client, projectRef: projectRef, jsonOut: jsonOut, dryRun: dryRun}, nil
+}
+
+func (b *branchOps) list(ctx context.Context) error {
+	branches, err := b.client.ListBranches(ctx, b.projectRef)
+	if err != nil {
+		return branchesError(b.jsonOut, "failed to list branches", err)
+	}
+
+	if b.jsonOut {
+		return b.output(BranchesResult{
+			Message:  fmt.Sprintf("%d branch(es)", len(branches)),
+			Branches: branches,
+		})
+	}
+
+	if len(branches) == 0 {
+		fmt.Println("No branches found.")
+		return nil
+	}
+
+	rows := make([][]string, len(branches))
+	for i, br := range branches {
+		def := ""
+		if br.IsDefault {
+			def = "✓"
+		}
+		rows[i] = []string{br.Name, br.Status, br.GitBranch, def}
+	}
+
+	if !term.IsTerminal(int(os.Stdout.Fd())) {
+		for _, r := range rows {
+			fmt.Printf("%-30s %-20s %-25s %s\n", r[0], r[1], r[2], r[3])
+		}
+		return nil
+	}
+
+	_, err = tea.NewProgram(tui.NewTable(tui.NewBranchesTable(rows))).Run()
+	return err
+}
+
+func (b *branchOps) create(ctx context.Context, name, gitBranch, region string, wait bool) error {
+	if name == "" {
+		name = gitBranch
+	}
+	if name == "" || gitBranch == "" {
+		cwd, _ := os.Getwd()
+		current, err := git.GetCurrentBranch(cwd)
+		if err != nil && name == "" {
+			return branchesError(b.jsonOut, "no branch name given and not on a git branch", err)
+		}
+		if name == "" {
+			name = current
+		}
+		if gitBranch == "" {
+			gitBranch = current
+		}
+	}
+
+	if b.dryRun {
+		return b.done(BranchesResult{
+			Message: fmt.Sprintf("Dry run - would create branch %s tracking %s", name, gitBranch),
+			Branch:  &api.Branch{Name: name, GitBranch: gitBranch},
+		})
+	}
+
+	branch, err := b.client.CreateBranch(ctx, b.projectRef, api.CreateBranchRequest{
+		BranchName: name,
+		GitBranch:  gitBranch,
+		Region:     region,
+	})
+	if err != nil {
+		return branchesError(b.jsonOut, "failed to create branch", err)
+	}
+
+	if wait {
+		if !b.jsonOut {
+			fmt.Printf("⏳ Waiting for branch %s to be ready...\n", name)
+		}
+		// A new branch has no earlier status to tell apart from
+		branch, err = waitForBranch(ctx, b.client, branch.ID, &api.Branch{}, b.jsonOut)
+		if err != nil {
+			return branchesError(b.jsonOut, fmt.Sprintf("branch %s was created but didn't become ready", name), err)
+		}
+		if _, failed := api.BranchSettled(branch.Status); failed {
+			return b.fail(BranchesResult{Branch: branch}, fmt.Sprintf("branch %s was created but failed to set up (%s)", name, branch.Status))
+		}
+	}
+
+	return b.done(BranchesResult{
+		Message: fmt.Sprintf("Created branch %s (%s)", name, branch.ProjectRef),
+		Branch:  branch,
+	})
+}
+
+func (b *branchOps) delete(ctx context.Context, name string, yes bool) error {
+	branch, err := b.find(ctx, name)
+	if err != nil {
+		return err
+	}
+	if branch.IsDefault {
+		return branchesError(b.jsonOut, "the default branch can't be deleted", nil)
+	}
+
+	if b.dryRun {
+		return b.done(BranchesResult{Message: "Dry run - would delete branch " + branch.Name, Branch: branch})
+	}
+	if !b.confirm(fmt.Sprintf("Delete branch %s (%s)?", branch.Name, branch.ProjectRef), yes) {
+		return nil
+	}
+
+	if err := b.client.DeleteBranch(ctx, branch.ID); err != nil {
+		return branchesError(b.jsonOut, "failed to delete branch", err)
+	}
+	return b.done(BranchesResult{Message: "Deleted branch " + branch.Name, Branch: branch})
+}
+
+func (b *branchOps) reset(ctx context.Context, name string, yes bool) error {
+	branch, err := b.find(ctx, name)
+	if err != nil {
+		return err
+	}
+	if branch.IsDefault {
+		return branchesError(b.jsonOut, "the default branch can't be reset", nil)
+	}
+
+	if b.dryRun {
+		return b.done(BranchesResult{Message: "Dry run - would reset branch " + branch.Name, Branch: branch})
+	}
+	if !b.confirm(fmt.Sprintf("Reset branch %s? Its changes will be lost.", branch.Name), yes) {
+		return nil
+	}
+
+	if _, err := b.client.ResetBranch(ctx, branch.ID, api.BranchActionRequest{}); err != nil {
+		return branchesError(b.jsonOut, "failed to reset branch", err)
+	}
+
+	if !b.jsonOut {
+		fmt.Printf("⏳ Waiting for branch %s to reset...\n", branch.Name)
+	}
+	final, err := waitForBranch(ctx, b.client, branch.ID, branch, b.jsonOut)
+	if err != nil {
+		return branchesError(b.jsonOut, "branch reset did not complete", err)
+	}
+	if _, failed := api.BranchSettled(final.Status); failed {
+		return b.fail(BranchesResult{Branch: final}, fmt.Sprintf("branch reset failed (%s)", final.Status))
+	}
+	return b.done(BranchesResult{Message: "Reset branch " + branch.Name, Branch: final})
+}
+
+func (b *branchOps) restore(ctx context.Context, name string) error {
+	branch, err := b.find(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if b.dryRun {
+		return b.done(BranchesResult{Message: "Dry run - would restore branch " + branch.Name, Branch: branch})
+	}
+
+	if _, err := b.client.RestoreBranch(ctx, branch.ID); err != nil {
+		return branchesError(b.jsonOut, "failed to restore branch", err)
+	}
+	return b.done(BranchesResult{Message: "Restored branch " + branch.Name, Branch: branch})
+}
+
+func (b *branchOps) diff(ctx context.Context, name, schemas string) error {
+	branch, err := b.find(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	diff, err := b.client.GetBranchDiff(ctx, branch.ID, schemas)
+	if err != nil {
+		return branchesError(b.jsonOut, "failed to diff branch", err)
+	}
+
+	if b.jsonOut {
+		return b.output(BranchesResult{Message: "Diff of " + branch.Name, Branch: branch, Diff: &diff})
+	}
+
+	if strings.TrimSpace(diff) == "" {
+		fmt.Printf("✓ %s has no schema differences from its parent\n", branch.Name)
+		return nil
+	}
+	fmt.Println(strings.TrimRight(diff, "\n"))
+	return nil
+}
+
+// find looks a branch up by name, git branch, ID or ref
+func (b *branchOps) find(ctx context.Context, name string) (*api.Branch, error) {
+	branches, err := b.client.ListBranches(ctx, b.projectRef)
+	if err != nil {
+		return nil, branchesError(b.jsonOut, "failed to list branches", err)
+	}
+
+	for _, br := range branches {
+		if br.Name == name || br.ID == name || br.ProjectRef == name {
+			return &br, nil
+		}
+	}
+	for _, br := range branches {
+		if br.GitBranch != "" && br.GitBranch == name {
+			return &br, nil
+		}
+	}
+	return nil, branchesError(b.jsonOut, fmt.Sprintf("branch %q not found", name), nil)
+}
+
+func (b *branchOps) confirm(prompt string, yes bool) bool {
+	if yes || b.jsonOut {
+		return true
+	}
+	fmt.Printf("%s [y/N] ", prompt)
+	reader := bufio.NewReader(os.Stdin)
+	response, _ := reader.ReadString('\n')
+	response = strings.TrimSpace(strings.ToLower(response))
+	if response != "y" && response != "yes" {
+		fmt.Println("Cancelled.")
+		return false
+	}
+	return true
+}
+
+// done reports a successful result
+func (b *branchOps) done(result BranchesResult) error {
+	if b.jsonOut {
+		return b.output(result)
+	}
+	fmt.Printf("✓ %s\n", result.Message)
+	return nil
+}
+
+// fail reports a failed operation along with the branch's state
+func (b *branchOps) fail(result BranchesResult, message string) error {
+	if b.jsonOut {
+		result.Status = "error"
+		result.Message = message
+		result.ProjectRef = b.projectRef
+		printJSON(result)
+		return &ExitError{Code: 1, Err: fmt.Errorf("%s", message)}
+	}
+	return fmt.Errorf("%s", message)
+}
+
+func (b *branchOps) output(result BranchesResult) error {
+	result.Status = "success"
+	result.ProjectRef = b.projectRef
+	result.DryRun = b.dryRun
+	printJSON(result)
+	return nil
+}
+
+func branchesError(jsonOut bool, message string, err error) error {
+	if jsonOut {
+		result := BranchesResult{
+			Status:  "error",
+			Message: message,
+		}
+		if err != nil {
+			result.Error = err.Error()
+			result.ErrorCode = api.ErrorCode(err)
+		}
+		printJSON(result)
+		return &ExitError{Code: 1, Err: fmt.Errorf("%s", message)}
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", message, err)
+	}
+	return fmt.Errorf("%s", message)
+}
@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/supabase/supabase-dx/cli/internal/api"
+	"github.com/supabase/supabase-dx/cli/internal/backend"
+)
+
+func TestBranchCreateWaitsForNewBranch(t *testing.T) {
+	previous := mergePollInterval
+	mergePollInterval = time.Millisecond
+	t.Cleanup(func() { mergePollInterval = previous })
+
+	tests := []struct {
+		statuses []string // reported by successive polls of the new branch
+		polls    int
+		fails    bool
+	}{
+		{[]string{api.BranchCreatingProject, api.BranchRunningMigrations, api.BranchMigrationsPassed}, 3, false},
+		// A branch that's already set up by the first poll needs no earlier status
+		{[]string{api.BranchFunctionsDeployed}, 1, false},
+		{[]string{api.BranchRunningMigrations, api.BranchMigrationsFailed}, 2, true},
+	}
+	for _, tt := range tests {
+		polls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch {
+			case r.Method == "POST" && r.URL.Path == "/v1/projects/abcdefghijklmnopqrst/branches":
+				json.NewEncoder(w).Encode(api.Branch{ID: "branch-new", Name: "feature-x", Status: api.BranchCreatingProject})
+			case r.Method == "GET" && r.URL.Path == "/v1/branches/branch-new":
+				status := tt.statuses[min(polls, len(tt.statuses)-1)]
+				polls++
+				json.NewEncoder(w).Encode(api.Branch{ID: "branch-new", Name: "feature-x", ProjectRef: "newrefnewrefnewrefne", Status: status, UpdatedAt: "t1"})
+			default:
+				t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			}
+		}))
+
+		client := api.NewClient("test-token")
+		client.BaseURL = server.URL
+		b := &branchOps{client: client, projectRef: "abcdefghijklmnopqrst", jsonOut: true}
+
+		err := b.create(context.Background(), "feature-x", "feature-x", "", true)
+		server.Close()
+		if tt.fails != (err != nil) {
+			t.Errorf("%v: expected failure %v, got %v", tt.statuses, tt.fails, err)
+		}
+		if polls != tt.polls {
+			t.Errorf("%v: expected %d polls, got %d", tt.statuses, tt.polls, polls)
+		}
+	}
+}
+
+func TestBranchCreateNamesAfterGitBranch(t *testing.T) {
+	// Not a git repository, so there's no current branch to fall back on
+	fakeProject(t, backend.NewFake("fake"), nil)
+	out := captureJSON(t)
+	b := &branchOps{projectRef: "abcdefghijklmnopqrst", jsonOut: true, dryRun: true}
+
+	if err := b.create(context.Background(), "", "feature-x", "", false); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var result BranchesResult
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("expected a JSON result, got %q", out.String())
+	}
+	if result.Branch == nil || result.Branch.Name != "feature-x" || result.Branch.GitBranch != "feature-x" {
+		t.Errorf("expected branch feature-x tracking feature-x, got %+v", result.Branch)
+	}
+
+	out.Reset()
+	if err := b.create(context.Background(), "", "", "", false); err == nil {
+		t.Errorf("expected an error without a name or git branch, got %s", out)
+	}
+}
@@ -0,0 +1,184 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/supabase/supabase-dx/cli/internal/api"
+	"github.com/supabase/supabase-dx/cli/internal/backend"
+	"github.com/supabase/supabase-dx/cli/internal/config"
+	"github.com/supabase/supabase-dx/cli/internal/profiles"
+)
+
+// apiClients keeps a client per account while the daemon runs, so calls
+// share connections and rotated tokens instead of reloading credentials.
+// Nil for one-shot commands.
+var apiClients *clientCache
+
+type clientCache struct {
+	mu      sync.Mutex
+	clients map[string]*api.Client
+}
+
+func newClientCache() *clientCache {
+	return &clientCache{clients: make(map[string]*api.Client)}
+}
+
+// reset drops the cached clients, e.g. after a login or account switch
+func (c *clientCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clients = make(map[string]*api.Client)
+}
+
+// newAPIClient returns a Management API client for the named account
+// (empty for the current account), from the daemon's cache if it has one
+func newAPIClient(account string) (*api.Client, error) {
+	cache := apiClients
+	if cache == nil {
+		return loadAPIClient(account)
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if client, ok := cache.clients[account]; ok {
+		return client, nil
+	}
+	client, err := loadAPIClient(account)
+	if err != nil {
+		return nil, err
+	}
+	cache.clients[account] = client
+	return client, nil
+}
+
+// loadAPIClient builds a Management API client from the stored credentials
+// of the named account. OAuth logins get automatic token rotation,
+// persisted back to the config.
+func loadAPIClient(account string) (*api.Client, error) {
+	// An explicit environment token always wins and is never rotated
+	if token := os.Getenv("SUPABASE_ACCESS_TOKEN"); token != "" {
+		return api.NewClient(token), nil
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	acct, name, err := cfg.GetAccount(account)
+	if err != nil {
+		return nil, err
+	}
+
+	client := api.NewClient(acct.AccessToken)
+
+	if acct.IsOAuth() {
+		_, secret := config.GetOAuthClient()
+		client.OAuth = &api.OAuthSession{
+			App:          api.OAuthApp{ClientID: acct.ClientID, ClientSecret: secret},
+			RefreshToken: acct.RefreshToken,
+			OnRefresh: func(t *api.OAuthToken) error {
+				// Reload so concurrent changes to other accounts aren't lost
+				latest, err := config.Load()
+				if err != nil {
+					return err
+				}
+				return latest.StoreAccount(name, &config.Account{
+					AccessToken:  t.AccessToken,
+					RefreshToken: t.RefreshToken,
+					ExpiresAt:    t.ExpiresAt(time.Now()).Unix(),
+					ClientID:     acct.ClientID,
+				})
+			},
+		}
+	}
+
+	return client, nil
+}
+
+// openBackend returns what a profile operates on: its database in local
+// mode, otherwise its project - or in preview mode, the branch for the
+// current git branch, created if missing unless create is false. The
+// returned func releases it. Tests replace this with a fake.
+var openBackend = func(ctx context.Context, cfg *profiles.Config, profile *profiles.Profile, cwd string, create, jsonOut bool) (backend.Backend, func(), error) {
+	if profile.IsLocal() {
+		db, err := backend.OpenPostgres(ctx, profile.DatabaseURL())
+		if err != nil {
+			return nil, nil, &openError{"failed to connect to local database", err}
+		}
+		return db, func() { db.Close(context.Background()) }, nil
+	}
+
+	projectRef := profile.GetProjectRef(cfg)
+	if projectRef == "" {
+		return nil, nil, &openError{"no project ref configured", nil}
+	}
+
+	client, err := newAPIClient(profile.Account)
+	if err != nil {
+		return nil, nil, &openError{"authentication required", err}
+	}
+
+	projectRef, err = resolveProjectRef(ctx, client, cfg, profile, cwd, create, jsonOut)
+	if err != nil {
+		return nil, nil, &openError{"failed to resolve preview branch", err}
+	}
+	return backend.NewAPI(client, projectRef), func() {}, nil
+}
+
+// openProject returns a profile's project itself, whatever its mode, or nil
+// if it has none. Local pulls sync from it.
+var openProject = func(cfg *profiles.Config, profile *profiles.Profile) (backend.Backend, error) {
+	projectRef := profile.GetProjectRef(cfg)
+	if projectRef == "" {
+		return nil, nil
+	}
+
+	client, err := newAPIClient(profile.Account)
+	if err != nil {
+		return nil, &openError{"authentication required", err}
+	}
+	return backend.NewAPI(client, projectRef), nil
+}
+
+// openError is a failure to open a backend: the message commands report,
+// and its cause
+type openError struct {
+	message string
+	err     error
+}
+
+func (e *openError) Error() string {
+	if e.err != nil {
+		return fmt.Sprintf("%s: %v", e.message, e.err)
+	}
+	return e.message
+}
+
+func (e *openError) Unwrap() error {
+	return e.err
+}
+
+// openFailure splits an error from openBackend or openProject into the
+// message and cause that commands pass to their error helpers
+func openFailure(err error) (string, error) {
+	var oe *openError
+	if errors.As(err, &oe) {
+		return oe.message, oe.err
+	}
+	return "failed to open backend", err
+}
+
+// describeBackend returns the project ref or, for a database, its
+// host:port/dbname - for the result fields of the same names
+func describeBackend(b backend.Backend) (projectRef, database string) {
+	if _, ok := b.(*backend.Postgres); ok {
+		return "", b.Describe()
+	}
+	return b.Describe(), ""
+}
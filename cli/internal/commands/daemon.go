@@ -0,0 +1,338 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/supabase/supabase-dx/cli/internal/jsonrpc"
+)
+
+// commandFailed is the JSON-RPC error code of a command that ran and
+// failed. The error's data is the command's --json result.
+const commandFailed = -32000
+
+// commandMu serializes commands run by the daemon: they write their
+// results to jsonOutput. A subscribed watch's auto-push holds it too, since
+// it applies migrations and writes the same lockfiles as push.
+var commandMu sync.Mutex
+
+func NewDaemonCmd() *cobra.Command {
+	var socket string
+
+	cmd := &cobra.Command{
+		Use:   "daemon",
+		Short: "Serve commands over JSON-RPC for editors",
+		Long: `Daemon keeps running and serves JSON-RPC 2.0, with messages framed by
+Content-Length headers as in the Language Server Protocol. It serves
+stdin/stdout, or with --socket, any number of clients on a Unix socket.
+
+API clients stay open between calls, so each call skips reloading
+credentials. Run 'reload' after logging in or switching accounts.
+
+Methods (params are optional):
+  status             {profile, schemas}
+  pull               {profile, dry_run, types_only, schemas, migration}
+  push               {profile, dry_run, migrations_only, force, continue_on_error, prune, no_lint}
+  branches           {profile}
+  watch/subscribe    {profile, types_interval, no_branch_watch}
+  watch/unsubscribe
+  reload
+  shutdown
+
+Results are the command's --json output. A command that fails returns
+error code -32000 with that output as the error's data. Subscribers get
+watch events (see cli/events-schema) as "watch/event" notifications.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDaemon(cmd.Context(), socket)
+		},
+	}
+
+	cmd.Flags().StringVar(&socket, "socket", "", "Listen on this Unix socket instead of stdin/stdout")
+
+	return cmd
+}
+
+func runDaemon(ctx context.Context, socket string) error {
+	ctx, shutdown := context.WithCancel(ctx)
+	defer shutdown()
+
+	apiClients = newClientCache()
+	defer func() { apiClients = nil }()
+
+	if socket == "" {
+		// Anything printed outside a result would corrupt the stream
+		stdout := os.Stdout
+		os.Stdout = os.Stderr
+		defer func() { os.Stdout = stdout }()
+
+		if err := newDaemonSession(shutdown).serve(ctx, os.Stdin, stdout); err != nil && ctx.Err() == nil {
+			return err
+		}
+		return nil
+	}
+
+	// A socket left by a daemon that didn't shut down cleanly
+	if info, err := os.Stat(socket); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", socket); err == nil {
+			conn.Close()
+			return fmt.Errorf("a daemon is already listening on %s", socket)
+		}
+		os.Remove(socket)
+	}
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", socket, err)
+	}
+	defer os.Remove(socket)
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	fmt.Fprintf(os.Stderr, "supa daemon listening on %s\n", socket)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+			// Closing the connection ends the session's read
+			stop := context.AfterFunc(ctx, func() { conn.Close() })
+			defer stop()
+			if err := newDaemonSession(shutdown).serve(ctx, conn, conn); err != nil && ctx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "daemon client: %v\n", err)
+			}
+		}()
+	}
+}
+
+// daemonSession is one client's connection and its watch subscription
+type daemonSession struct {
+	conn     *jsonrpc.Conn
+	shutdown context.CancelFunc
+
+	mu        sync.Mutex
+	stopWatch func() // set while subscribed
+}
+
+func newDaemonSession(shutdown context.CancelFunc) *daemonSession {
+	return &daemonSession{shutdown: shutdown}
+}
+
+func (s *daemonSession) serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.conn = jsonrpc.NewConn(r, w)
+	defer s.unsubscribe()
+	return s.conn.Serve(ctx, s.handle)
+}
+
+// Method params. Omitted fields take the command's flag defaults.
+type daemonStatusParams struct {
+	Profile string `json:"profile"`
+	Schemas string `json:"schemas"`
+}
+
+type daemonPullParams struct {
+	Profile   string `json:"profile"`
+	DryRun    bool   `json:"dry_run"`
+	TypesOnly bool   `json:"types_only"`
+	Schemas   string `json:"schemas"`
+	Migration bool   `json:"migration"`
+}
+
+type daemonPushParams struct {
+	Profile         string `json:"profile"`
+	DryRun          bool   `json:"dry_run"`
+	MigrationsOnly  bool   `json:"migrations_only"`
+	Force           bool   `json:"force"`
+	ContinueOnError bool   `json:"continue_on_error"`
+	Prune           bool   `json:"prune"`
+	NoLint          bool   `json:"no_lint"`
+}
+
+type daemonBranchesParams struct {
+	Profile string `json:"profile"`
+}
+
+type daemonWatchParams struct {
+	Profile       string `json:"profile"`
+	TypesInterval string `json:"types_interval"`
+	NoBranchWatch bool   `json:"no_branch_watch"`
+}
+
+func (s *daemonSession) handle(ctx context.Context, method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "status":
+		p := daemonStatusParams{Schemas: "public"}
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return runCommand(func() error { return runStatus(ctx, p.Profile, true, p.Schemas) })
+
+	case "pull":
+		p := daemonPullParams{Schemas: "public"}
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return runCommand(func() error {
+			return runPull(ctx, p.Profile, p.DryRun, true, p.TypesOnly, p.Schemas, p.Migration)
+		})
+
+	case "push":
+		var p daemonPushParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return runCommand(func() error {
+			return runPush(ctx, p.Profile, p.DryRun, true, true, p.MigrationsOnly, p.Force, p.ContinueOnError, p.Prune, p.NoLint)
+		})
+
+	case "branches":
+		var p daemonBranchesParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return runCommand(func() error {
+			ops, err := newBranchOps(p.Profile, false, true)
+			if err != nil {
+				return err
+			}
+			return ops.list(ctx)
+		})
+
+	case "watch/subscribe":
+		p := daemonWatchParams{TypesInterval: "0"}
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return s.subscribe(ctx, p)
+
+	case "watch/unsubscribe":
+		return map[string]bool{"subscribed": false}, s.unsubscribe()
+
+	case "reload":
+		apiClients.reset()
+		return map[string]bool{"reloaded": true}, nil
+
+	case "shutdown":
+		s.shutdown()
+		return map[string]bool{"shutdown": true}, nil
+	}
+
+	return nil, &jsonrpc.Error{Code: jsonrpc.MethodNotFound, Message: "method not found: " + method}
+}
+
+func decodeParams(params json.RawMessage, v any) error {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &jsonrpc.Error{Code: jsonrpc.InvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// runCommand runs a command in JSON mode and returns its result
+func runCommand(run func() error) (any, error) {
+	commandMu.Lock()
+	defer commandMu.Unlock()
+
+	var buf bytes.Buffer
+	previous := jsonOutput
+	jsonOutput = &buf
+	defer func() { jsonOutput = previous }()
+
+	err := run()
+	result := bytes.TrimSpace(buf.Bytes())
+
+	if err != nil {
+		rpcErr := &jsonrpc.Error{Code: commandFailed, Message: err.Error()}
+		if len(result) > 0 && json.Valid(result) {
+			rpcErr.Data = json.RawMessage(result)
+		}
+		return nil, rpcErr
+	}
+	if len(result) == 0 || !json.Valid(result) {
+		return nil, &jsonrpc.Error{Code: jsonrpc.InternalError, Message: "command wrote no JSON result"}
+	}
+	return json.RawMessage(result), nil
+}
+
+// subscribe starts a watch that sends its events to the client
+func (s *daemonSession) subscribe(ctx context.Context, p daemonWatchParams) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopWatch != nil {
+		return nil, &jsonrpc.Error{Code: commandFailed, Message: "already subscribed"}
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	var stopOnce sync.Once
+	stop := func() {
+		stopOnce.Do(func() {
+			cancel()
+			<-done
+		})
+	}
+	s.stopWatch = stop
+
+	go func() {
+		defer close(done)
+		// Failures to start are reported as a fatal watch_error event
+		runWatch(watchCtx, p.Profile, true, &eventNotifier{conn: s.conn}, p.TypesInterval, p.NoBranchWatch)
+
+		// Ended by itself: allow a new subscription
+		s.mu.Lock()
+		if ctx.Err() == nil && watchCtx.Err() == nil {
+			s.stopWatch = nil
+		}
+		s.mu.Unlock()
+	}()
+
+	return map[string]bool{"subscribed": true}, nil
+}
+
+// unsubscribe stops the session's watch, if any, once its last event is
+// sent
+func (s *daemonSession) unsubscribe() error {
+	s.mu.Lock()
+	stop := s.stopWatch
+	s.stopWatch = nil
+	s.mu.Unlock()
+
+	if stop != nil {
+		stop()
+	}
+	return nil
+}
+
+// eventNotifier turns each event line the watch writes into a
+// "watch/event" notification
+type eventNotifier struct {
+	conn *jsonrpc.Conn
+}
+
+func (n *eventNotifier) Write(p []byte) (int, error) {
+	if err := n.conn.Notify("watch/event", json.RawMessage(bytes.TrimSpace(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
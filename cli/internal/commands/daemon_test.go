@@ -0,0 +1,153 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/supabase/supabase-dx/cli/internal/backend"
+	"github.com/supabase/supabase-dx/cli/internal/jsonrpc"
+)
+
+// rpcClient is the editor's end of a daemon session
+type rpcClient struct {
+	t      *testing.T
+	w      io.Writer
+	r      *textproto.Reader
+	nextID int
+	events []string // types of the watch events received so far
+}
+
+// call sends a request and returns its response, collecting any watch
+// events that arrive first
+func (c *rpcClient) call(method string, params any) *jsonrpc.Message {
+	c.t.Helper()
+	c.nextID++
+	raw, _ := json.Marshal(params)
+	body, _ := json.Marshal(jsonrpc.Message{JSONRPC: jsonrpc.Version, ID: json.RawMessage(strconv.Itoa(c.nextID)), Method: method, Params: raw})
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		c.t.Fatalf("failed to send %s: %v", method, err)
+	}
+
+	for {
+		msg := c.read()
+		if msg.Method == "watch/event" {
+			var event struct {
+				Type string `json:"type"`
+			}
+			json.Unmarshal(msg.Params, &event)
+			c.events = append(c.events, event.Type)
+			continue
+		}
+		if string(msg.ID) != strconv.Itoa(c.nextID) {
+			c.t.Fatalf("expected a response to %d, got %+v", c.nextID, msg)
+		}
+		return msg
+	}
+}
+
+func (c *rpcClient) read() *jsonrpc.Message {
+	c.t.Helper()
+	read := make(chan *jsonrpc.Message, 1)
+	go func() {
+		defer close(read)
+		headers, err := c.r.ReadMIMEHeader()
+		if err != nil {
+			return
+		}
+		length, _ := strconv.Atoi(headers.Get("Content-Length"))
+		body := make([]byte, length)
+		if _, err := io.ReadFull(c.r.R, body); err != nil {
+			return
+		}
+		var msg jsonrpc.Message
+		if json.Unmarshal(body, &msg) == nil {
+			read <- &msg
+		}
+	}()
+
+	select {
+	case msg, ok := <-read:
+		if !ok {
+			c.t.Fatal("failed to read a message from the daemon")
+		}
+		return msg
+	case <-time.After(10 * time.Second):
+		c.t.Fatal("timed out waiting for the daemon")
+		return nil
+	}
+}
+
+// daemonClient serves a daemon session over pipes for the rest of the test
+func daemonClient(t *testing.T) *rpcClient {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	previous := apiClients
+	apiClients = newClientCache()
+	t.Cleanup(func() { apiClients = previous })
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		newDaemonSession(cancel).serve(ctx, inR, outW)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		inW.Close()
+		outR.Close()
+		<-done
+	})
+
+	return &rpcClient{t: t, w: inW, r: textproto.NewReader(bufio.NewReader(outR))}
+}
+
+func TestDaemonSession(t *testing.T) {
+	fake := backend.NewFake("localhost:54322/postgres")
+	pushed(t, fake, "20240101000000_init.sql", initSQL)
+	fakeProject(t, fake, map[string]string{"20240101000000_init.sql": initSQL})
+	client := daemonClient(t)
+
+	// A call answers with the command's --json output
+	resp := client.call("status", map[string]string{"profile": "local"})
+	if resp.Error != nil {
+		t.Fatalf("expected a status result, got %+v", resp.Error)
+	}
+	var status StatusResult
+	if err := json.Unmarshal(resp.Result, &status); err != nil || status.Profile != "local" || status.Migrations == nil || !status.Migrations.InSync {
+		t.Errorf("expected the local profile's status, got %s (%v)", resp.Result, err)
+	}
+
+	resp = client.call("watch/subscribe", map[string]string{"profile": "local"})
+	if resp.Error != nil {
+		t.Fatalf("expected to subscribe, got %+v", resp.Error)
+	}
+	resp = client.call("watch/subscribe", map[string]string{"profile": "local"})
+	if resp.Error == nil || resp.Error.Message != "already subscribed" {
+		t.Errorf("expected already subscribed, got %+v", resp)
+	}
+
+	// Unsubscribing answers once the watch has sent its last event
+	if resp = client.call("watch/unsubscribe", nil); resp.Error != nil {
+		t.Fatalf("expected to unsubscribe, got %+v", resp.Error)
+	}
+	if len(client.events) < 2 || client.events[0] != "watch_started" || client.events[len(client.events)-1] != "watch_stopped" {
+		t.Fatalf("expected watch_started through watch_stopped, got %v", client.events)
+	}
+
+	sent := len(client.events)
+	if resp = client.call("reload", nil); resp.Error != nil {
+		t.Fatalf("expected to reload, got %+v", resp.Error)
+	}
+	if len(client.events) != sent {
+		t.Errorf("expected no events after unsubscribing, got %v", client.events[sent:])
+	}
+}
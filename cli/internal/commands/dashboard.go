@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/supabase/supabase-dx/cli/internal/backend"
+	"github.com/supabase/supabase-dx/cli/internal/declarative"
+	"github.com/supabase/supabase-dx/cli/internal/migrations"
+	"github.com/supabase/supabase-dx/cli/internal/profiles"
+)
+
+// pullSchemas captures schema changes made in the Studio. The target's
+// managed schemas are read with read-only queries and written to
+// supabase/schemas, one file per kind of object. With withMigration, the
+// files from the last pull are applied to the shadow database and diffed
+// against the target; the difference is written as a migration and
+// recorded as applied, since the target already has it.
+func pullSchemas(ctx context.Context, target backend.Backend, cwd string, profile *profiles.Profile, dryRun, withMigration bool, result *PullResult) error {
+	remote, err := declarative.Introspect(ctx, declarative.QueryFunc(target.RunReadOnlyQuery), profile.ManagedSchemas())
+	if err != nil {
+		return err
+	}
+
+	if withMigration {
+		previous, err := declarative.Load(cwd)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", declarative.Dir, err)
+		}
+		// On the first pull there's nothing to compare with - the existing
+		// migrations are what built the schema
+		if len(previous) > 0 {
+			before, err := shadowCatalog(ctx, profile, previous)
+			if err != nil {
+				return fmt.Errorf("failed to apply the previous %s: %w", declarative.Dir, err)
+			}
+
+			delta := declarative.Diff(before, remote)
+			result.Schema = delta
+			if !delta.Empty() {
+				file := schemaMigrationFile("dashboard_changes")
+				result.SchemaMigration = file.Filename
+				if !dryRun {
+					if err := writeSchemaMigration(cwd, file, delta); err != nil {
+						return err
+					}
+					if err := recordMigration(ctx, target, file, delta.SQL()); err != nil {
+						// Unrecorded, push would apply it a second time
+						os.Remove(filepath.Join(cwd, migrations.Dir, file.Filename))
+						return fmt.Errorf("failed to record %s as applied: %w", file.Filename, err)
+					}
+				}
+			}
+		}
+	}
+
+	for _, source := range declarative.Dump(remote) {
+		path := filepath.Join(cwd, source.Path)
+		if existing, err := os.ReadFile(path); err == nil && string(existing) == source.SQL {
+			continue
+		}
+		result.SchemaFiles = append(result.SchemaFiles, source.Path)
+		if dryRun {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(source.SQL), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recordMigration adds a migration to the target's history without running
+// it, like `supabase migration repair --status applied`
+func recordMigration(ctx context.Context, target backend.Backend, file migrations.File, content string) error {
+	queries := []string{
+		"create schema if not exists supabase_migrations",
+		"create table if not exists supabase_migrations.schema_migrations (version text not null primary key, statements text[], name text)",
+		fmt.Sprintf("insert into supabase_migrations.schema_migrations (version, name, statements) values (%s, %s, array[%s])",
+			sqlLiteral(file.Version), sqlLiteral(file.Name), sqlLiteral(content)),
+	}
+	for _, query := range queries {
+		if _, err := target.RunQuery(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sqlLiteral quotes a string for SQL (standard_conforming_strings is on)
+func sqlLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// printSchemaPull reports the schema files and migration pull wrote
+func printSchemaPull(result PullResult, dryRun bool) {
+	verb := "updated"
+	if dryRun {
+		verb = "to update"
+	}
+	if len(result.SchemaFiles) == 0 {
+		fmt.Printf("  ✓ %s matches the database\n", declarative.Dir)
+	} else {
+		fmt.Printf("  Schema:     %d file(s) %s in %s\n", len(result.SchemaFiles), verb, declarative.Dir)
+		for _, f := range result.SchemaFiles {
+			fmt.Printf("    ~ %s\n", f)
+		}
+	}
+
+	if result.SchemaMigration != "" {
+		if dryRun {
+			fmt.Printf("  + %s (%d statement(s), would be recorded as applied)\n", result.SchemaMigration, len(result.Schema.Statements))
+		} else {
+			fmt.Printf("  ✓ Wrote %s and recorded it as applied\n", filepath.Join(migrations.Dir, result.SchemaMigration))
+		}
+	}
+	if result.Schema != nil {
+		for _, w := range result.Schema.Warnings {
+			fmt.Printf("  ⚠ %s\n", w)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/supabase/supabase-dx/cli/internal/backend"
+	"github.com/supabase/supabase-dx/cli/internal/declarative"
+	"github.com/supabase/supabase-dx/cli/internal/localdb"
+	"github.com/supabase/supabase-dx/cli/internal/migrations"
+	"github.com/supabase/supabase-dx/cli/internal/profiles"
+)
+
+// diffSchemas compares the target's managed schemas with supabase/schemas.
+// The target is read through read-only queries, the files through the
+// shadow database, so the diff only sees what the files declare.
+func diffSchemas(ctx context.Context, target backend.Backend, cwd string, profile *profiles.Profile) (*declarative.Migration, error) {
+	sources, err := declarative.Load(cwd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", declarative.Dir, err)
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no .sql files in %s", declarative.Dir)
+	}
+
+	current, err := declarative.Introspect(ctx, declarative.QueryFunc(target.RunReadOnlyQuery), profile.ManagedSchemas())
+	if err != nil {
+		return nil, err
+	}
+
+	desired, err := shadowCatalog(ctx, profile, sources)
+	if err != nil {
+		return nil, err
+	}
+
+	return declarative.Diff(current, desired), nil
+}
+
+// shadowCatalog applies schema files to a scratch database on the
+// profile's shadow server, dropped afterwards, and introspects the result
+func shadowCatalog(ctx context.Context, profile *profiles.Profile, sources []declarative.Source) (*declarative.Catalog, error) {
+	// The scratch database keeps a commit from reaching anything else, but
+	// a file that ends the transaction wouldn't describe a schema either
+	if err := declarative.CheckTransactions(sources); err != nil {
+		return nil, err
+	}
+
+	shadowURL := profile.ShadowDatabaseURL()
+	shadow, err := localdb.Connect(ctx, shadowURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to shadow database %s: %w", localdb.Describe(shadowURL), err)
+	}
+	defer shadow.Close(context.Background())
+
+	var catalog *declarative.Catalog
+	err = shadow.Scratch(ctx, func(scratch *localdb.DB) error {
+		for _, source := range sources {
+			if err := scratch.Exec(ctx, source.SQL); err != nil {
+				location := source.Path
+				if pos, ok := migrations.ErrorPosition(source.SQL, err.Error()); ok {
+					location = fmt.Sprintf("%s:%d", location, pos.Line)
+					if pos.Column > 0 {
+						location = fmt.Sprintf("%s:%d", location, pos.Column)
+					}
+				}
+				return fmt.Errorf("%s: %w", location, err)
+			}
+		}
+		catalog, err = declarative.Introspect(ctx, scratch, profile.ManagedSchemas())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return catalog, nil
+}
+
+// schemaMigrationFile names a generated migration
+func schemaMigrationFile(name string) migrations.File {
+	version := time.Now().UTC().Format("20060102150405")
+	return migrations.File{Filename: version + "_" + name + ".sql", Version: version, Name: name}
+}
+
+// writeSchemaMigration writes a generated migration to supabase/migrations
+func writeSchemaMigration(cwd string, file migrations.File, migration *declarative.Migration) error {
+	path := filepath.Join(cwd, migrations.Dir, file.Filename)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(migration.SQL()), 0644)
+}
+
+// printSchemaMigration shows the statements generated from supabase/schemas
+func printSchemaMigration(file migrations.File, migration *declarative.Migration) {
+	if migration.Empty() {
+		fmt.Printf("  Schema: target matches %s\n", declarative.Dir)
+	} else {
+		fmt.Printf("  Schema: %d statement(s) from %s → %s\n", len(migration.Statements), declarative.Dir, file.Filename)
+	}
+	for _, s := range migration.Statements {
+		for _, line := range strings.Split(s, "\n") {
+			fmt.Printf("    %s\n", line)
+		}
+	}
+	for _, w := range migration.Warnings {
+		fmt.Printf("  ⚠ %s\n", w)
+	}
+}
@@ -0,0 +1,22 @@
+package commands
+
+import "fmt"
+
+// ExitError makes the process exit with Code after the command has already
+// reported the failure itself (e.g. as a JSON result), so main shouldn't
+// print it again
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,252 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/supabase/supabase-dx/cli/internal/api"
+	"github.com/supabase/supabase-dx/cli/internal/backend"
+	"github.com/supabase/supabase-dx/cli/internal/git"
+	"github.com/supabase/supabase-dx/cli/internal/lint"
+	"github.com/supabase/supabase-dx/cli/internal/migrations"
+	"github.com/supabase/supabase-dx/cli/internal/profiles"
+)
+
+type LintResult struct {
+	Status     string         `json:"status"`
+	Message    string         `json:"message"`
+	Profile    string         `json:"profile,omitempty"`
+	ProjectRef string         `json:"project_ref,omitempty"`
+	Files      []string       `json:"files"`
+	Findings   []lint.Finding `json:"findings"`
+	Errors     int            `json:"errors"`
+	Warnings   int            `json:"warnings"`
+	Error      string         `json:"error,omitempty"`
+	ErrorCode  string         `json:"error_code,omitempty"`
+}
+
+func NewLintCmd(profile *string, jsonOut *bool) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lint [migration...]",
+		Short: "Check pending migrations for unsafe DDL",
+		Long: `Lint parses the migrations push would apply (or the given files in
+supabase/migrations, by name or path) and flags risky operations:
+
+  drop_table                DROP TABLE                                  error
+  drop_column               ALTER TABLE ... DROP COLUMN                 error
+  alter_column_type         ALTER COLUMN ... TYPE, which rewrites       warning
+  index_not_concurrent      CREATE INDEX without CONCURRENTLY on a      warning
+                            large table (large_table_rows, from the
+                            target's estimates; default 100000)
+  not_null_without_default  ADD COLUMN ... NOT NULL without a default,  error
+                            and SET NOT NULL
+  table_without_rls         CREATE TABLE in an exposed schema without   error
+                            ENABLE ROW LEVEL SECURITY, or DISABLE
+
+Statements on tables created by the same migrations are not flagged. Push
+runs the same checks and refuses to apply anything while there are errors.
+
+Rules are configured per profile:
+
+  [profiles.prod.lint]
+  rules = { drop_column = "warning", alter_column_type = "off" }
+  exposed_schemas = ["public", "api"]
+  large_table_rows = 50000
+
+Exits non-zero when there are errors.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLint(cmd.Context(), *profile, *jsonOut, args)
+		},
+	}
+
+	return cmd
+}
+
+func runLint(ctx context.Context, profileName string, jsonOut bool, files []string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return lintError(jsonOut, "failed to get working directory", err)
+	}
+
+	cfg, err := profiles.LoadConfig(cwd)
+	if err != nil {
+		return lintError(jsonOut, "failed to load config", err)
+	}
+
+	currentBranch, _ := git.GetCurrentBranch(cwd)
+
+	profile, selectedName, err := cfg.GetProfileOrAuto(profileName, currentBranch)
+	if err != nil {
+		return lintError(jsonOut, "failed to get profile", err)
+	}
+
+	// Preview profiles work on the branch mapped to the current git branch
+	target, closeTarget, err := openBackend(ctx, cfg, profile, cwd, false, jsonOut)
+	if err != nil {
+		message, cause := openFailure(err)
+		return lintError(jsonOut, message, cause)
+	}
+	defer closeTarget()
+	projectRef, _ := describeBackend(target)
+
+	var pending []migrations.File
+	if len(files) > 0 {
+		dir := filepath.Join(cwd, migrations.Dir)
+		for _, file := range files {
+			// A bare filename is looked up in supabase/migrations
+			filename := file
+			if filepath.Base(file) != file {
+				path, err := filepath.Abs(file)
+				if err != nil || filepath.Dir(path) != dir {
+					return lintError(jsonOut, fmt.Sprintf("%s is not in %s", file, migrations.Dir), nil)
+				}
+				filename = filepath.Base(path)
+			}
+			version, name, ok := migrations.ParseFilename(filename)
+			if !ok {
+				return lintError(jsonOut, fmt.Sprintf("%s is not a migration", file), nil)
+			}
+			pending = append(pending, migrations.File{Filename: filename, Version: version, Name: name})
+		}
+	} else {
+		local, _, err := migrations.List(cwd)
+		if err != nil {
+			return lintError(jsonOut, "failed to read migrations", err)
+		}
+		remote, err := target.ListMigrations(ctx)
+		if err != nil {
+			return lintError(jsonOut, "failed to fetch remote migrations", err)
+		}
+		pending = migrations.Compare(local, remote).Pending
+	}
+
+	findings, err := lintMigrations(ctx, target, cwd, profile, pending)
+	if err != nil {
+		return lintError(jsonOut, "failed to lint migrations", err)
+	}
+	if findings == nil {
+		findings = []lint.Finding{}
+	}
+
+	result := LintResult{
+		Status:     "success",
+		Profile:    selectedName,
+		ProjectRef: projectRef,
+		Files:      []string{},
+		Findings:   findings,
+		Errors:     lint.Errors(findings),
+		Warnings:   len(findings) - lint.Errors(findings),
+	}
+	for _, f := range pending {
+		result.Files = append(result.Files, f.Filename)
+	}
+
+	switch {
+	case len(pending) == 0:
+		result.Message = "No pending migrations"
+	case result.Errors > 0:
+		result.Status = "error"
+		result.Message = fmt.Sprintf("%d error(s), %d warning(s) in %d migration(s)", result.Errors, result.Warnings, len(pending))
+		failed := &lint.FailedError{Findings: findings}
+		result.Error = failed.Error()
+		result.ErrorCode = failed.ErrorCode()
+	default:
+		result.Message = fmt.Sprintf("%d warning(s) in %d migration(s)", result.Warnings, len(pending))
+	}
+
+	if jsonOut {
+		printJSON(result)
+	} else {
+		fmt.Printf("🔍 Linting %d migration(s) for profile %s\n", len(pending), selectedName)
+		fmt.Println()
+		if len(findings) > 0 {
+			printLintFindings(findings)
+			fmt.Println()
+		}
+		if result.Errors > 0 {
+			fmt.Printf("✗ %s\n", result.Message)
+		} else if result.Warnings > 0 {
+			fmt.Printf("⚠ %s\n", result.Message)
+		} else {
+			fmt.Println("✓ No problems found")
+		}
+	}
+
+	if result.Errors > 0 {
+		return &ExitError{Code: 1, Err: &lint.FailedError{Findings: findings}}
+	}
+	return nil
+}
+
+// lintMigrations checks migrations about to be applied to target, with the
+// profile's rules. Migrations not written yet are passed in unsaved, by
+// filename.
+func lintMigrations(ctx context.Context, target backend.Backend, cwd string, profile *profiles.Profile, files []migrations.File, unsaved ...lint.Source) ([]lint.Finding, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	sources := make([]lint.Source, 0, len(files))
+	for _, f := range files {
+		source := lint.Source{File: f.Filename}
+		for _, u := range unsaved {
+			if u.File == f.Filename {
+				source = u
+			}
+		}
+		if source.SQL == "" {
+			content, err := os.ReadFile(filepath.Join(cwd, migrations.Dir, f.Filename))
+			if err != nil {
+				return nil, err
+			}
+			source.SQL = string(content)
+		}
+		sources = append(sources, source)
+	}
+
+	opts := lint.Options{
+		Levels:         profile.Lint.Rules,
+		ExposedSchemas: profile.Lint.ExposedSchemas,
+		LargeTableRows: profile.Lint.LargeTableRows,
+	}
+	// Without estimates every index built without CONCURRENTLY is flagged
+	opts.TableRows, _ = lint.TableRows(ctx, target)
+
+	return lint.Lint(sources, opts)
+}
+
+func printLintFindings(findings []lint.Finding) {
+	for _, f := range findings {
+		marker := "⚠"
+		if f.Level == lint.LevelError {
+			marker = "✗"
+		}
+		fmt.Printf("  %s %s [%s]\n", marker, f.Location(), f.Rule)
+		fmt.Printf("    %s\n", f.Message)
+	}
+}
+
+func lintError(jsonOut bool, message string, err error) error {
+	if jsonOut {
+		result := LintResult{
+			Status:   "error",
+			Message:  message,
+			Files:    []string{},
+			Findings: []lint.Finding{},
+		}
+		if err != nil {
+			result.Error = err.Error()
+			result.ErrorCode = api.ErrorCode(err)
+		}
+		printJSON(result)
+		return &ExitError{Code: 1, Err: fmt.Errorf("%s", message)}
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", message, err)
+	}
+	return fmt.Errorf("%s", message)
+}
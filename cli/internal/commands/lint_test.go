@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/supabase/supabase-dx/cli/internal/backend"
+)
+
+func TestLintFileArguments(t *testing.T) {
+	fakeProject(t, backend.NewFake("fake"), map[string]string{"20240101000000_init.sql": initSQL})
+	out := captureJSON(t)
+
+	for _, file := range []string{
+		"20240101000000_init.sql",
+		filepath.Join("supabase", "migrations", "20240101000000_init.sql"),
+	} {
+		out.Reset()
+		if err := runLint(context.Background(), "staging", true, []string{file}); err != nil {
+			t.Fatalf("%s: expected no error, got %v", file, err)
+		}
+		var result LintResult
+		if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+			t.Fatalf("%s: expected a JSON result, got %q", file, out.String())
+		}
+		if strings.Join(result.Files, ",") != "20240101000000_init.sql" {
+			t.Errorf("%s: expected init to be linted, got %+v", file, result)
+		}
+	}
+
+	// A path elsewhere isn't quietly swapped for the migration of that name
+	out.Reset()
+	if err := runLint(context.Background(), "staging", true, []string{filepath.Join("other", "20240101000000_init.sql")}); err == nil {
+		t.Errorf("expected an error for a file outside supabase/migrations, got %s", out)
+	}
+}
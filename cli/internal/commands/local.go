@@ -0,0 +1,267 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/supabase/supabase-dx/cli/internal/api"
+	"github.com/supabase/supabase-dx/cli/internal/backend"
+	"github.com/supabase/supabase-dx/cli/internal/migrations"
+	"github.com/supabase/supabase-dx/cli/internal/profiles"
+)
+
+// localPull is a project migration missing from the local database
+type localPull struct {
+	file    migrations.File
+	content []byte
+	write   bool // no file in supabase/migrations yet
+}
+
+// runPullLocal brings a local profile's database up to date with the
+// project: migrations applied to the project but not locally are applied in
+// version order, and written to supabase/migrations when there's no file
+// for them yet. Types are then generated from the local database.
+func runPullLocal(ctx context.Context, db backend.Backend, cwd string, cfg *profiles.Config, profile *profiles.Profile, selectedName string, dryRun, jsonOut, typesOnly bool, schemas string, withMigration bool) error {
+	result := PullResult{
+		Status:   "success",
+		Profile:  selectedName,
+		Database: db.Describe(),
+		DryRun:   dryRun,
+	}
+
+	if typesOnly {
+		return pullTypes(ctx, db, schemas, cwd, dryRun, jsonOut, result)
+	}
+
+	// Without a project there is nothing to sync from; types still work
+	project, err := openProject(cfg, profile)
+	if err != nil {
+		message, cause := openFailure(err)
+		return outputError(jsonOut, message, cause)
+	}
+	var pending []localPull
+	if project != nil {
+		result.ProjectRef = project.Describe()
+		pending, err = planLocalPull(ctx, project, db, cwd)
+		if err != nil {
+			return outputError(jsonOut, "failed to compare migrations", err)
+		}
+	}
+
+	for _, p := range pending {
+		if dryRun {
+			result.MigrationsPulled = append(result.MigrationsPulled, p.file.Filename)
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		if p.write {
+			path := filepath.Join(cwd, migrations.Dir, p.file.Filename)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+				err = os.WriteFile(path, p.content, 0644)
+			}
+			if err != nil {
+				result.Failures = append(result.Failures, newMigrationFailure(p.file, nil, err))
+				break
+			}
+		}
+
+		req := api.ApplyMigrationRequest{Query: string(p.content), Name: p.file.BaseName()}
+		if err := db.ApplyMigration(ctx, req); err != nil {
+			result.Failures = append(result.Failures, newMigrationFailure(p.file, p.content, err))
+			break
+		}
+		result.MigrationsPulled = append(result.MigrationsPulled, p.file.Filename)
+	}
+
+	// Capture schema changes made in the local Studio
+	if profile.IsDashboard() && len(result.Failures) == 0 {
+		if err := pullSchemas(ctx, db, cwd, profile, dryRun, withMigration, &result); err != nil {
+			return outputError(jsonOut, "failed to pull schema", err)
+		}
+	}
+
+	if !dryRun && len(result.Failures) == 0 {
+		typesResp, err := db.GetTypescriptTypes(ctx, schemas)
+		if err != nil {
+			if !jsonOut {
+				fmt.Printf("  ⚠ Could not generate types: %v\n", err)
+			}
+		} else if err := writeTypes(cwd, typesResp.Types); err == nil {
+			result.TypesWritten = true
+		}
+	}
+
+	if len(result.Failures) > 0 {
+		first := result.Failures[0]
+		result.Status = "error"
+		result.Error = first.Error
+		result.ErrorCode = first.ErrorCode
+		result.Message = fmt.Sprintf("Applied %d of %d migrations; failed at %s", len(result.MigrationsPulled), len(pending), first.Location())
+	} else if dryRun {
+		result.Message = fmt.Sprintf("Dry run - %d migration(s) to apply locally", len(pending))
+	} else {
+		result.Message = fmt.Sprintf("Applied %d migration(s) to the local database", len(result.MigrationsPulled))
+	}
+
+	if jsonOut {
+		printJSON(result)
+		if result.Status != "success" {
+			return &ExitError{Code: 1, Err: fmt.Errorf("%s", result.Message)}
+		}
+		return nil
+	}
+
+	fmt.Println("📥 Pull completed")
+	fmt.Println()
+	fmt.Printf("  Profile:    %s\n", selectedName)
+	fmt.Printf("  Database:   %s (local)\n", result.Database)
+	if result.ProjectRef != "" {
+		fmt.Printf("  Project:    %s\n", result.ProjectRef)
+	} else {
+		fmt.Println("  Project:    none configured - only types were generated")
+	}
+	fmt.Println()
+
+	if len(pending) > 0 {
+		fmt.Printf("  Migrations: %d to apply locally\n", len(pending))
+		marker := "✓"
+		if dryRun {
+			marker = "+"
+		}
+		for _, f := range result.MigrationsPulled {
+			fmt.Printf("    %s %s\n", marker, f)
+		}
+		for _, f := range result.Failures {
+			printMigrationFailure(f)
+		}
+		fmt.Println()
+	} else if result.ProjectRef != "" {
+		fmt.Println("  ✓ Local database has all project migrations")
+	}
+
+	if profile.IsDashboard() && len(result.Failures) == 0 {
+		printSchemaPull(result, dryRun)
+	}
+
+	if result.TypesWritten {
+		fmt.Println("  ✓ TypeScript types written to supabase/types/database.ts")
+	}
+
+	if dryRun {
+		fmt.Println("\n  (dry-run mode - no changes applied)")
+	}
+
+	if result.Status != "success" {
+		return fmt.Errorf("pull incomplete: %s", result.Message)
+	}
+	return nil
+}
+
+// planLocalPull lists the project's migrations that the local database
+// hasn't applied, oldest first, with the SQL to apply: the local file if
+// there is one, otherwise the statements the project recorded
+func planLocalPull(ctx context.Context, project, db backend.Backend, cwd string) ([]localPull, error) {
+	remote, err := project.ListMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(remote, func(i, j int) bool { return remote[i].Version < remote[j].Version })
+
+	applied, err := db.ListMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	files, _, err := migrations.List(cwd)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []localPull
+	for _, m := range remote {
+		p := localPull{write: true}
+		for _, f := range files {
+			if migrations.Matches(f, m) {
+				p.file = f
+				p.write = false
+				break
+			}
+		}
+		if p.write {
+			if p.file, err = remoteMigrationFile(m); err != nil {
+				return nil, err
+			}
+		}
+
+		done := false
+		for _, a := range applied {
+			if migrations.Matches(p.file, a) {
+				done = true
+				break
+			}
+		}
+		if done {
+			continue
+		}
+
+		if p.write {
+			detail, err := project.GetMigration(ctx, m.Version)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch migration %s: %w", m.Version, err)
+			}
+			p.content = []byte(joinStatements(detail.Statements))
+		} else {
+			p.content, err = os.ReadFile(filepath.Join(cwd, migrations.Dir, p.file.Filename))
+			if err != nil {
+				return nil, err
+			}
+		}
+		pending = append(pending, p)
+	}
+
+	return pending, nil
+}
+
+// remoteMigrationFile names the file for a project migration that has none.
+// Migrations pushed by supa are named after their file, so that name is
+// kept; others get "<version>_<name>.sql". Names that would leave the
+// migrations directory are refused.
+func remoteMigrationFile(m api.Migration) (migrations.File, error) {
+	filename := m.Version + ".sql"
+	if _, _, ok := migrations.ParseFilename(m.Name + ".sql"); ok {
+		filename = m.Name + ".sql"
+	} else if m.Name != "" {
+		filename = m.Version + "_" + m.Name + ".sql"
+	}
+
+	if strings.ContainsAny(filename, `/\`) {
+		return migrations.File{}, fmt.Errorf("migration %s has an unsafe name %q", m.Version, m.Name)
+	}
+
+	version, name, _ := migrations.ParseFilename(filename)
+	return migrations.File{Filename: filename, Version: version, Name: name}, nil
+}
+
+// joinStatements turns recorded migration statements back into a file
+func joinStatements(statements []string) string {
+	var b strings.Builder
+	for _, s := range statements {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		b.WriteString(s)
+		if !strings.HasSuffix(s, ";") {
+			b.WriteString(";")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
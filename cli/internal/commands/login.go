@@ -2,13 +2,15 @@ package commands
 
 import (
 	"bufio"
-	"encoding/json"
+	"context"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/supabase/supabase-dx/cli/internal/api"
+	"github.com/supabase/supabase-dx/cli/internal/auth"
 	"github.com/supabase/supabase-dx/cli/internal/config"
 )
 
@@ -17,28 +19,172 @@ type LoginResult struct {
 	Message string `json:"message"`
 }
 
+// loginTimeout bounds how long we wait for the browser callback
+const loginTimeout = 5 * time.Minute
+
 func NewLoginCmd(jsonOut *bool) *cobra.Command {
+	var usePAT bool
+	var noBrowser bool
+	var clientID string
+	var account string
+
 	cmd := &cobra.Command{
 		Use:   "login",
 		Short: "Authenticate with Supabase",
-		Long: `Authenticate with Supabase using a Personal Access Token (PAT).
+		Long: `Authenticate with Supabase.
+
+When an OAuth client is configured (--client-id or SUPABASE_OAUTH_CLIENT_ID),
+this opens your browser to authorize the CLI via OAuth (PKCE). The resulting
+tokens are refreshed automatically when they expire. Otherwise you are asked
+for a Personal Access Token.
+
+Use --no-browser on headless machines: the authorization URL is printed and
+you can paste the URL you are redirected to back into the terminal.
 
-You can generate a PAT at: https://supabase.com/dashboard/account/tokens
+Use --token to paste a Personal Access Token (PAT) instead. You can generate
+one at: https://supabase.com/dashboard/account/tokens
 
-The token will be stored in ~/.supabase-dx/config.json.
+Use --account to keep several logins side by side (e.g. a personal and a
+work organization). Profiles pick one with account = "<name>" in
+supabase/config.toml; otherwise the current account is used.
+
+Credentials are stored in ~/.supabase-dx/config.json by default. Run
+'supa accounts migrate keyring' to move them into the OS keyring, or
+'supa accounts migrate encrypted' for a passphrase-protected file.
 Alternatively, set the SUPABASE_ACCESS_TOKEN environment variable.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if *jsonOut {
 				return loginJSON()
 			}
-			return loginInteractive()
+			if usePAT {
+				return loginInteractive(cmd.Context(), account)
+			}
+
+			if clientID == "" {
+				clientID, _ = config.GetOAuthClient()
+			}
+			if clientID == "" {
+				// No OAuth app to authorize against - ask for a PAT
+				return loginInteractive(cmd.Context(), account)
+			}
+			_, secret := config.GetOAuthClient()
+
+			return loginOAuth(cmd.Context(), api.OAuthApp{ClientID: clientID, ClientSecret: secret}, noBrowser, account)
 		},
 	}
 
+	cmd.Flags().BoolVar(&usePAT, "token", false, "Log in with a Personal Access Token instead of the browser")
+	cmd.Flags().BoolVar(&noBrowser, "no-browser", false, "Print the authorization URL instead of opening a browser")
+	cmd.Flags().StringVar(&clientID, "client-id", "", "OAuth client ID (defaults to SUPABASE_OAUTH_CLIENT_ID)")
+	cmd.Flags().StringVar(&account, "account", "", "Name to store this login under (default: current account)")
+
 	return cmd
 }
 
-func loginInteractive() error {
+func loginOAuth(ctx context.Context, app api.OAuthApp, noBrowser bool, account string) error {
+	pkce, err := auth.NewPKCE()
+	if err != nil {
+		return err
+	}
+	state, err := auth.NewState()
+	if err != nil {
+		return err
+	}
+
+	server, err := auth.StartCallbackServer(state)
+	if err != nil {
+		return err
+	}
+	defer server.Close()
+
+	client := api.NewClient("")
+	authURL := client.AuthorizeURL(app, server.RedirectURI(), state, pkce.Challenge)
+
+	ctx, cancel := context.WithTimeout(ctx, loginTimeout)
+	defer cancel()
+
+	// The callback server and the pasted URL race; whichever loses gives up
+	// once ctx is cancelled on return
+	codes := make(chan string)
+	errs := make(chan error)
+	deliver := func(code string, err error) {
+		if err != nil {
+			select {
+			case errs <- err:
+			case <-ctx.Done():
+			}
+			return
+		}
+		select {
+		case codes <- code:
+		case <-ctx.Done():
+		}
+	}
+
+	go func() {
+		deliver(server.Wait(ctx))
+	}()
+
+	if noBrowser {
+		fmt.Println("Open this URL in a browser to authorize the CLI:")
+		fmt.Printf("\n  %s\n\n", authURL)
+		fmt.Println("If the browser can't reach this machine, paste the URL you were redirected to:")
+		fmt.Print("> ")
+
+		// Headless fallback: the redirect URL can be pasted instead
+		go func() {
+			line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil || strings.TrimSpace(line) == "" {
+				return
+			}
+			deliver(auth.ParseCallbackURL(line, state))
+		}()
+	} else {
+		fmt.Println("Opening your browser to authorize the CLI...")
+		if err := auth.OpenBrowser(authURL); err != nil {
+			fmt.Println("Could not open a browser. Visit this URL instead:")
+		} else {
+			fmt.Println("If nothing happened, visit this URL:")
+		}
+		fmt.Printf("\n  %s\n\n", authURL)
+		fmt.Println("Waiting for authorization...")
+	}
+
+	var code string
+	select {
+	case code = <-codes:
+	case err := <-errs:
+		return fmt.Errorf("login failed: %w", err)
+	case <-ctx.Done():
+		return fmt.Errorf("login failed: %w", ctx.Err())
+	}
+
+	token, err := client.ExchangeCode(ctx, app, code, pkce.Verifier, server.RedirectURI())
+	if err != nil {
+		return fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+
+	// Verify the token works
+	projects, err := api.NewClient(token.AccessToken).ListProjects(ctx)
+	if err != nil {
+		return fmt.Errorf("token verification failed: %w", err)
+	}
+
+	name, err := saveAccount(account, &config.Account{
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		ExpiresAt:    token.ExpiresAt(time.Now()).Unix(),
+		ClientID:     app.ClientID,
+	})
+	if err != nil {
+		return err
+	}
+
+	printLoggedIn(name, len(projects))
+	return nil
+}
+
+func loginInteractive(ctx context.Context, account string) error {
 	fmt.Println("Enter your Supabase Personal Access Token (PAT):")
 	fmt.Println("You can generate one at: https://supabase.com/dashboard/account/tokens")
 	fmt.Print("\nToken: ")
@@ -57,29 +203,61 @@ func loginInteractive() error {
 	// Verify the token works
 	fmt.Println("\nVerifying token...")
 	client := api.NewClient(token)
-	projects, err := client.ListProjects()
+	projects, err := client.ListProjects(ctx)
 	if err != nil {
+		if api.IsUnauthorized(err) {
+			return fmt.Errorf("token verification failed: the token is invalid or has been revoked")
+		}
 		return fmt.Errorf("token verification failed: %w", err)
 	}
 
 	// Save the token
-	cfg := &config.GlobalConfig{
-		AccessToken: token,
-	}
-	if err := config.Save(cfg); err != nil {
-		return fmt.Errorf("failed to save token: %w", err)
+	name, err := saveAccount(account, &config.Account{AccessToken: token})
+	if err != nil {
+		return err
 	}
 
-	fmt.Printf("\n✓ Logged in successfully! Found %d projects.\n", len(projects))
+	printLoggedIn(name, len(projects))
 	return nil
 }
 
+// saveAccount stores credentials under the given account name, defaulting to
+// the current account. Returns the resolved name.
+func saveAccount(name string, account *config.Account) (string, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return "", err
+	}
+
+	if name == "" {
+		name = cfg.CurrentAccount
+	}
+	if name == "" {
+		name = config.DefaultAccountName
+	}
+
+	if err := cfg.StoreAccount(name, account); err != nil {
+		return "", fmt.Errorf("failed to save token: %w", err)
+	}
+
+	return name, nil
+}
+
+func printLoggedIn(account string, projects int) {
+	fmt.Printf("\n✓ Logged in as account %q! Found %d projects.\n", account, projects)
+
+	cfg, err := config.Load()
+	if err == nil && cfg.CurrentAccount != account {
+		fmt.Printf("  Current account is still %q. Run 'supa accounts use %s' to switch,\n", cfg.CurrentAccount, account)
+		fmt.Printf("  or set account = %q on a profile in supabase/config.toml.\n", account)
+	}
+}
+
 func loginJSON() error {
 	result := LoginResult{
 		Status:  "requires_input",
 		Message: "Login requires interactive input. Please run without --json flag.",
 	}
-	out, _ := json.MarshalIndent(result, "", "  ")
-	fmt.Println(string(out))
+	printJSON(result)
 	return nil
 }
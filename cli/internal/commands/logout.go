@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/supabase/supabase-dx/cli/internal/api"
+	"github.com/supabase/supabase-dx/cli/internal/config"
+)
+
+type LogoutResult struct {
+	Status    string `json:"status"`
+	Message   string `json:"message"`
+	Account   string `json:"account,omitempty"`
+	Revoked   bool   `json:"revoked"`
+	Warning   string `json:"warning,omitempty"` // set when the account was removed but its token couldn't be revoked
+	Error     string `json:"error,omitempty"`
+	ErrorCode string `json:"error_code,omitempty"`
+}
+
+func NewLogoutCmd(jsonOut *bool) *cobra.Command {
+	var account string
+
+	cmd := &cobra.Command{
+		Use:   "logout",
+		Short: "Log out and revoke stored credentials",
+		Long: `Removes the stored credentials for an account (the current one by
+default) from the configured credential store.
+
+If you logged in through the browser, the refresh token is revoked with
+Supabase first so it can no longer be used. Personal access tokens must be
+revoked from the dashboard.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return logoutError(*jsonOut, "failed to load config", err)
+			}
+
+			name := account
+			if name == "" {
+				name = cfg.CurrentAccount
+			}
+
+			result := LogoutResult{Status: "success", Account: name, Message: "Logged out"}
+
+			if name == "" {
+				result.Message = "Not logged in"
+			} else {
+				acct, ok := cfg.Accounts[name]
+				if !ok {
+					return logoutError(*jsonOut, fmt.Sprintf("account %q not found", name), nil)
+				}
+				isOAuth := acct.IsOAuth()
+
+				revokeErr, err := removeAccount(cmd.Context(), cfg, name, *jsonOut)
+				if err != nil {
+					return logoutError(*jsonOut, "failed to remove account", err)
+				}
+				if revokeErr != nil {
+					// The credentials are gone either way; the token may outlive them
+					result.Warning = fmt.Sprintf("could not revoke token: %v", revokeErr)
+				} else {
+					result.Revoked = isOAuth
+				}
+			}
+
+			if *jsonOut {
+				printJSON(result)
+				return nil
+			}
+
+			if name == "" {
+				fmt.Println("Not logged in")
+			} else {
+				fmt.Printf("✓ Logged out of account %q\n", name)
+				if cfg.CurrentAccount != "" {
+					fmt.Printf("  Current account is now %q\n", cfg.CurrentAccount)
+				}
+			}
+			if os.Getenv("SUPABASE_ACCESS_TOKEN") != "" {
+				fmt.Println("  Note: SUPABASE_ACCESS_TOKEN is still set in your environment")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&account, "account", "", "Account to log out of (default: current account)")
+
+	return cmd
+}
+
+func logoutError(jsonOut bool, message string, err error) error {
+	if jsonOut {
+		result := LogoutResult{
+			Status:  "error",
+			Message: message,
+		}
+		if err != nil {
+			result.Error = err.Error()
+			result.ErrorCode = api.ErrorCode(err)
+		}
+		printJSON(result)
+		return &ExitError{Code: 1, Err: fmt.Errorf("%s", message)}
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", message, err)
+	}
+	return fmt.Errorf("%s", message)
+}
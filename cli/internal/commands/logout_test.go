@@ -0,0 +1,32 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestLogoutUnknownAccountJSON(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	out := captureJSON(t)
+
+	jsonOut := true
+	cmd := NewLogoutCmd(&jsonOut)
+	cmd.SetArgs([]string{"--account", "nope"})
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	err := cmd.Execute()
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 1 {
+		t.Fatalf("expected exit code 1, got %v", err)
+	}
+
+	var result LogoutResult
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("expected a JSON result, got %q", out.String())
+	}
+	if result.Status != "error" || result.Message != `account "nope" not found` {
+		t.Errorf("expected an account not found error, got %+v", result)
+	}
+}
@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/supabase/supabase-dx/cli/internal/api"
+	"github.com/supabase/supabase-dx/cli/internal/git"
+	"github.com/supabase/supabase-dx/cli/internal/lsp"
+	"github.com/supabase/supabase-dx/cli/internal/profiles"
+)
+
+func NewLSPCmd(profile *string) *cobra.Command {
+	var offline bool
+
+	cmd := &cobra.Command{
+		Use:   "lsp",
+		Short: "Language server with diagnostics for migration and schema files",
+		Long: `LSP runs a language server on stdin/stdout that checks the SQL files in
+supabase/migrations and supabase/schemas as you edit them, so problems show
+up in the editor before 'supa push':
+
+- Migration filenames without a version, or whose version isn't a
+  YYYYMMDDHHMMSS timestamp
+- Migrations sharing a version
+- Pending migrations older than the latest applied one (fetched from the
+  active profile's target when the server starts and when a migration is
+  saved; skipped with --offline)
+- SQL syntax errors, from the Postgres parser
+
+Point your editor's generic LSP client at 'supa lsp' for .sql files.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLSP(cmd.Context(), *profile, offline)
+		},
+	}
+
+	cmd.Flags().BoolVar(&offline, "offline", false, "Don't fetch the migration history")
+
+	return cmd
+}
+
+func runLSP(ctx context.Context, profileName string, offline bool) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	var history lsp.HistoryFunc
+	if !offline {
+		apiClients = newClientCache()
+		defer func() { apiClients = nil }()
+		history = func(ctx context.Context) ([]api.Migration, error) {
+			return appliedMigrations(ctx, cwd, profileName)
+		}
+	}
+
+	// Anything printed outside a message would corrupt the stream
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	defer func() { os.Stdout = stdout }()
+
+	return lsp.NewServer(cwd, history).Serve(ctx, os.Stdin, stdout)
+}
+
+// appliedMigrations returns the migration history of the profile's target,
+// resolved again on every call so git branch switches are followed
+func appliedMigrations(ctx context.Context, cwd, profileName string) ([]api.Migration, error) {
+	cfg, err := profiles.LoadConfig(cwd)
+	if err != nil {
+		return nil, err
+	}
+
+	currentBranch, _ := git.GetCurrentBranch(cwd)
+	profile, _, err := cfg.GetProfileOrAuto(profileName, currentBranch)
+	if err != nil {
+		return nil, err
+	}
+
+	target, closeTarget, err := openBackend(ctx, cfg, profile, cwd, false, true)
+	if err != nil {
+		return nil, err
+	}
+	defer closeTarget()
+
+	return target.ListMigrations(ctx)
+}
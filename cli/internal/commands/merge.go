@@ -0,0 +1,449 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/supabase/supabase-dx/cli/internal/api"
+	"github.com/supabase/supabase-dx/cli/internal/git"
+	"github.com/supabase/supabase-dx/cli/internal/profiles"
+)
+
+type MergeResult struct {
+	Status        string    `json:"status"`
+	Message       string    `json:"message"`
+	Source        *MergeEnv `json:"source,omitempty"`
+	Target        *MergeEnv `json:"target,omitempty"`
+	Operation     string    `json:"operation,omitempty"` // merge, push, reset
+	DryRun        bool      `json:"dry_run"`
+	Diff          string    `json:"diff,omitempty"`
+	Functions     []string  `json:"functions,omitempty"` // slugs of edge functions that differ
+	WorkflowRunID string    `json:"workflow_run_id,omitempty"`
+	BranchStatus  string    `json:"branch_status,omitempty"` // final status of the updated branch
+	Error         string    `json:"error,omitempty"`
+	ErrorCode     string    `json:"error_code,omitempty"`
+}
+
+// MergeEnv is one side of a merge: a profile or branch name resolved to a
+// branch of the main project
+type MergeEnv struct {
+	Name       string `json:"name"`
+	BranchName string `json:"branch_name"`
+	BranchID   string `json:"branch_id"`
+	ProjectRef string `json:"project_ref"`
+	Default    bool   `json:"default"` // the main (production) branch
+}
+
+// Merge operations
+const (
+	MergeOpMerge = "merge" // source branch into its parent
+	MergeOpPush  = "push"  // parent into a branch
+	MergeOpReset = "reset" // reset a branch to its parent
+)
+
+// How often and how long to wait for a branch operation to settle
+var (
+	mergePollInterval = 3 * time.Second
+	mergeTimeout      = 15 * time.Minute
+)
+
+func NewMergeCmd(profile *string, dryRun *bool, jsonOut *bool) *cobra.Command {
+	var yes bool
+	var reset bool
+
+	cmd := &cobra.Command{
+		Use:   "merge [source] [target]",
+		Short: "Merge one environment into another (e.g. staging → production)",
+		Long: `Merge promotes the state of one environment to another. Source and
+target are profile names from supabase/config.toml, or branch names of the
+main project; "production" refers to the main branch unless a profile or
+branch has that name.
+
+With no arguments, merges staging into production, if profiles with those
+names exist (the staged workflow).
+
+  supa merge staging production   # merge a branch into production
+  supa merge production preview-x # bring a branch up to date with production
+  supa merge production preview-x --reset  # discard the branch's changes
+
+Shows the schema diff and the edge functions that differ as the plan, asks
+for confirmation, then waits until the branch operation finishes. Merging
+directly between two non-production branches isn't supported.`,
+		Args: cobra.RangeArgs(0, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMerge(cmd.Context(), *profile, args, *dryRun, *jsonOut, yes, reset)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip confirmation prompt")
+	cmd.Flags().BoolVar(&reset, "reset", false, "When merging production into a branch, reset the branch instead of updating it")
+
+	return cmd
+}
+
+func runMerge(ctx context.Context, profileName string, args []string, dryRun, jsonOut, yes, reset bool) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return mergeError(jsonOut, "failed to get working directory", err)
+	}
+
+	cfg, err := profiles.LoadConfig(cwd)
+	if err != nil {
+		return mergeError(jsonOut, "failed to load config", err)
+	}
+
+	sourceName, targetName, err := mergeArgs(cfg, args)
+	if err != nil {
+		return mergeError(jsonOut, err.Error(), nil)
+	}
+
+	// Branches belong to the main project; use the account of the selected
+	// profile (or the target's) to reach it
+	currentBranch, _ := git.GetCurrentBranch(cwd)
+	profile, _, err := cfg.GetProfileOrAuto(profileName, currentBranch)
+	if err != nil {
+		return mergeError(jsonOut, "failed to get profile", err)
+	}
+	account := profile.Account
+	if target, ok := cfg.Profiles[targetName]; ok && profileName == "" {
+		account = target.Account
+	}
+
+	parentRef := cfg.Project.ID
+	if parentRef == "" {
+		if target, ok := cfg.Profiles[targetName]; ok {
+			parentRef = target.Project
+		}
+	}
+	if parentRef == "" {
+		return mergeError(jsonOut, "no main project configured - set [project] id in supabase/config.toml", nil)
+	}
+
+	client, err := newAPIClient(account)
+	if err != nil {
+		return mergeError(jsonOut, "authentication required", err)
+	}
+
+	branches, err := client.ListBranches(ctx, parentRef)
+	if err != nil {
+		return mergeError(jsonOut, "failed to fetch branches", err)
+	}
+
+	source, err := resolveMergeEnv(cfg, branches, sourceName)
+	if err != nil {
+		return mergeError(jsonOut, "unknown source", err)
+	}
+	target, err := resolveMergeEnv(cfg, branches, targetName)
+	if err != nil {
+		return mergeError(jsonOut, "unknown target", err)
+	}
+
+	result := MergeResult{
+		Status: "success",
+		Source: source,
+		Target: target,
+		DryRun: dryRun,
+	}
+
+	// The branch the operation runs on, and whose diff against its parent
+	// is the plan
+	var branch, parent *MergeEnv
+	switch {
+	case source.BranchID == target.BranchID:
+		return mergeResultError(jsonOut, result, fmt.Sprintf("%s and %s are the same environment", sourceName, targetName), nil)
+	case target.Default:
+		result.Operation = MergeOpMerge
+		branch, parent = source, target
+	case source.Default && reset:
+		result.Operation = MergeOpReset
+		branch, parent = target, source
+	case source.Default:
+		result.Operation = MergeOpPush
+		branch, parent = target, source
+	default:
+		return mergeResultError(jsonOut, result, fmt.Sprintf("can't merge %s into %s directly - merge %s into production first", sourceName, targetName, sourceName), nil)
+	}
+
+	diff, err := client.GetBranchDiff(ctx, branch.BranchID, "")
+	if err != nil {
+		return mergeResultError(jsonOut, result, "failed to diff branches", err)
+	}
+	result.Diff = strings.TrimSpace(diff)
+
+	result.Functions, err = functionDifferences(ctx, client, branch.ProjectRef, parent.ProjectRef)
+	if err != nil {
+		return mergeResultError(jsonOut, result, "failed to compare edge functions", err)
+	}
+
+	if !jsonOut {
+		printMergePlan(&result)
+	}
+
+	// Only schema and functions are compared - a branch that changed nothing
+	// else has nothing the merge would carry over
+	if result.Diff == "" && len(result.Functions) == 0 && result.Operation != MergeOpReset {
+		result.Message = "Nothing to merge - no schema or edge function differences"
+		if jsonOut {
+			printJSON(result)
+			return nil
+		}
+		fmt.Println("✓ Nothing to merge - no schema or edge function differences")
+		return nil
+	}
+
+	if dryRun {
+		result.Message = "Dry run - no changes applied"
+		if jsonOut {
+			printJSON(result)
+			return nil
+		}
+		fmt.Println("  (dry-run mode - no changes will be applied)")
+		return nil
+	}
+
+	if !yes && !jsonOut {
+		fmt.Printf("Merge %s into %s? [y/N] ", source.Name, target.Name)
+		reader := bufio.NewReader(os.Stdin)
+		response, _ := reader.ReadString('\n')
+		response = strings.TrimSpace(strings.ToLower(response))
+		if response != "y" && response != "yes" {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	// Status before starting, so a stale "passed" isn't taken as the result
+	before, err := client.GetBranchByID(ctx, branch.BranchID)
+	if err != nil {
+		return mergeResultError(jsonOut, result, fmt.Sprintf("failed to fetch branch %s", branch.BranchName), err)
+	}
+
+	var started *api.BranchActionResponse
+	switch result.Operation {
+	case MergeOpMerge:
+		started, err = client.MergeBranch(ctx, branch.BranchID, api.BranchActionRequest{})
+	case MergeOpPush:
+		started, err = client.PushBranch(ctx, branch.BranchID, api.BranchActionRequest{})
+	case MergeOpReset:
+		started, err = client.ResetBranch(ctx, branch.BranchID, api.BranchActionRequest{})
+	}
+	if err != nil {
+		return mergeResultError(jsonOut, result, fmt.Sprintf("failed to %s branch", result.Operation), err)
+	}
+	result.WorkflowRunID = started.WorkflowRunID
+
+	// The operation's workflow runs on the branch it was started on - for a
+	// merge that's the source, not production
+	if !jsonOut {
+		fmt.Printf("  ⏳ Waiting for %s to finish...\n", branch.Name)
+	}
+	final, err := waitForBranch(ctx, client, branch.BranchID, before, jsonOut)
+	if final != nil {
+		result.BranchStatus = final.Status
+	}
+	if err != nil {
+		return mergeResultError(jsonOut, result, fmt.Sprintf("%s of %s into %s did not complete", result.Operation, source.Name, target.Name), err)
+	}
+	if _, failed := api.BranchSettled(final.Status); failed {
+		return mergeResultError(jsonOut, result, fmt.Sprintf("%s of %s into %s failed (%s)", result.Operation, source.Name, target.Name, final.Status), nil)
+	}
+
+	result.Message = fmt.Sprintf("Merged %s into %s", source.Name, target.Name)
+	if jsonOut {
+		printJSON(result)
+		return nil
+	}
+	fmt.Printf("✓ %s (%s)\n", result.Message, final.Status)
+	return nil
+}
+
+// mergeArgs returns the source and target names. With no arguments, the
+// staged workflow's staging → production is assumed if both profiles exist.
+func mergeArgs(cfg *profiles.Config, args []string) (string, string, error) {
+	switch len(args) {
+	case 2:
+		return args[0], args[1], nil
+	case 0:
+		_, staging := cfg.Profiles["staging"]
+		_, production := cfg.Profiles["production"]
+		if staging && production {
+			return "staging", "production", nil
+		}
+		return "", "", fmt.Errorf("specify a source and target, e.g. 'supa merge staging production'")
+	default:
+		return "", "", fmt.Errorf("specify a target to merge %s into", args[0])
+	}
+}
+
+// resolveMergeEnv finds the branch for a profile name, a branch name, or
+// "production" (the default branch)
+func resolveMergeEnv(cfg *profiles.Config, branches []api.Branch, name string) (*MergeEnv, error) {
+	env := func(b api.Branch) *MergeEnv {
+		return &MergeEnv{Name: name, BranchName: b.Name, BranchID: b.ID, ProjectRef: b.ProjectRef, Default: b.IsDefault}
+	}
+
+	if profile, ok := cfg.Profiles[name]; ok {
+		ref := profile.GetProjectRef(cfg)
+		for _, b := range branches {
+			if b.ProjectRef == ref || (b.IsDefault && b.ParentProjectRef == ref) {
+				return env(b), nil
+			}
+		}
+		return nil, fmt.Errorf("profile %s uses project %s, which isn't a branch of %s", name, ref, cfg.Project.ID)
+	}
+
+	for _, b := range branches {
+		if b.Name == name || (b.GitBranch != "" && b.GitBranch == name) {
+			return env(b), nil
+		}
+	}
+
+	if name == "production" {
+		for _, b := range branches {
+			if b.IsDefault {
+				return env(b), nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no profile or branch named %q", name)
+}
+
+// functionDifferences returns the slugs of edge functions that exist on only
+// one of two projects, or whose deployed bundle or JWT setting differs
+func functionDifferences(ctx context.Context, client *api.Client, branchRef, parentRef string) ([]string, error) {
+	branchFns, err := client.ListFunctions(ctx, branchRef)
+	if err != nil {
+		return nil, err
+	}
+	parentFns, err := client.ListFunctions(ctx, parentRef)
+	if err != nil {
+		return nil, err
+	}
+
+	parent := make(map[string]api.Function, len(parentFns))
+	for _, fn := range parentFns {
+		parent[fn.Slug] = fn
+	}
+
+	var slugs []string
+	for _, fn := range branchFns {
+		other, ok := parent[fn.Slug]
+		delete(parent, fn.Slug)
+		if ok && fn.EzbrSha256 == other.EzbrSha256 && fn.VerifyJWT == other.VerifyJWT {
+			continue
+		}
+		slugs = append(slugs, fn.Slug)
+	}
+	for slug := range parent {
+		slugs = append(slugs, slug)
+	}
+	sort.Strings(slugs)
+	return slugs, nil
+}
+
+// waitForBranch polls a branch until its status settles after an operation
+// started. A settled status only counts once the branch has changed since
+// before, so the previous operation's result isn't mistaken for this one.
+func waitForBranch(ctx context.Context, client *api.Client, branchID string, before *api.Branch, jsonOut bool) (*api.Branch, error) {
+	ctx, cancel := context.WithTimeout(ctx, mergeTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(mergePollInterval)
+	defer ticker.Stop()
+
+	started := false
+	last := before.Status
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+
+		branch, err := client.GetBranchByID(ctx, branchID)
+		if err != nil {
+			// The client already retried; keep polling through a flaky stretch
+			if api.IsServerError(err) || api.IsRateLimited(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		if branch.Status != last && !jsonOut {
+			fmt.Printf("    %s\n", branch.Status)
+		}
+		last = branch.Status
+
+		settled, _ := api.BranchSettled(branch.Status)
+		if !settled || branch.UpdatedAt != before.UpdatedAt {
+			started = true
+		}
+		if settled && started {
+			return branch, nil
+		}
+	}
+}
+
+func printMergePlan(result *MergeResult) {
+	fmt.Println("🔀 Merge Plan")
+	fmt.Println()
+	fmt.Printf("  Source:     %s (%s)\n", result.Source.Name, result.Source.ProjectRef)
+	fmt.Printf("  Target:     %s (%s)\n", result.Target.Name, result.Target.ProjectRef)
+	switch result.Operation {
+	case MergeOpMerge:
+		fmt.Printf("  Operation:  merge %s into its parent\n", result.Source.BranchName)
+	case MergeOpPush:
+		fmt.Printf("  Operation:  update %s from its parent\n", result.Target.BranchName)
+	case MergeOpReset:
+		fmt.Printf("  Operation:  reset %s to its parent (its changes are discarded)\n", result.Target.BranchName)
+	}
+	fmt.Println()
+
+	if result.Target.Default {
+		fmt.Println("  ⚠ This changes production")
+		fmt.Println()
+	}
+
+	if result.Diff == "" {
+		fmt.Println("  No schema differences")
+	} else {
+		fmt.Println("  Schema diff:")
+		for _, line := range strings.Split(result.Diff, "\n") {
+			fmt.Printf("    %s\n", line)
+		}
+	}
+	if len(result.Functions) > 0 {
+		fmt.Printf("  Edge functions that differ: %s\n", strings.Join(result.Functions, ", "))
+	}
+	fmt.Println()
+}
+
+func mergeError(jsonOut bool, message string, err error) error {
+	return mergeResultError(jsonOut, MergeResult{}, message, err)
+}
+
+// mergeResultError is mergeError with the resolved environments attached
+func mergeResultError(jsonOut bool, result MergeResult, message string, err error) error {
+	if jsonOut {
+		result.Status = "error"
+		result.Message = message
+		if err != nil {
+			result.Error = err.Error()
+			result.ErrorCode = api.ErrorCode(err)
+		}
+		printJSON(result)
+		return &ExitError{Code: 1, Err: fmt.Errorf("%s", message)}
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", message, err)
+	}
+	return fmt.Errorf("%s", message)
+}
@@ -0,0 +1,306 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/supabase/supabase-dx/cli/internal/api"
+	"github.com/supabase/supabase-dx/cli/internal/profiles"
+)
+
+const mergeConfig = `[project]
+id = "mainrefmainrefmainre"
+
+[profiles.staging]
+mode = "remote"
+workflow = "git"
+project = "stagingrefstagingref"
+
+[profiles.production]
+mode = "remote"
+workflow = "git"
+project = "mainrefmainrefmainre"
+`
+
+func mergeBranches() []api.Branch {
+	return []api.Branch{
+		{ID: "branch-main", Name: "main", ProjectRef: "mainrefmainrefmainre", ParentProjectRef: "mainrefmainrefmainre", IsDefault: true, GitBranch: "main", Status: api.BranchMigrationsPassed, UpdatedAt: "t0"},
+		{ID: "branch-staging", Name: "staging", ProjectRef: "stagingrefstagingref", ParentProjectRef: "mainrefmainrefmainre", Persistent: true, Status: api.BranchMigrationsPassed, UpdatedAt: "t0"},
+		{ID: "branch-preview", Name: "preview-x", ProjectRef: "previewrefpreviewref", ParentProjectRef: "mainrefmainrefmainre", GitBranch: "feature/x", Status: api.BranchMigrationsPassed, UpdatedAt: "t0"},
+	}
+}
+
+// branchServer fakes the branch endpoints of the Management API. A branch
+// an operation was started on reports RUNNING_MIGRATIONS until it has been
+// polled settleAfter times (never when negative).
+type branchServer struct {
+	mu          sync.Mutex
+	branches    []api.Branch
+	diff        string
+	functions   map[string][]api.Function // by project ref
+	settleAfter int
+	actions     []string // "<action> <branch id>"
+	polls       map[string]int
+}
+
+func (s *branchServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == "GET" && len(parts) == 4 && parts[1] == "projects" && parts[3] == "branches":
+		json.NewEncoder(w).Encode(s.branches)
+	case r.Method == "GET" && len(parts) == 4 && parts[1] == "projects" && parts[3] == "functions":
+		json.NewEncoder(w).Encode(s.functions[parts[2]])
+	case r.Method == "GET" && len(parts) == 4 && parts[1] == "branches" && parts[3] == "diff":
+		w.Write([]byte(s.diff))
+	case r.Method == "GET" && len(parts) == 3 && parts[1] == "branches":
+		b := s.branch(parts[2])
+		if b == nil {
+			http.NotFound(w, r)
+			return
+		}
+		s.polls[b.ID]++
+		if b.Status == api.BranchRunningMigrations && s.settleAfter >= 0 && s.polls[b.ID] >= s.settleAfter {
+			b.Status = api.BranchMigrationsPassed
+			b.UpdatedAt = "t2"
+		}
+		json.NewEncoder(w).Encode(b)
+	case r.Method == "POST" && len(parts) == 4 && parts[1] == "branches":
+		b := s.branch(parts[2])
+		if b == nil {
+			http.NotFound(w, r)
+			return
+		}
+		s.actions = append(s.actions, parts[3]+" "+b.ID)
+		b.Status = api.BranchRunningMigrations
+		b.UpdatedAt = "t1"
+		s.polls[b.ID] = 0
+		json.NewEncoder(w).Encode(api.BranchActionResponse{WorkflowRunID: "run-1"})
+	default:
+		http.Error(w, `{"message":"unexpected request"}`, http.StatusNotFound)
+	}
+}
+
+func (s *branchServer) branch(id string) *api.Branch {
+	for i := range s.branches {
+		if s.branches[i].ID == id {
+			return &s.branches[i]
+		}
+	}
+	return nil
+}
+
+// mergeProject makes a temp project the working directory and routes API
+// calls to a branchServer for the rest of the test
+func mergeProject(t *testing.T) *branchServer {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "supabase"), 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "supabase", "config.toml"), []byte(mergeConfig), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	fake := &branchServer{branches: mergeBranches(), diff: "create table todos ();", settleAfter: 2, polls: make(map[string]int)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client := api.NewClient("test-token")
+	client.BaseURL = server.URL
+	previous := apiClients
+	apiClients = newClientCache()
+	apiClients.clients[""] = client
+	t.Cleanup(func() { apiClients = previous })
+
+	previousInterval := mergePollInterval
+	mergePollInterval = time.Millisecond
+	t.Cleanup(func() { mergePollInterval = previousInterval })
+
+	return fake
+}
+
+func mergeJSON(t *testing.T, out *bytes.Buffer) MergeResult {
+	t.Helper()
+	var result MergeResult
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("expected a JSON result, got %q", out.String())
+	}
+	return result
+}
+
+func TestMergeArgs(t *testing.T) {
+	staged := &profiles.Config{Profiles: map[string]profiles.Profile{"staging": {}, "production": {}}}
+	other := &profiles.Config{Profiles: map[string]profiles.Profile{"dev": {}}}
+
+	if source, target, err := mergeArgs(staged, nil); err != nil || source != "staging" || target != "production" {
+		t.Errorf("expected staging → production, got %s → %s (%v)", source, target, err)
+	}
+	if source, target, err := mergeArgs(other, []string{"a", "b"}); err != nil || source != "a" || target != "b" {
+		t.Errorf("expected a → b, got %s → %s (%v)", source, target, err)
+	}
+	if _, _, err := mergeArgs(other, nil); err == nil {
+		t.Error("expected an error without staging and production profiles")
+	}
+	if _, _, err := mergeArgs(staged, []string{"staging"}); err == nil {
+		t.Error("expected an error with only a source")
+	}
+}
+
+func TestResolveMergeEnv(t *testing.T) {
+	cfg := &profiles.Config{Profiles: map[string]profiles.Profile{
+		"staging": {Project: "stagingrefstagingref"},
+		"stray":   {Project: "strayrefstrayrefstra"},
+	}}
+	cfg.Project.ID = "mainrefmainrefmainre"
+
+	tests := []struct {
+		name   string
+		branch string
+	}{
+		{"staging", "branch-staging"},
+		{"preview-x", "branch-preview"},
+		{"feature/x", "branch-preview"},
+		{"main", "branch-main"},
+		{"production", "branch-main"},
+	}
+	for _, tt := range tests {
+		env, err := resolveMergeEnv(cfg, mergeBranches(), tt.name)
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", tt.name, err)
+			continue
+		}
+		if env.BranchID != tt.branch || env.Name != tt.name {
+			t.Errorf("%s: expected %s, got %+v", tt.name, tt.branch, env)
+		}
+	}
+
+	for _, name := range []string{"stray", "nope"} {
+		if env, err := resolveMergeEnv(cfg, mergeBranches(), name); err == nil {
+			t.Errorf("%s: expected an error, got %+v", name, env)
+		}
+	}
+}
+
+func TestMergeOperations(t *testing.T) {
+	tests := []struct {
+		args      []string
+		reset     bool
+		operation string
+		branch    string // the branch the operation runs on and is polled
+	}{
+		{[]string{"staging", "production"}, false, MergeOpMerge, "branch-staging"},
+		{[]string{"production", "preview-x"}, false, MergeOpPush, "branch-preview"},
+		{[]string{"production", "preview-x"}, true, MergeOpReset, "branch-preview"},
+	}
+	for _, tt := range tests {
+		fake := mergeProject(t)
+		out := captureJSON(t)
+
+		if err := runMerge(context.Background(), "", tt.args, false, true, true, tt.reset); err != nil {
+			t.Fatalf("%v: expected no error, got %v", tt.args, err)
+		}
+
+		result := mergeJSON(t, out)
+		if result.Operation != tt.operation || result.WorkflowRunID != "run-1" {
+			t.Errorf("%v: expected a %s, got %+v", tt.args, tt.operation, result)
+		}
+		if result.BranchStatus != api.BranchMigrationsPassed {
+			t.Errorf("%v: expected the branch to settle as passed, got %q", tt.args, result.BranchStatus)
+		}
+		if len(fake.actions) != 1 || fake.actions[0] != tt.operation+" "+tt.branch {
+			t.Errorf("%v: expected %s on %s, got %v", tt.args, tt.operation, tt.branch, fake.actions)
+		}
+		for id, n := range fake.polls {
+			if id != tt.branch && n > 0 {
+				t.Errorf("%v: expected only %s to be polled, got %d polls of %s", tt.args, tt.branch, n, id)
+			}
+		}
+	}
+
+	mergeProject(t)
+	captureJSON(t)
+	if err := runMerge(context.Background(), "", []string{"staging", "preview-x"}, false, true, true, false); err == nil {
+		t.Error("expected an error merging two non-production branches")
+	}
+}
+
+func TestMergeNothingToMerge(t *testing.T) {
+	fake := mergeProject(t)
+	fake.diff = ""
+	hello := api.Function{Slug: "hello", EzbrSha256: "aaa", VerifyJWT: true}
+	fake.functions = map[string][]api.Function{
+		"stagingrefstagingref": {hello},
+		"mainrefmainrefmainre": {hello},
+	}
+	out := captureJSON(t)
+
+	if err := runMerge(context.Background(), "", []string{"staging", "production"}, false, true, true, false); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	result := mergeJSON(t, out)
+	if !strings.HasPrefix(result.Message, "Nothing to merge") || len(fake.actions) != 0 {
+		t.Errorf("expected nothing to merge, got %+v and actions %v", result, fake.actions)
+	}
+
+	// A changed function is still something to merge
+	changed := hello
+	changed.EzbrSha256 = "bbb"
+	fake.functions["stagingrefstagingref"] = []api.Function{changed, {Slug: "new"}}
+	out.Reset()
+
+	if err := runMerge(context.Background(), "", []string{"staging", "production"}, false, true, true, false); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	result = mergeJSON(t, out)
+	if len(fake.actions) != 1 || strings.Join(result.Functions, ",") != "hello,new" {
+		t.Errorf("expected hello and new to be merged, got %+v and actions %v", result, fake.actions)
+	}
+}
+
+func TestWaitForBranch(t *testing.T) {
+	fake := mergeProject(t)
+	client, _ := newAPIClient("")
+	before := fake.branches[1]
+	fake.branches[1].Status = api.BranchRunningMigrations
+
+	branch, err := waitForBranch(context.Background(), client, "branch-staging", &before, true)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if branch.Status != api.BranchMigrationsPassed || fake.polls["branch-staging"] != 2 {
+		t.Errorf("expected passed after 2 polls, got %s after %d", branch.Status, fake.polls["branch-staging"])
+	}
+
+	// A branch that never settles times out
+	fake.settleAfter = -1
+	fake.branches[1].Status = api.BranchRunningMigrations
+	previous := mergeTimeout
+	mergeTimeout = 20 * time.Millisecond
+	t.Cleanup(func() { mergeTimeout = previous })
+
+	if _, err := waitForBranch(context.Background(), client, "branch-staging", &before, true); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/supabase/supabase-dx/cli/internal/api"
+	"github.com/supabase/supabase-dx/cli/internal/backend"
+	"github.com/supabase/supabase-dx/cli/internal/git"
+	"github.com/supabase/supabase-dx/cli/internal/migrations"
+	"github.com/supabase/supabase-dx/cli/internal/profiles"
+)
+
+type MigrationsResult struct {
+	Status     string   `json:"status"`
+	Message    string   `json:"message"`
+	Profile    string   `json:"profile,omitempty"`
+	ProjectRef string   `json:"project_ref,omitempty"`
+	Repaired   []string `json:"repaired,omitempty"` // versions whose checksum was re-recorded
+	Removed    []string `json:"removed,omitempty"`  // lock entries with no applied migration
+	Error      string   `json:"error,omitempty"`
+	ErrorCode  string   `json:"error_code,omitempty"`
+}
+
+func NewMigrationsCmd(profile *string, jsonOut *bool) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrations",
+		Short: "Manage migration history",
+	}
+
+	cmd.AddCommand(newMigrationsRepairCmd(profile, jsonOut))
+
+	return cmd
+}
+
+func newMigrationsRepairCmd(profile *string, jsonOut *bool) *cobra.Command {
+	return &cobra.Command{
+		Use:   "repair [version...]",
+		Short: "Accept edits to applied migrations by re-recording their checksums",
+		Long: `Push records a checksum of every applied migration in
+supabase/migrations.lock, and push and status refuse to continue when an
+applied file changes afterwards, since the edit will never reach the remote
+database. Migrations applied before the lock existed are recorded by push
+once their file is checked against the statements the remote ran.
+
+If the change is intentional (e.g. a comment fix, or you have applied the
+equivalent change by hand), repair re-baselines the lock from the current
+files. Pass versions to repair only those; with no arguments every applied
+migration is re-recorded and entries for unknown versions are dropped.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMigrationsRepair(cmd.Context(), *profile, *jsonOut, args)
+		},
+	}
+}
+
+func runMigrationsRepair(ctx context.Context, profileName string, jsonOut bool, versions []string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return migrationsError(jsonOut, "failed to get working directory", err)
+	}
+
+	cfg, err := profiles.LoadConfig(cwd)
+	if err != nil {
+		return migrationsError(jsonOut, "failed to load config", err)
+	}
+
+	currentBranch, _ := git.GetCurrentBranch(cwd)
+
+	profile, selectedName, err := cfg.GetProfileOrAuto(profileName, currentBranch)
+	if err != nil {
+		return migrationsError(jsonOut, "failed to get profile", err)
+	}
+
+	// Preview profiles work on the branch mapped to the current git branch.
+	// Repairing the lock never creates one.
+	target, closeTarget, err := openBackend(ctx, cfg, profile, cwd, false, jsonOut)
+	if err != nil {
+		message, cause := openFailure(err)
+		return migrationsError(jsonOut, message, cause)
+	}
+	defer closeTarget()
+	projectRef, _ := describeBackend(target)
+
+	remote, err := target.ListMigrations(ctx)
+	if err != nil {
+		return migrationsError(jsonOut, "failed to fetch remote migrations", err)
+	}
+
+	local, _, err := migrations.List(cwd)
+	if err != nil {
+		return migrationsError(jsonOut, "failed to read migrations", err)
+	}
+
+	lock, err := migrations.LoadLock(cwd)
+	if err != nil {
+		return migrationsError(jsonOut, "failed to load migrations lock", err)
+	}
+
+	applied := make(map[string]migrations.File)
+	for _, f := range migrations.Compare(local, remote).Applied {
+		applied[f.Version] = f
+	}
+
+	result := MigrationsResult{
+		Status:     "success",
+		Profile:    selectedName,
+		ProjectRef: projectRef,
+		Repaired:   []string{},
+		Removed:    []string{},
+	}
+
+	targets := versions
+	if len(targets) == 0 {
+		for version := range applied {
+			targets = append(targets, version)
+		}
+		// Drop entries for migrations that are gone or were never applied
+		for version := range lock.Migrations {
+			if _, ok := applied[version]; !ok {
+				delete(lock.Migrations, version)
+				result.Removed = append(result.Removed, version)
+			}
+		}
+	}
+	sort.Strings(targets)
+	sort.Strings(result.Removed)
+
+	for _, version := range targets {
+		f, ok := applied[version]
+		if !ok {
+			return migrationsError(jsonOut, fmt.Sprintf("migration %s is not applied remotely", version), nil)
+		}
+		sum, err := migrations.ChecksumFile(cwd, f)
+		if err != nil {
+			return migrationsError(jsonOut, fmt.Sprintf("failed to hash %s", f.Filename), err)
+		}
+		if lock.Migrations[version] != sum {
+			lock.Migrations[version] = sum
+			result.Repaired = append(result.Repaired, version)
+		}
+	}
+
+	if len(result.Repaired) > 0 || len(result.Removed) > 0 {
+		if err := lock.Save(cwd); err != nil {
+			return migrationsError(jsonOut, "failed to write migrations lock", err)
+		}
+	}
+
+	result.Message = fmt.Sprintf("Repaired %d migration(s)", len(result.Repaired))
+
+	if jsonOut {
+		printJSON(result)
+		return nil
+	}
+
+	if len(result.Repaired) == 0 && len(result.Removed) == 0 {
+		fmt.Printf("✓ %s is up to date\n", migrations.LockFile)
+		return nil
+	}
+	for _, version := range result.Repaired {
+		fmt.Printf("  ✓ %s (%s)\n", applied[version].Filename, version)
+	}
+	for _, version := range result.Removed {
+		fmt.Printf("  - %s (no longer applied, removed from lock)\n", version)
+	}
+	fmt.Println()
+	fmt.Printf("✓ %s - updated %s\n", result.Message, migrations.LockFile)
+
+	return nil
+}
+
+// verifyLock checks applied migrations against the lock. Those without an
+// entry are baselined from the statements the target ran, so an edit made
+// before the lock knew about a migration is still caught. added counts the
+// new entries, for the caller to save.
+func verifyLock(ctx context.Context, target backend.Backend, cwd string, lock *migrations.Lock, drift *migrations.Drift) ([]migrations.Tampered, int, error) {
+	tampered, unrecorded, err := lock.Verify(cwd, drift.Applied)
+	if err != nil || len(unrecorded) == 0 {
+		return tampered, 0, err
+	}
+
+	baselined, added, err := lock.Baseline(cwd, unrecorded, func(f migrations.File) ([]string, error) {
+		// Pushed migrations are recorded under the server's version
+		version := f.Version
+		if m, ok := drift.Remote[f.Version]; ok {
+			version = m.Version
+		}
+		detail, err := target.GetMigration(ctx, version)
+		if err != nil {
+			return nil, err
+		}
+		return detail.Statements, nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	tampered = append(tampered, baselined...)
+	sort.Slice(tampered, func(i, j int) bool {
+		return tampered[i].Version < tampered[j].Version
+	})
+	return tampered, added, nil
+}
+
+func migrationsError(jsonOut bool, message string, err error) error {
+	if jsonOut {
+		result := MigrationsResult{
+			Status:  "error",
+			Message: message,
+		}
+		if err != nil {
+			result.Error = err.Error()
+			result.ErrorCode = api.ErrorCode(err)
+		}
+		printJSON(result)
+		return &ExitError{Code: 1, Err: fmt.Errorf("%s", message)}
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", message, err)
+	}
+	return fmt.Errorf("%s", message)
+}
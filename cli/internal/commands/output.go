@@ -0,0 +1,18 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// jsonOutput is where --json results are written. The daemon points it at
+// the response to the call it's serving.
+var jsonOutput io.Writer = os.Stdout
+
+// printJSON writes a --json result
+func printJSON(result any) {
+	out, _ := json.MarshalIndent(result, "", "  ")
+	fmt.Fprintln(jsonOutput, string(out))
+}
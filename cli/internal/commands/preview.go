@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/supabase/supabase-dx/cli/internal/api"
+	"github.com/supabase/supabase-dx/cli/internal/git"
+	"github.com/supabase/supabase-dx/cli/internal/profiles"
+)
+
+// resolveProjectRef returns the project ref a profile operates on. For
+// preview profiles that's the ref of the Supabase branch mapped to the
+// current git branch, which is created when missing (unless create is
+// false, e.g. in dry runs). Other profiles use their project directly.
+func resolveProjectRef(ctx context.Context, client *api.Client, cfg *profiles.Config, profile *profiles.Profile, cwd string, create, jsonOut bool) (string, error) {
+	parentRef := profile.GetProjectRef(cfg)
+	if !profile.IsPreview() {
+		return parentRef, nil
+	}
+
+	gitBranch, err := git.GetCurrentBranch(cwd)
+	if err != nil {
+		return "", fmt.Errorf("preview mode needs a git branch: %w", err)
+	}
+	name := profile.PreviewBranchName(gitBranch)
+
+	branches, err := client.ListBranches(ctx, parentRef)
+	if err != nil {
+		return "", err
+	}
+
+	if create && profile.CleanupBranches {
+		cleanupPreviewBranches(ctx, client, cwd, profile, branches, gitBranch, jsonOut)
+	}
+
+	// The default and persistent branches can track a git branch too (the
+	// production branch usually tracks main) - never hand those out as a
+	// preview
+	var branch *api.Branch
+	for i := range branches {
+		if branches[i].IsDefault || branches[i].Persistent {
+			continue
+		}
+		if branches[i].Name == name || branches[i].GitBranch == gitBranch {
+			branch = &branches[i]
+			break
+		}
+	}
+
+	if branch == nil {
+		if !create {
+			return "", fmt.Errorf("preview branch %s doesn't exist yet - run supa pull or supa push to create it", name)
+		}
+
+		if !jsonOut {
+			fmt.Printf("🌱 Creating preview branch %s for git branch %s...\n", name, gitBranch)
+		}
+		branch, err = client.CreateBranch(ctx, parentRef, api.CreateBranchRequest{
+			BranchName: name,
+			GitBranch:  gitBranch,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to create preview branch %s: %w", name, err)
+		}
+	}
+
+	// Wait out a branch that is still being set up, whether we just created
+	// it or an earlier run did. A branch whose migrations failed is still
+	// usable - pushing is how that gets fixed.
+	if settled, _ := api.BranchSettled(branch.Status); !settled {
+		if !jsonOut {
+			fmt.Printf("⏳ Waiting for preview branch %s to be ready...\n", name)
+		}
+		branch, err = waitForBranch(ctx, client, branch.ID, &api.Branch{}, jsonOut)
+		if err != nil {
+			return "", fmt.Errorf("preview branch %s didn't become ready: %w", name, err)
+		}
+	}
+
+	return branch.ProjectRef, nil
+}
+
+// cleanupPreviewBranches deletes preview branches whose git branch no longer
+// exists locally or on origin. Only branches named by the profile's template
+// are considered, so branches created by hand or by other profiles are left
+// alone. Failures are reported but never stop the calling command.
+func cleanupPreviewBranches(ctx context.Context, client *api.Client, cwd string, profile *profiles.Profile, branches []api.Branch, current string, jsonOut bool) {
+	for _, b := range branches {
+		if b.IsDefault || b.Persistent || b.GitBranch == "" || b.GitBranch == current {
+			continue
+		}
+		if b.Name != profile.PreviewBranchName(b.GitBranch) {
+			continue
+		}
+		if git.BranchExists(cwd, b.GitBranch) {
+			continue
+		}
+		// Keep the branch when origin can't be reached - a teammate may
+		// still be working on it
+		if onOrigin, err := git.RemoteBranchExists(cwd, "origin", b.GitBranch); err != nil || onOrigin {
+			continue
+		}
+
+		if err := client.DeleteBranch(ctx, b.ID); err != nil {
+			if !jsonOut {
+				fmt.Printf("  ⚠ Could not delete stale preview branch %s: %v\n", b.Name, err)
+			}
+			continue
+		}
+		if !jsonOut {
+			fmt.Printf("🧹 Deleted preview branch %s (git branch %s is gone)\n", b.Name, b.GitBranch)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/supabase/supabase-dx/cli/internal/api"
+	"github.com/supabase/supabase-dx/cli/internal/profiles"
+)
+
+// gitCheckout makes a temp directory that looks checked out on branch
+func gitCheckout(t *testing.T, branch string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0755); err != nil {
+		t.Fatalf("failed to create .git dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("ref: refs/heads/"+branch+"\n"), 0644); err != nil {
+		t.Fatalf("failed to write HEAD: %v", err)
+	}
+	return dir
+}
+
+func TestResolveProjectRefSkipsDefaultBranch(t *testing.T) {
+	var created *api.CreateBranchRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case "GET":
+			json.NewEncoder(w).Encode([]api.Branch{{
+				ID:         "branch-main",
+				Name:       "main",
+				ProjectRef: "abcdefghijklmnopqrst",
+				IsDefault:  true,
+				GitBranch:  "main",
+				Status:     api.BranchFunctionsDeployed,
+			}})
+		case "POST":
+			created = &api.CreateBranchRequest{}
+			json.NewDecoder(r.Body).Decode(created)
+			json.NewEncoder(w).Encode(api.Branch{
+				ID:         "branch-preview",
+				Name:       created.BranchName,
+				ProjectRef: "previewrefpreviewref",
+				GitBranch:  created.GitBranch,
+				Status:     api.BranchMigrationsPassed,
+			})
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := api.NewClient("test-token")
+	client.BaseURL = server.URL
+	cfg := &profiles.Config{}
+	cfg.Project.ID = "abcdefghijklmnopqrst"
+	profile := &profiles.Profile{Name: "preview", Mode: "preview", BranchTemplate: "preview-{branch}"}
+	cwd := gitCheckout(t, "main")
+
+	if ref, err := resolveProjectRef(context.Background(), client, cfg, profile, cwd, false, true); err == nil {
+		t.Errorf("expected an error without a preview branch, got ref %s", ref)
+	}
+
+	ref, err := resolveProjectRef(context.Background(), client, cfg, profile, cwd, true, true)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if ref != "previewrefpreviewref" {
+		t.Errorf("expected the new preview branch's ref, got %s", ref)
+	}
+	if created == nil || created.BranchName != "preview-main" || created.GitBranch != "main" {
+		t.Errorf("expected preview-main to be created for main, got %+v", created)
+	}
+}
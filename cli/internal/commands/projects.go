@@ -1,51 +1,51 @@
 package commands
 
 import (
-	"encoding/json"
 	"fmt"
 
 	"github.com/spf13/cobra"
 	"github.com/supabase/supabase-dx/cli/internal/api"
-	"github.com/supabase/supabase-dx/cli/internal/config"
 )
 
 type ProjectsResult struct {
-	Status   string        `json:"status"`
-	Projects []api.Project `json:"projects,omitempty"`
-	Error    string        `json:"error,omitempty"`
+	Status    string        `json:"status"`
+	Projects  []api.Project `json:"projects,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	ErrorCode string        `json:"error_code,omitempty"`
 }
 
 func NewProjectsCmd(jsonOut *bool) *cobra.Command {
+	var account string
+
 	cmd := &cobra.Command{
 		Use:   "projects",
 		Short: "List your Supabase projects",
 		Long:  `Lists all Supabase projects you have access to.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			token, err := config.GetAccessToken()
+			client, err := newAPIClient(account)
 			if err != nil {
 				if *jsonOut {
 					result := ProjectsResult{
-						Status: "error",
-						Error:  err.Error(),
+						Status:    "error",
+						Error:     err.Error(),
+						ErrorCode: api.ErrorCode(err),
 					}
-					out, _ := json.MarshalIndent(result, "", "  ")
-					fmt.Println(string(out))
-					return nil
+					printJSON(result)
+					return &ExitError{Code: 1, Err: err}
 				}
 				return err
 			}
 
-			client := api.NewClient(token)
-			projects, err := client.ListProjects()
+			projects, err := client.ListProjects(cmd.Context())
 			if err != nil {
 				if *jsonOut {
 					result := ProjectsResult{
-						Status: "error",
-						Error:  err.Error(),
+						Status:    "error",
+						Error:     err.Error(),
+						ErrorCode: api.ErrorCode(err),
 					}
-					out, _ := json.MarshalIndent(result, "", "  ")
-					fmt.Println(string(out))
-					return nil
+					printJSON(result)
+					return &ExitError{Code: 1, Err: fmt.Errorf("failed to list projects: %w", err)}
 				}
 				return fmt.Errorf("failed to list projects: %w", err)
 			}
@@ -55,8 +55,7 @@ func NewProjectsCmd(jsonOut *bool) *cobra.Command {
 					Status:   "success",
 					Projects: projects,
 				}
-				out, _ := json.MarshalIndent(result, "", "  ")
-				fmt.Println(string(out))
+				printJSON(result)
 				return nil
 			}
 
@@ -79,5 +78,7 @@ func NewProjectsCmd(jsonOut *bool) *cobra.Command {
 		},
 	}
 
+	cmd.Flags().StringVar(&account, "account", "", "Account to list projects for (default: current account)")
+
 	return cmd
 }
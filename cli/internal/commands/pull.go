@@ -1,34 +1,74 @@
 package commands
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/supabase/supabase-dx/cli/internal/api"
-	"github.com/supabase/supabase-dx/cli/internal/config"
+	"github.com/supabase/supabase-dx/cli/internal/backend"
+	"github.com/supabase/supabase-dx/cli/internal/declarative"
+	"github.com/supabase/supabase-dx/cli/internal/functions"
 	"github.com/supabase/supabase-dx/cli/internal/git"
 	"github.com/supabase/supabase-dx/cli/internal/profiles"
 )
 
 type PullResult struct {
-	Status      string        `json:"status"`
-	Message     string        `json:"message"`
-	Profile     string        `json:"profile,omitempty"`
-	ProjectRef  string        `json:"project_ref,omitempty"`
-	DryRun      bool          `json:"dry_run"`
-	Project     *api.Project  `json:"project,omitempty"`
-	Branches    []api.Branch  `json:"branches,omitempty"`
-	Functions   []api.Function `json:"functions,omitempty"`
-	TypesWritten bool         `json:"types_written,omitempty"`
-	Error       string        `json:"error,omitempty"`
+	Status       string         `json:"status"`
+	Message      string         `json:"message"`
+	Profile      string         `json:"profile,omitempty"`
+	ProjectRef   string         `json:"project_ref,omitempty"`
+	Database     string         `json:"database,omitempty"` // local profiles: host:port/dbname
+	DryRun       bool           `json:"dry_run"`
+	Project      *api.Project   `json:"project,omitempty"`
+	Branches     []api.Branch   `json:"branches,omitempty"`
+	Functions    []api.Function `json:"functions,omitempty"`
+	TypesWritten bool           `json:"types_written,omitempty"`
+	Error        string         `json:"error,omitempty"`
+	ErrorCode    string         `json:"error_code,omitempty"`
+
+	FunctionsPulled []FunctionPull `json:"functions_pulled,omitempty"`
+
+	// Local profiles: project migrations applied to the local database
+	MigrationsPulled []string           `json:"migrations_pulled,omitempty"`
+	Failures         []MigrationFailure `json:"failures,omitempty"`
+
+	// Dashboard workflow: schema files rewritten from the database, and the
+	// migration for changes since the last pull (--migration)
+	SchemaFiles     []string               `json:"schema_files,omitempty"`
+	Schema          *declarative.Migration `json:"schema,omitempty"`
+	SchemaMigration string                 `json:"schema_migration,omitempty"`
+}
+
+// Function pull states
+const (
+	PullWritten   = "written"
+	PullUnchanged = "unchanged"
+	PullPending   = "pending" // would be written (dry run)
+	PullConflict  = "conflict"
+	PullFailed    = "failed"
+)
+
+// FunctionPull is the outcome of downloading one function's source
+type FunctionPull struct {
+	Slug      string                 `json:"slug"`
+	Version   int                    `json:"version"`
+	Status    string                 `json:"status"`            // written, unchanged, pending, conflict, failed
+	Files     []string               `json:"files,omitempty"`   // files written (or to be written)
+	Changes   []functions.FileChange `json:"changes,omitempty"` // three-way report when local edits exist
+	Error     string                 `json:"error,omitempty"`
+	ErrorCode string                 `json:"error_code,omitempty"`
 }
 
 func NewPullCmd(profile *string, dryRun *bool, jsonOut *bool) *cobra.Command {
 	var typesOnly bool
 	var schemas string
+	var withMigration bool
 
 	cmd := &cobra.Command{
 		Use:   "pull",
@@ -37,22 +77,39 @@ func NewPullCmd(profile *string, dryRun *bool, jsonOut *bool) *cobra.Command {
 
 Based on your profile configuration, this may:
 - Fetch database schema and create migration files
-- Download edge functions
+- Download edge function source into supabase/functions (local edits are
+  never overwritten; conflicting changes are reported instead)
 - Update local configuration
 - Generate TypeScript types
-- Apply changes to your local Supabase instance`,
+- Apply changes to your local Supabase instance
+
+Profiles with mode = "local" apply the project's migrations that their
+database (db_url, default: the local stack) is missing, writing files for
+any that aren't in supabase/migrations yet, and generate types from the
+local database.
+
+Profiles with workflow = "dashboard" also capture schema changes made in
+the Studio: the schemas listed in the profile's schemas (default: public)
+are read with read-only queries and written to supabase/schemas, one file
+per kind of object, in an order that applies cleanly. The files are
+deterministic, so unchanged schemas leave them untouched. With --migration,
+the files from the last pull are diffed against the database in a scratch
+database on the shadow server (shadow_db_url, default: the local stack),
+and the difference is written to supabase/migrations and recorded as
+applied, so dashboard edits end up in git.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runPull(*profile, *dryRun, *jsonOut, typesOnly, schemas)
+			return runPull(cmd.Context(), *profile, *dryRun, *jsonOut, typesOnly, schemas, withMigration)
 		},
 	}
 
 	cmd.Flags().BoolVar(&typesOnly, "types-only", false, "Only generate TypeScript types")
 	cmd.Flags().StringVar(&schemas, "schemas", "public", "Schemas to include for type generation")
+	cmd.Flags().BoolVar(&withMigration, "migration", false, "Dashboard workflow: write a migration for schema changes since the last pull")
 
 	return cmd
 }
 
-func runPull(profileName string, dryRun bool, jsonOut bool, typesOnly bool, schemas string) error {
+func runPull(ctx context.Context, profileName string, dryRun bool, jsonOut bool, typesOnly bool, schemas string, withMigration bool) error {
 	// Get current working directory
 	cwd, err := os.Getwd()
 	if err != nil {
@@ -74,20 +131,19 @@ func runPull(profileName string, dryRun bool, jsonOut bool, typesOnly bool, sche
 		return outputError(jsonOut, "failed to get profile", err)
 	}
 
-	// Get project ref
-	projectRef := profile.GetProjectRef(cfg)
-	if projectRef == "" {
-		return outputError(jsonOut, "no project ref configured", nil)
-	}
-
-	// Get access token
-	token, err := config.GetAccessToken()
+	// Preview profiles work on the branch mapped to the current git branch
+	target, closeTarget, err := openBackend(ctx, cfg, profile, cwd, !dryRun, jsonOut)
 	if err != nil {
-		return outputError(jsonOut, "authentication required", err)
+		message, cause := openFailure(err)
+		return outputError(jsonOut, message, cause)
 	}
+	defer closeTarget()
 
-	// Create API client
-	client := api.NewClient(token)
+	// Local profiles sync the project's migrations into their database
+	if profile.IsLocal() {
+		return runPullLocal(ctx, target, cwd, cfg, profile, selectedName, dryRun, jsonOut, typesOnly, schemas, withMigration)
+	}
+	projectRef, _ := describeBackend(target)
 
 	// Initialize result
 	result := PullResult{
@@ -99,59 +155,90 @@ func runPull(profileName string, dryRun bool, jsonOut bool, typesOnly bool, sche
 
 	// If types-only mode, just generate types
 	if typesOnly {
-		return pullTypes(client, projectRef, schemas, cwd, dryRun, jsonOut, result)
+		return pullTypes(ctx, target, schemas, cwd, dryRun, jsonOut, result)
 	}
 
-	// Fetch project info
-	project, err := client.GetProject(projectRef)
-	if err != nil {
-		return outputError(jsonOut, "failed to fetch project", err)
-	}
-	result.Project = project
+	// Fetch project info and branches, when the target is a project
+	if info, ok := target.(backend.Project); ok {
+		project, err := info.GetProject(ctx)
+		if err != nil {
+			return outputError(jsonOut, "failed to fetch project", err)
+		}
+		result.Project = project
 
-	// Fetch branches
-	branches, err := client.ListBranches(projectRef)
-	if err != nil {
-		// Branches might not be enabled, not a fatal error
-		if !jsonOut {
-			fmt.Printf("  ⚠ Could not fetch branches: %v\n", err)
+		branches, err := info.ListBranches(ctx)
+		if err != nil {
+			// Branches might not be enabled, not a fatal error
+			if !jsonOut {
+				fmt.Printf("  ⚠ Could not fetch branches: %v\n", err)
+			}
+		} else {
+			result.Branches = branches
 		}
-	} else {
-		result.Branches = branches
 	}
 
-	// Fetch functions
-	functions, err := client.ListFunctions(projectRef)
+	// Fetch functions and download their source
+	remoteFunctions, err := target.ListFunctions(ctx)
 	if err != nil {
 		if !jsonOut {
 			fmt.Printf("  ⚠ Could not fetch functions: %v\n", err)
 		}
 	} else {
-		result.Functions = functions
+		result.Functions = remoteFunctions
+		result.FunctionsPulled = pullFunctions(ctx, target, cwd, cfg, remoteFunctions, dryRun, jsonOut)
+	}
+
+	// Capture schema changes made in the Studio
+	if profile.IsDashboard() {
+		if err := pullSchemas(ctx, target, cwd, profile, dryRun, withMigration, &result); err != nil {
+			return outputError(jsonOut, "failed to pull schema", err)
+		}
 	}
 
 	// Generate types
 	if !dryRun {
-		typesResp, err := client.GetTypescriptTypes(projectRef, schemas)
+		typesResp, err := target.GetTypescriptTypes(ctx, schemas)
 		if err != nil {
 			if !jsonOut {
 				fmt.Printf("  ⚠ Could not generate types: %v\n", err)
 			}
 		} else {
 			// Write types to file
-			typesPath := filepath.Join(cwd, "supabase", "types", "database.ts")
-			if err := os.MkdirAll(filepath.Dir(typesPath), 0755); err == nil {
-				if err := os.WriteFile(typesPath, []byte(typesResp.Types), 0644); err == nil {
-					result.TypesWritten = true
-				}
+			if err := writeTypes(cwd, typesResp.Types); err == nil {
+				result.TypesWritten = true
 			}
 		}
 	}
 
+	var conflicts, failed []string
+	for _, f := range result.FunctionsPulled {
+		switch f.Status {
+		case PullConflict:
+			conflicts = append(conflicts, f.Slug)
+		case PullFailed:
+			failed = append(failed, f.Slug)
+			if result.Error == "" {
+				result.Error = f.Error
+				result.ErrorCode = f.ErrorCode
+			}
+		}
+	}
+	switch {
+	case len(conflicts) > 0:
+		result.Status = "error"
+		result.ErrorCode = "function_conflict"
+		result.Message = fmt.Sprintf("Local edits conflict with the deployed source of %s; commit or stash them and pull again", strings.Join(conflicts, ", "))
+	case len(failed) > 0:
+		result.Status = "error"
+		result.Message = fmt.Sprintf("Failed to download %s", strings.Join(failed, ", "))
+	}
+
 	// Output result
 	if jsonOut {
-		out, _ := json.MarshalIndent(result, "", "  ")
-		fmt.Println(string(out))
+		printJSON(result)
+		if result.Status != "success" {
+			return &ExitError{Code: 1, Err: fmt.Errorf("%s", result.Message)}
+		}
 		return nil
 	}
 
@@ -159,9 +246,13 @@ func runPull(profileName string, dryRun bool, jsonOut bool, typesOnly bool, sche
 	fmt.Println("📥 Pull completed")
 	fmt.Println()
 	fmt.Printf("  Profile:    %s\n", selectedName)
-	fmt.Printf("  Project:    %s (%s)\n", project.Name, projectRef)
-	fmt.Printf("  Region:     %s\n", project.Region)
-	fmt.Printf("  Status:     %s\n", project.Status)
+	if project := result.Project; project != nil {
+		fmt.Printf("  Project:    %s (%s)\n", project.Name, projectRef)
+		fmt.Printf("  Region:     %s\n", project.Region)
+		fmt.Printf("  Status:     %s\n", project.Status)
+	} else {
+		fmt.Printf("  Target:     %s\n", projectRef)
+	}
 	fmt.Println()
 
 	if len(result.Branches) > 0 {
@@ -176,14 +267,18 @@ func runPull(profileName string, dryRun bool, jsonOut bool, typesOnly bool, sche
 		fmt.Println()
 	}
 
-	if len(result.Functions) > 0 {
-		fmt.Printf("  Functions:  %d\n", len(result.Functions))
-		for _, f := range result.Functions {
-			fmt.Printf("    - %s (v%d)\n", f.Slug, f.Version)
+	if len(result.FunctionsPulled) > 0 {
+		fmt.Printf("  Functions:  %d\n", len(result.FunctionsPulled))
+		for _, f := range result.FunctionsPulled {
+			printFunctionPull(f)
 		}
 		fmt.Println()
 	}
 
+	if profile.IsDashboard() {
+		printSchemaPull(result, dryRun)
+	}
+
 	if result.TypesWritten {
 		fmt.Println("  ✓ TypeScript types written to supabase/types/database.ts")
 	}
@@ -192,21 +287,237 @@ func runPull(profileName string, dryRun bool, jsonOut bool, typesOnly bool, sche
 		fmt.Println("\n  (dry-run mode - no changes applied)")
 	}
 
+	if result.Status != "success" {
+		return fmt.Errorf("pull incomplete: %s", result.Message)
+	}
+
+	return nil
+}
+
+// pullFunctions downloads each deployed function and writes its files under
+// supabase/functions. If the function's files have uncommitted edits, the
+// local copy, the remote copy and the last commit are compared: files only
+// changed remotely are written, local edits are kept, and if any file
+// changed on both sides nothing is written for that function.
+func pullFunctions(ctx context.Context, target backend.Backend, cwd string, cfg *profiles.Config, remote []api.Function, dryRun, jsonOut bool) []FunctionPull {
+	lock, err := functions.LoadLock(cwd)
+	if err != nil {
+		lock = &functions.Lock{}
+	}
+	lockChanged := false
+
+	var pulls []FunctionPull
+	for _, fn := range remote {
+		if ctx.Err() != nil {
+			break
+		}
+		p := FunctionPull{Slug: fn.Slug, Version: fn.Version}
+
+		contentType, body, err := target.GetFunctionBody(ctx, fn.Slug)
+		if err == nil {
+			var files []functions.File
+			if files, err = functions.Unpack(fn.Slug, contentType, body); err == nil {
+				err = pullFunction(cwd, &p, files, dryRun)
+			}
+		}
+		if err != nil {
+			p.Status = PullFailed
+			p.Error = err.Error()
+			p.ErrorCode = api.ErrorCode(err)
+			pulls = append(pulls, p)
+			continue
+		}
+
+		// Record the pulled version as deployed once local matches remote, so
+		// push doesn't redeploy it
+		if !dryRun && p.Status != PullConflict && len(p.Changes) == 0 {
+			if bundle, err := functions.Build(cwd, fn.Slug, cfg.Functions[fn.Slug]); err == nil {
+				lock.Record(target.Describe(), fn.Slug, functions.Deployment{Hash: bundle.Hash(), Version: fn.Version, EzbrSha256: fn.EzbrSha256})
+				lockChanged = true
+			}
+		}
+		pulls = append(pulls, p)
+	}
+
+	if lockChanged {
+		if err := lock.Save(cwd); err != nil && !jsonOut {
+			fmt.Printf("  ⚠ Could not update %s: %v\n", functions.LockFile, err)
+		}
+	}
+	return pulls
+}
+
+// pullFunction decides which of a function's downloaded files to write and
+// writes them
+func pullFunction(cwd string, p *FunctionPull, files []functions.File, dryRun bool) error {
+	remote := make(map[string][]byte, len(files))
+	for _, f := range files {
+		remote[f.Name] = f.Content
+	}
+
+	paths := []string{filepath.Join(functions.Dir, p.Slug)}
+	for _, f := range files {
+		paths = append(paths, filepath.FromSlash(f.Name))
+	}
+	// Outside a git repository every local difference counts as an edit
+	dirty, err := git.HasUncommittedChanges(cwd, paths...)
+	inRepo := err == nil
+
+	write := files
+	if dirty || !inRepo {
+		local, err := readLocalFunction(cwd, p.Slug, files)
+		if err != nil {
+			return err
+		}
+		base := make(map[string][]byte)
+		if inRepo {
+			for name := range local {
+				if content, ok, _ := git.ShowHead(cwd, name); ok {
+					base[name] = content
+				}
+			}
+			for name := range remote {
+				if content, ok, _ := git.ShowHead(cwd, name); ok {
+					base[name] = content
+				}
+			}
+		}
+
+		p.Changes = functions.Compare3(base, local, remote)
+		if functions.HasConflicts(p.Changes) {
+			p.Status = PullConflict
+			return nil
+		}
+
+		write = nil
+		for _, c := range p.Changes {
+			if c.State == functions.ChangedRemote {
+				if content, ok := remote[c.File]; ok {
+					write = append(write, functions.File{Name: c.File, Content: content})
+				}
+			}
+		}
+	}
+
+	for _, f := range write {
+		existing, err := os.ReadFile(filepath.Join(cwd, filepath.FromSlash(f.Name)))
+		if err == nil && bytes.Equal(existing, f.Content) {
+			continue
+		}
+		p.Files = append(p.Files, f.Name)
+	}
+
+	switch {
+	case len(p.Files) == 0:
+		p.Status = PullUnchanged
+	case dryRun:
+		p.Status = PullPending
+	default:
+		for _, name := range p.Files {
+			dest := filepath.Join(cwd, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(dest, remote[name], 0644); err != nil {
+				return err
+			}
+		}
+		p.Status = PullWritten
+	}
+
+	// Only local edits remain in the report
+	var kept []functions.FileChange
+	for _, c := range p.Changes {
+		if c.State == functions.ChangedLocal {
+			kept = append(kept, c)
+		}
+	}
+	p.Changes = kept
 	return nil
 }
 
-func pullTypes(client *api.Client, projectRef, schemas, cwd string, dryRun, jsonOut bool, result PullResult) error {
-	typesResp, err := client.GetTypescriptTypes(projectRef, schemas)
+// readLocalFunction reads the function's directory and any other files the
+// remote copy ships (e.g. _shared) from the working tree
+func readLocalFunction(cwd, slug string, files []functions.File) (map[string][]byte, error) {
+	local := make(map[string][]byte)
+
+	dir := filepath.Join(cwd, functions.Dir, slug)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if os.IsNotExist(err) && p == dir {
+			return filepath.SkipDir
+		}
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != dir && (strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(cwd, p)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		local[filepath.ToSlash(rel)] = content
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", slug, err)
+	}
+
+	for _, f := range files {
+		if _, ok := local[f.Name]; ok {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(cwd, filepath.FromSlash(f.Name)))
+		if err == nil {
+			local[f.Name] = content
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return local, nil
+}
+
+func printFunctionPull(f FunctionPull) {
+	switch f.Status {
+	case PullWritten:
+		fmt.Printf("    ✓ %s (v%d) - wrote %d file(s)\n", f.Slug, f.Version, len(f.Files))
+	case PullPending:
+		fmt.Printf("    ~ %s (v%d) - would write %d file(s)\n", f.Slug, f.Version, len(f.Files))
+	case PullUnchanged:
+		fmt.Printf("    ✓ %s (v%d) - up to date\n", f.Slug, f.Version)
+	case PullFailed:
+		fmt.Printf("    ✗ %s (v%d) - %s\n", f.Slug, f.Version, f.Error)
+	case PullConflict:
+		fmt.Printf("    ✗ %s (v%d) - local edits conflict with the deployed source\n", f.Slug, f.Version)
+	}
+
+	for _, c := range f.Changes {
+		switch c.State {
+		case functions.ChangedConflict:
+			fmt.Printf("        ! %s (changed locally and remotely)\n", c.File)
+		case functions.ChangedLocal:
+			fmt.Printf("        ~ %s (local edit kept)\n", c.File)
+		case functions.ChangedRemote:
+			fmt.Printf("        + %s (remote change)\n", c.File)
+		}
+	}
+}
+
+func pullTypes(ctx context.Context, target backend.Backend, schemas, cwd string, dryRun, jsonOut bool, result PullResult) error {
+	typesResp, err := target.GetTypescriptTypes(ctx, schemas)
 	if err != nil {
 		return outputError(jsonOut, "failed to generate types", err)
 	}
 
 	if !dryRun {
-		typesPath := filepath.Join(cwd, "supabase", "types", "database.ts")
-		if err := os.MkdirAll(filepath.Dir(typesPath), 0755); err != nil {
-			return outputError(jsonOut, "failed to create types directory", err)
-		}
-		if err := os.WriteFile(typesPath, []byte(typesResp.Types), 0644); err != nil {
+		if err := writeTypes(cwd, typesResp.Types); err != nil {
 			return outputError(jsonOut, "failed to write types file", err)
 		}
 		result.TypesWritten = true
@@ -214,8 +525,7 @@ func pullTypes(client *api.Client, projectRef, schemas, cwd string, dryRun, json
 
 	if jsonOut {
 		result.Message = "TypeScript types generated"
-		out, _ := json.MarshalIndent(result, "", "  ")
-		fmt.Println(string(out))
+		printJSON(result)
 		return nil
 	}
 
@@ -229,6 +539,15 @@ func pullTypes(client *api.Client, projectRef, schemas, cwd string, dryRun, json
 	return nil
 }
 
+// writeTypes writes generated types to supabase/types/database.ts
+func writeTypes(cwd, types string) error {
+	typesPath := filepath.Join(cwd, "supabase", "types", "database.ts")
+	if err := os.MkdirAll(filepath.Dir(typesPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(typesPath, []byte(types), 0644)
+}
+
 func outputError(jsonOut bool, message string, err error) error {
 	if jsonOut {
 		result := PullResult{
@@ -237,10 +556,10 @@ func outputError(jsonOut bool, message string, err error) error {
 		}
 		if err != nil {
 			result.Error = err.Error()
+			result.ErrorCode = api.ErrorCode(err)
 		}
-		out, _ := json.MarshalIndent(result, "", "  ")
-		fmt.Println(string(out))
-		return nil
+		printJSON(result)
+		return &ExitError{Code: 1, Err: fmt.Errorf("%s", message)}
 	}
 
 	if err != nil {
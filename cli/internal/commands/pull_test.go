@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/supabase/supabase-dx/cli/internal/api"
+	"github.com/supabase/supabase-dx/cli/internal/backend"
+	"github.com/supabase/supabase-dx/cli/internal/profiles"
+)
+
+func TestPullLocalWritesMigrations(t *testing.T) {
+	db := backend.NewFake("localhost:54322/postgres")
+	db.Types = "export type Database = {}\n"
+	root := fakeProject(t, db, map[string]string{"20240101000000_init.sql": initSQL})
+
+	// The project has init, pushed from the file, and a migration made
+	// elsewhere that has no file yet
+	project := backend.NewFake("abcdefghijklmnopqrst")
+	pushed(t, project, "20240101000000_init.sql", initSQL)
+	if err := project.ApplyMigration(context.Background(), api.ApplyMigrationRequest{Name: "add_done", Query: "alter table todos add column done boolean"}); err != nil {
+		t.Fatalf("failed to apply add_done: %v", err)
+	}
+	addDone := project.Migrations[1].Version + "_add_done.sql"
+	previous := openProject
+	openProject = func(cfg *profiles.Config, profile *profiles.Profile) (backend.Backend, error) {
+		return project, nil
+	}
+	t.Cleanup(func() { openProject = previous })
+	out := captureJSON(t)
+
+	if err := runPull(context.Background(), "local", false, true, false, "public", false); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var result PullResult
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("expected a JSON result, got %q", out.String())
+	}
+	if result.Status != "success" || len(result.MigrationsPulled) != 2 {
+		t.Errorf("expected both migrations pulled, got %+v", result)
+	}
+
+	written, err := os.ReadFile(filepath.Join(root, "supabase", "migrations", addDone))
+	if err != nil {
+		t.Fatalf("expected the migration to be written, got %v", err)
+	}
+	if string(written) != "alter table todos add column done boolean;\n" {
+		t.Errorf("unexpected migration content: %q", written)
+	}
+	if len(db.Migrations) != 2 || db.Migrations[0].Statements[0] != initSQL {
+		t.Errorf("expected both migrations applied locally, init from its file, got %+v", db.Migrations)
+	}
+
+	if !result.TypesWritten {
+		t.Error("expected types to be written")
+	}
+}
+
+func TestPullLocalRefusesUnsafeMigrationName(t *testing.T) {
+	db := backend.NewFake("localhost:54322/postgres")
+	root := fakeProject(t, db, nil)
+
+	project := backend.NewFake("abcdefghijklmnopqrst")
+	if err := project.ApplyMigration(context.Background(), api.ApplyMigrationRequest{Name: "20240101000000_../../../../x", Query: "select 1"}); err != nil {
+		t.Fatalf("failed to apply migration: %v", err)
+	}
+	previous := openProject
+	openProject = func(cfg *profiles.Config, profile *profiles.Profile) (backend.Backend, error) {
+		return project, nil
+	}
+	t.Cleanup(func() { openProject = previous })
+	out := captureJSON(t)
+
+	err := runPull(context.Background(), "local", false, true, false, "public", false)
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 1 {
+		t.Fatalf("expected exit code 1, got %v", err)
+	}
+
+	var result PullResult
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("expected a JSON result, got %q", out.String())
+	}
+	if result.Status != "error" {
+		t.Errorf("expected an error, got %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(root, "x.sql")); !os.IsNotExist(err) {
+		t.Errorf("expected nothing written outside supabase/migrations, got %v", err)
+	}
+	if len(db.Migrations) != 0 {
+		t.Errorf("expected nothing applied locally, got %+v", db.Migrations)
+	}
+}
@@ -2,7 +2,7 @@ package commands
 
 import (
 	"bufio"
-	"encoding/json"
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,33 +10,88 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/supabase/supabase-dx/cli/internal/api"
-	"github.com/supabase/supabase-dx/cli/internal/config"
+	"github.com/supabase/supabase-dx/cli/internal/backend"
+	"github.com/supabase/supabase-dx/cli/internal/declarative"
+	"github.com/supabase/supabase-dx/cli/internal/functions"
 	"github.com/supabase/supabase-dx/cli/internal/git"
+	"github.com/supabase/supabase-dx/cli/internal/lint"
+	"github.com/supabase/supabase-dx/cli/internal/migrations"
 	"github.com/supabase/supabase-dx/cli/internal/profiles"
+	"github.com/supabase/supabase-dx/cli/internal/secrets"
 )
 
 type PushResult struct {
-	Status           string   `json:"status"`
-	Message          string   `json:"message"`
-	Profile          string   `json:"profile,omitempty"`
-	ProjectRef       string   `json:"project_ref,omitempty"`
-	DryRun           bool     `json:"dry_run"`
-	MigrationsFound  int      `json:"migrations_found,omitempty"`
-	MigrationsApplied int     `json:"migrations_applied,omitempty"`
-	FunctionsFound   int      `json:"functions_found,omitempty"`
-	SecretsFound     int      `json:"secrets_found,omitempty"`
-	Error            string   `json:"error,omitempty"`
+	Status            string `json:"status"`
+	Message           string `json:"message"`
+	Profile           string `json:"profile,omitempty"`
+	ProjectRef        string `json:"project_ref,omitempty"`
+	Database          string `json:"database,omitempty"` // local profiles: host:port/dbname
+	DryRun            bool   `json:"dry_run"`
+	MigrationsFound   int    `json:"migrations_found,omitempty"`
+	MigrationsPending int    `json:"migrations_pending,omitempty"`
+	MigrationsApplied int    `json:"migrations_applied,omitempty"`
+	FunctionsFound    int    `json:"functions_found,omitempty"`
+	FunctionsDeployed int    `json:"functions_deployed,omitempty"`
+	SecretsFound      int    `json:"secrets_found,omitempty"`
+	Forced            bool   `json:"forced,omitempty"`
+	LintSkipped       bool   `json:"lint_skipped,omitempty"`
+	LockUpdated       bool   `json:"lock_updated,omitempty"`
+	Error             string `json:"error,omitempty"`
+	ErrorCode         string `json:"error_code,omitempty"`
+
+	Migrations *migrations.Drift     `json:"migrations,omitempty"`
+	Tampered   []migrations.Tampered `json:"tampered,omitempty"`
+	Failures   []MigrationFailure    `json:"failures,omitempty"`
+	Skipped    []string              `json:"skipped,omitempty"` // pending migrations not attempted after a failure
+	Functions  []FunctionDeploy      `json:"functions,omitempty"`
+	Lint       []lint.Finding        `json:"lint,omitempty"` // findings in pending migrations
+
+	Secrets        *secrets.Plan `json:"secrets,omitempty"`
+	SecretsSet     int           `json:"secrets_set,omitempty"`
+	SecretsDeleted int           `json:"secrets_deleted,omitempty"`
+
+	// Declarative profiles: the migration generated from supabase/schemas
+	Schema          *declarative.Migration `json:"schema,omitempty"`
+	SchemaMigration string                 `json:"schema_migration,omitempty"` // file it's written to
+	SchemaSkipped   bool                   `json:"schema_skipped,omitempty"`   // not diffed, other migrations are pending
 }
 
 type PushPlan struct {
-	Migrations []string
-	Functions  []string
-	Secrets    []string
+	Migrations         []migrations.File // pending migrations, in version order
+	Drift              *migrations.Drift
+	Functions          []FunctionDeploy // functions that need deploying
+	UnchangedFunctions []string
+	Secrets            *secrets.Plan // nil if there is no env file
+}
+
+// Function deploy states
+const (
+	DeployPending  = "pending"
+	DeployDeployed = "deployed"
+	DeployFailed   = "failed"
+	DeploySkipped  = "skipped"
+)
+
+// FunctionDeploy is a function in the push plan
+type FunctionDeploy struct {
+	Slug      string `json:"slug"`
+	Reason    string `json:"reason"` // new, changed, untracked
+	Status    string `json:"status"` // pending, deployed, failed, skipped
+	Hash      string `json:"hash"`
+	Version   int    `json:"version,omitempty"` // deployed version after push
+	Error     string `json:"error,omitempty"`
+	ErrorCode string `json:"error_code,omitempty"`
+
+	bundle *functions.Bundle
 }
 
 func NewPushCmd(profile *string, dryRun *bool, jsonOut *bool) *cobra.Command {
 	var yes bool
 	var migrationsOnly bool
+	var force bool
+	var continueOnError bool
+	var prune bool
+	var noLint bool
 
 	cmd := &cobra.Command{
 		Use:   "push",
@@ -48,19 +103,61 @@ Based on your profile configuration, this may:
 - Deploy edge functions
 - Update remote configuration
 
+Migrations are matched against the remote history by their timestamp
+version, and only the ones not yet applied are pushed. If the remote has
+migrations with no local file (e.g. changes made from the dashboard or
+another branch), push refuses to run until you pull them - or pass --force
+to apply on top anyway.
+
+Each function in supabase/functions is bundled with _shared and its import
+map and deployed, honouring verify_jwt, entrypoint, import_map and
+static_files from [functions.<slug>] in supabase/config.toml. Bundle hashes
+are recorded per project in supabase/functions.lock, and only functions
+whose content changed since the version deployed there are redeployed.
+
+Secrets are read from the profile's env file (env_file, or
+supabase/.env.<profile>, or supabase/.env) and compared with the remote
+secrets by value digest; new and changed ones are set. Remote secrets not
+in the file are listed, and deleted with --prune.
+
+Migrations are applied in order and push stops at the first one that fails,
+reporting the file and the position of the SQL error, and exits non-zero.
+Use --continue-on-error to attempt the remaining migrations anyway.
+
+Before anything is applied, pending migrations are checked for unsafe DDL
+with the profile's lint rules (see 'supa lint'). Push refuses to run while
+there are errors; warnings are shown in the plan. --no-lint skips the check.
+
+Profiles with schema = "declarative" also diff supabase/schemas against
+the remote: the files are applied to a scratch database created on the
+shadow server (shadow_db_url, default: the local stack) and dropped
+afterwards, both sides are introspected, and the difference is written to
+a new migration in supabase/migrations for review, then applied with the
+others. Only the schemas listed in the profile's schemas (default: public)
+are compared. When other migrations are pending, the diff waits until
+they're applied.
+
+Profiles with mode = "local" apply migrations to the profile's database
+(db_url, default: the local stack) instead of a project. Functions and
+secrets are skipped; the local stack serves them from the working tree.
+
 By default, shows a plan and asks for confirmation.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runPush(*profile, *dryRun, *jsonOut, yes, migrationsOnly)
+			return runPush(cmd.Context(), *profile, *dryRun, *jsonOut, yes, migrationsOnly, force, continueOnError, prune, noLint)
 		},
 	}
 
 	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip confirmation prompt")
 	cmd.Flags().BoolVar(&migrationsOnly, "migrations-only", false, "Only apply migrations")
+	cmd.Flags().BoolVar(&force, "force", false, "Push even if the remote migration history has diverged")
+	cmd.Flags().BoolVar(&continueOnError, "continue-on-error", false, "Keep applying migrations after one fails")
+	cmd.Flags().BoolVar(&prune, "prune", false, "Delete remote secrets that aren't in the env file")
+	cmd.Flags().BoolVar(&noLint, "no-lint", false, "Apply migrations that fail lint")
 
 	return cmd
 }
 
-func runPush(profileName string, dryRun bool, jsonOut bool, yes bool, migrationsOnly bool) error {
+func runPush(ctx context.Context, profileName string, dryRun bool, jsonOut bool, yes bool, migrationsOnly bool, force bool, continueOnError bool, prune bool, noLint bool) error {
 	// Get current working directory
 	cwd, err := os.Getwd()
 	if err != nil {
@@ -82,47 +179,160 @@ func runPush(profileName string, dryRun bool, jsonOut bool, yes bool, migrations
 		return pushError(jsonOut, "failed to get profile", err)
 	}
 
-	// Get project ref
-	projectRef := profile.GetProjectRef(cfg)
-	if projectRef == "" {
-		return pushError(jsonOut, "no project ref configured", nil)
+	// Local profiles apply migrations only - the local stack serves
+	// functions and secrets from the working tree
+	if profile.IsLocal() {
+		migrationsOnly = true
 	}
 
-	// Get access token
-	token, err := config.GetAccessToken()
+	// Preview profiles work on the branch mapped to the current git branch
+	target, closeTarget, err := openBackend(ctx, cfg, profile, cwd, !dryRun, jsonOut)
 	if err != nil {
-		return pushError(jsonOut, "authentication required", err)
+		message, cause := openFailure(err)
+		return pushError(jsonOut, message, cause)
 	}
+	defer closeTarget()
+	projectRef, database := describeBackend(target)
 
-	// Create API client
-	client := api.NewClient(token)
+	// Fetch remote migration history
+	remote, err := target.ListMigrations(ctx)
+	if err != nil {
+		return pushError(jsonOut, "failed to fetch remote migrations", err)
+	}
+
+	// Fetch deployed functions
+	var remoteFunctions []api.Function
+	if !migrationsOnly {
+		remoteFunctions, err = target.ListFunctions(ctx)
+		if err != nil {
+			return pushError(jsonOut, "failed to fetch remote functions", err)
+		}
+	}
 
 	// Build push plan
-	plan, err := buildPushPlan(cwd, migrationsOnly)
+	plan, err := buildPushPlan(cwd, cfg, target.Describe(), migrationsOnly, remote, remoteFunctions)
 	if err != nil {
 		return pushError(jsonOut, "failed to build push plan", err)
 	}
+	if !migrationsOnly {
+		plan.Secrets, err = loadSecretsPlan(ctx, target, cwd, selectedName, profile.EnvFile)
+		if err != nil {
+			return pushError(jsonOut, "failed to compare secrets", err)
+		}
+	}
+	secretsPending := plan.Secrets != nil && plan.Secrets.Pending(prune)
 
 	// Initialize result
 	result := PushResult{
-		Status:          "success",
-		Profile:         selectedName,
-		ProjectRef:      projectRef,
-		DryRun:          dryRun,
-		MigrationsFound: len(plan.Migrations),
-		FunctionsFound:  len(plan.Functions),
-		SecretsFound:    len(plan.Secrets),
+		Status:            "success",
+		Profile:           selectedName,
+		ProjectRef:        projectRef,
+		Database:          database,
+		DryRun:            dryRun,
+		MigrationsFound:   len(plan.Drift.Applied) + len(plan.Drift.Pending),
+		MigrationsPending: len(plan.Migrations),
+		FunctionsFound:    len(plan.Functions) + len(plan.UnchangedFunctions),
+		SecretsFound:      secretsFound(plan.Secrets),
+		Forced:            force && plan.Drift.Diverged(),
+		Migrations:        plan.Drift,
+		Functions:         plan.Functions,
+		Secrets:           plan.Secrets,
+	}
+
+	// Refuse to build on top of a history we don't have locally
+	if err := plan.Drift.Check(); err != nil && !force {
+		if !jsonOut {
+			printMigrationDrift(plan.Drift)
+			fmt.Println()
+		}
+		return pushErrorResult(jsonOut, result, "remote migration history has diverged - run 'supa pull' or use --force", err)
+	}
+
+	// Applied migrations must not have been edited since they were applied
+	lock, err := migrations.LoadLock(cwd)
+	if err != nil {
+		return pushErrorResult(jsonOut, result, "failed to load migrations lock", err)
+	}
+	tampered, added, err := verifyLock(ctx, target, cwd, lock, plan.Drift)
+	if err != nil {
+		return pushErrorResult(jsonOut, result, "failed to verify applied migrations", err)
+	}
+	if len(tampered) > 0 {
+		result.Tampered = tampered
+		if !jsonOut {
+			printTampered(tampered)
+			fmt.Println()
+		}
+		return pushErrorResult(jsonOut, result, "applied migrations were modified - restore them, or run 'supa migrations repair' to accept the changes", &migrations.TamperedError{Tampered: tampered})
+	}
+	if added > 0 && !dryRun {
+		// First push with a lockfile - record what's already applied
+		if err := lock.Save(cwd); err != nil {
+			if !jsonOut {
+				fmt.Printf("  ⚠ Could not update %s: %v\n", migrations.LockFile, err)
+			}
+		} else {
+			result.LockUpdated = true
+		}
+	}
+
+	// Declarative profiles turn supabase/schemas into a migration. Pending
+	// migrations aren't on the target yet, so diffing now would generate
+	// their changes a second time.
+	var schemaFile migrations.File
+	if profile.IsDeclarative() {
+		if len(plan.Migrations) > 0 {
+			result.SchemaSkipped = true
+		} else {
+			diff, err := diffSchemas(ctx, target, cwd, profile)
+			if err != nil {
+				return pushErrorResult(jsonOut, result, "failed to diff declarative schemas", err)
+			}
+			result.Schema = diff
+			if !diff.Empty() {
+				schemaFile = schemaMigrationFile("schema_diff")
+				result.SchemaMigration = schemaFile.Filename
+				plan.Migrations = append(plan.Migrations, schemaFile)
+				result.MigrationsPending = len(plan.Migrations)
+			}
+		}
+	}
+
+	// Unsafe DDL stops the push before anything is applied
+	if noLint {
+		result.LintSkipped = len(plan.Migrations) > 0
+	} else {
+		var unsaved []lint.Source
+		if result.SchemaMigration != "" {
+			unsaved = append(unsaved, lint.Source{File: schemaFile.Filename, SQL: result.Schema.SQL()})
+		}
+		findings, err := lintMigrations(ctx, target, cwd, profile, plan.Migrations, unsaved...)
+		if err != nil {
+			return pushErrorResult(jsonOut, result, "failed to lint pending migrations", err)
+		}
+		result.Lint = findings
+		if lint.Errors(findings) > 0 {
+			if !jsonOut {
+				printLintFindings(findings)
+				fmt.Println()
+			}
+			return pushErrorResult(jsonOut, result, "pending migrations failed lint - fix them, adjust the rules in the profile's lint settings, or use --no-lint", &lint.FailedError{Findings: findings})
+		}
 	}
 
 	// Check if there's anything to push
-	if len(plan.Migrations) == 0 && len(plan.Functions) == 0 {
+	if len(plan.Migrations) == 0 && len(plan.Functions) == 0 && !secretsPending {
 		result.Message = "Nothing to push"
 		if jsonOut {
-			out, _ := json.MarshalIndent(result, "", "  ")
-			fmt.Println(string(out))
+			printJSON(result)
 			return nil
 		}
 		fmt.Println("✓ Nothing to push - everything is up to date")
+		if result.Schema != nil {
+			for _, w := range result.Schema.Warnings {
+				fmt.Printf("  ⚠ %s\n", w)
+			}
+		}
 		return nil
 	}
 
@@ -131,32 +341,59 @@ func runPush(profileName string, dryRun bool, jsonOut bool, yes bool, migrations
 		fmt.Println("📤 Push Plan")
 		fmt.Println()
 		fmt.Printf("  Profile:    %s\n", selectedName)
-		fmt.Printf("  Project:    %s\n", projectRef)
+		if database != "" {
+			fmt.Printf("  Database:   %s (local)\n", database)
+		} else {
+			fmt.Printf("  Project:    %s\n", projectRef)
+		}
 		fmt.Println()
 
-		if len(plan.Migrations) > 0 {
-			fmt.Printf("  Migrations (%d):\n", len(plan.Migrations))
-			for _, m := range plan.Migrations {
-				fmt.Printf("    + %s\n", m)
-			}
+		printMigrationDrift(plan.Drift)
+		fmt.Println()
+
+		if force && plan.Drift.Diverged() {
+			fmt.Println("  ⚠ --force: applying on top of a diverged remote history")
+			fmt.Println()
+		}
+		if outOfOrder := plan.Drift.OutOfOrder(); len(outOfOrder) > 0 {
+			fmt.Printf("  ⚠ %d pending migration(s) are older than the latest applied one\n", len(outOfOrder))
+			fmt.Println()
+		}
+		if len(result.Lint) > 0 {
+			printLintFindings(result.Lint)
+			fmt.Println()
+		} else if result.LintSkipped {
+			fmt.Println("  ⚠ --no-lint: pending migrations were not checked")
+			fmt.Println()
+		}
+
+		if result.SchemaSkipped {
+			fmt.Printf("  ⚠ %s is diffed once the pending migrations are applied\n", declarative.Dir)
+			fmt.Println()
+		} else if result.Schema != nil && (result.SchemaMigration != "" || len(result.Schema.Warnings) > 0) {
+			printSchemaMigration(schemaFile, result.Schema)
 			fmt.Println()
 		}
 
-		if len(plan.Functions) > 0 {
-			fmt.Printf("  Functions (%d):\n", len(plan.Functions))
+		if len(plan.Functions)+len(plan.UnchangedFunctions) > 0 {
+			fmt.Printf("  Functions: %d to deploy, %d unchanged\n", len(plan.Functions), len(plan.UnchangedFunctions))
 			for _, f := range plan.Functions {
-				fmt.Printf("    + %s\n", f)
+				fmt.Printf("    + %s (%s)\n", f.Slug, f.Reason)
 			}
 			fmt.Println()
 		}
+
+		if plan.Secrets != nil && len(plan.Secrets.Changes) > 0 {
+			printSecretsPlan(plan.Secrets, prune)
+			fmt.Println()
+		}
 	}
 
 	// Dry run - don't apply
 	if dryRun {
 		result.Message = "Dry run - no changes applied"
 		if jsonOut {
-			out, _ := json.MarshalIndent(result, "", "  ")
-			fmt.Println(string(out))
+			printJSON(result)
 			return nil
 		}
 		fmt.Println("  (dry-run mode - no changes will be applied)")
@@ -175,109 +412,362 @@ func runPush(profileName string, dryRun bool, jsonOut bool, yes bool, migrations
 		}
 	}
 
-	// Apply migrations
+	if result.SchemaMigration != "" {
+		if err := writeSchemaMigration(cwd, schemaFile, result.Schema); err != nil {
+			return pushErrorResult(jsonOut, result, "failed to write schema migration", err)
+		}
+		if !jsonOut {
+			fmt.Printf("  ✓ Wrote %s\n", filepath.Join(migrations.Dir, schemaFile.Filename))
+		}
+	}
+
+	// Apply migrations in order. Unless --continue-on-error, stop at the
+	// first failure so later migrations never run on top of a broken one.
 	appliedMigrations := 0
-	for _, migrationFile := range plan.Migrations {
-		migrationPath := filepath.Join(cwd, "supabase", "migrations", migrationFile)
-		content, err := os.ReadFile(migrationPath)
-		if err != nil {
+	for i, migration := range plan.Migrations {
+		content, err := applyMigration(ctx, target, cwd, migration)
+		if err == nil {
+			appliedMigrations++
+			lock.Migrations[migration.Version] = migrations.Checksum(content)
 			if !jsonOut {
-				fmt.Printf("  ✗ Failed to read %s: %v\n", migrationFile, err)
+				fmt.Printf("  ✓ Applied %s\n", migration.Filename)
 			}
 			continue
 		}
 
-		// Extract version and name from filename (e.g., "20240101120000_create_users.sql")
-		baseName := strings.TrimSuffix(migrationFile, ".sql")
-		parts := strings.SplitN(baseName, "_", 2)
-		name := ""
-		if len(parts) > 1 {
-			name = parts[1]
+		failure := newMigrationFailure(migration, content, err)
+		result.Failures = append(result.Failures, failure)
+		if !jsonOut {
+			printMigrationFailure(failure)
 		}
 
-		req := api.ApplyMigrationRequest{
-			Query: string(content),
-			Name:  name,
+		// Interrupted - never keep going, even with --continue-on-error
+		if ctx.Err() != nil || !continueOnError {
+			for _, m := range plan.Migrations[i+1:] {
+				result.Skipped = append(result.Skipped, m.Filename)
+			}
+			break
 		}
+	}
 
-		if err := client.ApplyMigration(projectRef, req); err != nil {
+	if appliedMigrations > 0 {
+		if err := lock.Save(cwd); err != nil {
 			if !jsonOut {
-				fmt.Printf("  ✗ Failed to apply %s: %v\n", migrationFile, err)
+				fmt.Printf("  ⚠ Could not update %s: %v\n", migrations.LockFile, err)
 			}
-			result.Error = err.Error()
 		} else {
-			appliedMigrations++
+			result.LockUpdated = true
+		}
+	}
+	result.MigrationsApplied = appliedMigrations
+
+	stopped := len(result.Failures) > 0 && !continueOnError
+
+	// Set secrets before deploying, so new function versions can read them
+	var secretsErr error
+	if secretsPending && !stopped && ctx.Err() == nil {
+		result.SecretsSet, result.SecretsDeleted, secretsErr = plan.Secrets.Apply(ctx, target, prune)
+		if secretsErr != nil {
 			if !jsonOut {
-				fmt.Printf("  ✓ Applied %s\n", migrationFile)
+				fmt.Printf("  ✗ Failed to update secrets: %v\n", secretsErr)
 			}
+			stopped = !continueOnError
+		} else if !jsonOut {
+			fmt.Printf("  ✓ Set %d secret(s), deleted %d\n", result.SecretsSet, result.SecretsDeleted)
 		}
 	}
 
-	result.MigrationsApplied = appliedMigrations
-	result.Message = fmt.Sprintf("Applied %d migrations", appliedMigrations)
+	// Deploy functions, unless an earlier failure stopped the push
+	if len(plan.Functions) > 0 {
+		result.FunctionsDeployed = deployFunctions(ctx, target, cwd, plan.Functions, stopped, continueOnError, jsonOut)
+	}
+
+	result.Message = fmt.Sprintf("Applied %d migrations, deployed %d functions", appliedMigrations, result.FunctionsDeployed)
+	if result.SecretsSet+result.SecretsDeleted > 0 {
+		result.Message += fmt.Sprintf(", set %d secrets, deleted %d", result.SecretsSet, result.SecretsDeleted)
+	}
+
+	if len(result.Failures) > 0 {
+		first := result.Failures[0]
+		result.Status = "error"
+		result.Error = first.Error
+		result.ErrorCode = first.ErrorCode
+		result.Message = fmt.Sprintf("Applied %d of %d migrations; %d failed, first at %s",
+			appliedMigrations, len(plan.Migrations), len(result.Failures), first.Location())
+	} else if secretsErr != nil {
+		result.Status = "error"
+		result.Error = secretsErr.Error()
+		result.ErrorCode = api.ErrorCode(secretsErr)
+		result.Message = fmt.Sprintf("Applied %d migrations; failed to update secrets", appliedMigrations)
+	} else {
+		for _, f := range result.Functions {
+			if f.Status == DeployFailed {
+				result.Status = "error"
+				result.Error = f.Error
+				result.ErrorCode = f.ErrorCode
+				result.Message = fmt.Sprintf("Applied %d migrations; failed to deploy function %s", appliedMigrations, f.Slug)
+				break
+			}
+		}
+	}
 
 	if jsonOut {
-		out, _ := json.MarshalIndent(result, "", "  ")
-		fmt.Println(string(out))
+		printJSON(result)
+		if result.Status != "success" {
+			return &ExitError{Code: 1, Err: fmt.Errorf("%s", result.Message)}
+		}
 		return nil
 	}
 
 	fmt.Println()
-	fmt.Printf("✓ Push completed - applied %d migrations\n", appliedMigrations)
+	if result.Status != "success" {
+		if len(result.Skipped) > 0 {
+			fmt.Printf("  Skipped %d migration(s) after the failure (use --continue-on-error to keep going)\n", len(result.Skipped))
+		}
+		return fmt.Errorf("push failed: %s", result.Message)
+	}
+	fmt.Printf("✓ Push completed - %s\n", result.Message)
 
 	return nil
 }
 
-func buildPushPlan(cwd string, migrationsOnly bool) (*PushPlan, error) {
-	plan := &PushPlan{
-		Migrations: []string{},
-		Functions:  []string{},
-		Secrets:    []string{},
+// deployFunctions deploys each planned function in order and records the
+// result in functions.lock. With stopped set (an earlier step failed), all
+// are marked skipped. Returns the number deployed.
+func deployFunctions(ctx context.Context, target backend.Backend, cwd string, deploys []FunctionDeploy, stopped, continueOnError, jsonOut bool) int {
+	lock, err := functions.LoadLock(cwd)
+	if err != nil {
+		// Deploying still works - we just redeploy everything next time
+		lock = &functions.Lock{}
 	}
 
-	// Find migrations
-	migrationsDir := filepath.Join(cwd, "supabase", "migrations")
-	if entries, err := os.ReadDir(migrationsDir); err == nil {
-		for _, entry := range entries {
-			if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".sql") {
-				plan.Migrations = append(plan.Migrations, entry.Name())
+	deployed := 0
+	for i := range deploys {
+		d := &deploys[i]
+		if stopped || ctx.Err() != nil {
+			d.Status = DeploySkipped
+			continue
+		}
+
+		fn, err := deployFunction(ctx, target, d.bundle)
+		if err != nil {
+			d.Status = DeployFailed
+			d.Error = err.Error()
+			d.ErrorCode = api.ErrorCode(err)
+			if !jsonOut {
+				fmt.Printf("  ✗ Failed to deploy %s: %v\n", d.Slug, err)
 			}
+			stopped = !continueOnError
+			continue
+		}
+
+		d.Status = DeployDeployed
+		d.Version = fn.Version
+		lock.Record(target.Describe(), d.Slug, functions.Deployment{Hash: d.Hash, Version: fn.Version, EzbrSha256: fn.EzbrSha256})
+		deployed++
+		if !jsonOut {
+			fmt.Printf("  ✓ Deployed %s (v%d)\n", d.Slug, fn.Version)
+		}
+	}
+
+	if deployed > 0 {
+		if err := lock.Save(cwd); err != nil && !jsonOut {
+			fmt.Printf("  ⚠ Could not update %s: %v\n", functions.LockFile, err)
 		}
 	}
 
+	return deployed
+}
+
+// deployFunction uploads a bundle through the multipart deploy endpoint
+func deployFunction(ctx context.Context, target backend.Backend, bundle *functions.Bundle) (*api.Function, error) {
+	verifyJWT := bundle.VerifyJWT
+	metadata := api.DeployFunctionMetadata{
+		EntrypointPath: bundle.Entrypoint,
+		ImportMapPath:  bundle.ImportMap,
+		StaticPatterns: bundle.StaticPatterns,
+		VerifyJWT:      &verifyJWT,
+		Name:           bundle.Slug,
+	}
+
+	files := make([]api.FunctionFile, len(bundle.Files))
+	for i, f := range bundle.Files {
+		files[i] = api.FunctionFile{Name: f.Name, Content: f.Content}
+	}
+
+	return target.DeployFunction(ctx, bundle.Slug, metadata, files)
+}
+
+// applyMigration reads and applies one migration file, returning its content
+func applyMigration(ctx context.Context, target backend.Backend, cwd string, migration migrations.File) ([]byte, error) {
+	content, err := os.ReadFile(filepath.Join(cwd, migrations.Dir, migration.Filename))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration: %w", err)
+	}
+
+	// The API assigns its own version on apply, so record the full
+	// "<version>_<name>" as the name to match this file on later pushes
+	req := api.ApplyMigrationRequest{
+		Query: string(content),
+		Name:  migration.BaseName(),
+	}
+
+	return content, target.ApplyMigration(ctx, req)
+}
+
+// MigrationFailure describes a migration that failed to apply
+type MigrationFailure struct {
+	File      string `json:"file"`
+	Version   string `json:"version"`
+	Line      int    `json:"line,omitempty"`
+	Column    int    `json:"column,omitempty"`
+	Error     string `json:"error"`
+	ErrorCode string `json:"error_code,omitempty"`
+}
+
+func newMigrationFailure(migration migrations.File, content []byte, err error) MigrationFailure {
+	failure := MigrationFailure{
+		File:      filepath.Join(migrations.Dir, migration.Filename),
+		Version:   migration.Version,
+		Error:     err.Error(),
+		ErrorCode: api.ErrorCode(err),
+	}
+	if pos, ok := migrations.ErrorPosition(string(content), err.Error()); ok {
+		failure.Line = pos.Line
+		failure.Column = pos.Column
+	}
+	return failure
+}
+
+// Location returns file[:line[:column]]
+func (f MigrationFailure) Location() string {
+	switch {
+	case f.Line > 0 && f.Column > 0:
+		return fmt.Sprintf("%s:%d:%d", f.File, f.Line, f.Column)
+	case f.Line > 0:
+		return fmt.Sprintf("%s:%d", f.File, f.Line)
+	default:
+		return f.File
+	}
+}
+
+func printMigrationFailure(f MigrationFailure) {
+	fmt.Printf("  ✗ Failed to apply %s\n", f.Location())
+	for _, line := range strings.Split(strings.TrimSpace(f.Error), "\n") {
+		fmt.Printf("      %s\n", line)
+	}
+}
+
+func buildPushPlan(cwd string, cfg *profiles.Config, ref string, migrationsOnly bool, remoteMigrations []api.Migration, remoteFunctions []api.Function) (*PushPlan, error) {
+	plan := &PushPlan{
+		Migrations: []migrations.File{},
+		Functions:  []FunctionDeploy{},
+	}
+
+	// Find migrations not yet applied remotely
+	local, _, err := migrations.List(cwd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	plan.Drift = migrations.Compare(local, remoteMigrations)
+	plan.Migrations = plan.Drift.Pending
+
 	if migrationsOnly {
 		return plan, nil
 	}
 
-	// Find functions
-	functionsDir := filepath.Join(cwd, "supabase", "functions")
-	if entries, err := os.ReadDir(functionsDir); err == nil {
-		for _, entry := range entries {
-			if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") && entry.Name() != "_shared" {
-				// Check if function has an index.ts
-				indexPath := filepath.Join(functionsDir, entry.Name(), "index.ts")
-				if _, err := os.Stat(indexPath); err == nil {
-					plan.Functions = append(plan.Functions, entry.Name())
-				}
-			}
+	// Find functions whose bundle differs from what's deployed
+	slugs, err := functions.List(cwd, cfg.Functions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read functions: %w", err)
+	}
+
+	lock, err := functions.LoadLock(cwd)
+	if err != nil {
+		return nil, err
+	}
+
+	deployedVersions := make(map[string]int)
+	for _, fn := range remoteFunctions {
+		deployedVersions[fn.Slug] = fn.Version
+	}
+
+	for _, slug := range slugs {
+		bundle, err := functions.Build(cwd, slug, cfg.Functions[slug])
+		if err != nil {
+			return nil, err
 		}
+		hash := bundle.Hash()
+
+		reason := lock.NeedsDeploy(ref, slug, hash, deployedVersions[slug])
+		if reason == "" {
+			plan.UnchangedFunctions = append(plan.UnchangedFunctions, slug)
+			continue
+		}
+		plan.Functions = append(plan.Functions, FunctionDeploy{
+			Slug:   slug,
+			Reason: reason,
+			Status: DeployPending,
+			Hash:   hash,
+			bundle: bundle,
+		})
 	}
 
 	return plan, nil
 }
 
+// printMigrationDrift prints applied, pending and orphaned migrations
+func printMigrationDrift(drift *migrations.Drift) {
+	fmt.Printf("  Migrations: %d applied, %d pending, %d remote only\n",
+		len(drift.Applied), len(drift.Pending), len(drift.Orphaned))
+	// Only the most recent applied migrations are interesting
+	applied := drift.Applied
+	if len(applied) > 5 {
+		fmt.Printf("    ✓ ... %d earlier\n", len(applied)-5)
+		applied = applied[len(applied)-5:]
+	}
+	for _, f := range applied {
+		fmt.Printf("    ✓ %s\n", f.Filename)
+	}
+	for _, f := range drift.Pending {
+		fmt.Printf("    + %s\n", f.Filename)
+	}
+	for _, m := range drift.Orphaned {
+		fmt.Printf("    ! %s %s (remote only)\n", m.Version, m.Name)
+	}
+}
+
+// printTampered lists applied migrations whose content changed
+func printTampered(tampered []migrations.Tampered) {
+	fmt.Printf("  ✗ %d applied migration(s) were modified:\n", len(tampered))
+	for _, t := range tampered {
+		fmt.Printf("    %s (%s)\n", t.File, t.Version)
+	}
+}
+
+// secretsFound returns the number of (non-reserved) secrets in the env file
+func secretsFound(plan *secrets.Plan) int {
+	if plan == nil {
+		return 0
+	}
+	return plan.Count(secrets.ActionAdd) + plan.Count(secrets.ActionUpdate) + len(plan.Unchanged)
+}
+
 func pushError(jsonOut bool, message string, err error) error {
+	return pushErrorResult(jsonOut, PushResult{}, message, err)
+}
+
+// pushErrorResult is pushError with the plan computed so far attached
+func pushErrorResult(jsonOut bool, result PushResult, message string, err error) error {
 	if jsonOut {
-		result := PushResult{
-			Status:  "error",
-			Message: message,
-		}
+		result.Status = "error"
+		result.Message = message
 		if err != nil {
 			result.Error = err.Error()
+			result.ErrorCode = api.ErrorCode(err)
 		}
-		out, _ := json.MarshalIndent(result, "", "  ")
-		fmt.Println(string(out))
-		return nil
+		printJSON(result)
+		return &ExitError{Code: 1, Err: fmt.Errorf("%s", message)}
 	}
 
 	if err != nil {
@@ -0,0 +1,493 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/supabase/supabase-dx/cli/internal/api"
+	"github.com/supabase/supabase-dx/cli/internal/backend"
+	"github.com/supabase/supabase-dx/cli/internal/events"
+	"github.com/supabase/supabase-dx/cli/internal/functions"
+	"github.com/supabase/supabase-dx/cli/internal/migrations"
+	"github.com/supabase/supabase-dx/cli/internal/profiles"
+	"github.com/supabase/supabase-dx/cli/internal/watcher"
+)
+
+const testConfig = `[project]
+id = "abcdefghijklmnopqrst"
+
+[profiles.staging]
+mode = "remote"
+workflow = "git"
+
+[profiles.local]
+mode = "local"
+workflow = "git"
+`
+
+// fakeProject makes a temp project with the given migration files the
+// working directory, and points openBackend at fake for the rest of the test
+func fakeProject(t *testing.T, fake *backend.Fake, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+
+	write := func(rel, content string) {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", rel, err)
+		}
+	}
+	write(filepath.Join("supabase", "config.toml"), testConfig)
+	for name, content := range files {
+		write(filepath.Join("supabase", "migrations", name), content)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	previous := openBackend
+	openBackend = func(ctx context.Context, cfg *profiles.Config, profile *profiles.Profile, cwd string, create, jsonOut bool) (backend.Backend, func(), error) {
+		return fake, func() {}, nil
+	}
+	t.Cleanup(func() { openBackend = previous })
+
+	return root
+}
+
+// captureJSON collects --json results for the rest of the test
+func captureJSON(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var out bytes.Buffer
+	previous := jsonOutput
+	jsonOutput = &out
+	t.Cleanup(func() { jsonOutput = previous })
+	return &out
+}
+
+// pushed records a migration on fake the way push applies it: under the
+// file's base name, with a version the fake assigns
+func pushed(t *testing.T, fake *backend.Fake, filename, content string) {
+	t.Helper()
+	req := api.ApplyMigrationRequest{Name: strings.TrimSuffix(filename, ".sql"), Query: content}
+	if err := fake.ApplyMigration(context.Background(), req); err != nil {
+		t.Fatalf("failed to apply %s: %v", filename, err)
+	}
+}
+
+const (
+	initSQL  = "create table todos (id bigint primary key);\nalter table todos enable row level security;\n"
+	todosSQL = "alter table todos add column done boolean default false;\n"
+)
+
+func pushJSON(t *testing.T, out *bytes.Buffer, err error) PushResult {
+	t.Helper()
+	var result PushResult
+	if jsonErr := json.Unmarshal(out.Bytes(), &result); jsonErr != nil {
+		t.Fatalf("expected a JSON result, got %q (%v)", out.String(), err)
+	}
+	if result.Error != "" && result.Status == "success" {
+		t.Errorf("expected an error result not to succeed, got %+v", result)
+	}
+	return result
+}
+
+func TestPushPlansPendingMigrations(t *testing.T) {
+	fake := backend.NewFake("fake")
+	pushed(t, fake, "20240101000000_init.sql", initSQL)
+	fakeProject(t, fake, map[string]string{
+		"20240101000000_init.sql":  initSQL,
+		"20240102000000_todos.sql": todosSQL,
+	})
+	out := captureJSON(t)
+
+	err := runPush(context.Background(), "staging", true, true, true, true, false, false, false, false)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	result := pushJSON(t, out, err)
+	if result.Status != "success" || result.MigrationsFound != 2 || result.MigrationsPending != 1 {
+		t.Errorf("expected 1 of 2 migrations pending, got %+v", result)
+	}
+	if len(result.Migrations.Pending) != 1 || result.Migrations.Pending[0].Filename != "20240102000000_todos.sql" {
+		t.Errorf("expected only the todos migration pending, got %+v", result.Migrations.Pending)
+	}
+	if len(fake.Migrations) != 1 {
+		t.Errorf("expected a dry run to apply nothing, got %d migrations", len(fake.Migrations))
+	}
+}
+
+func TestPushRefusesDivergedHistory(t *testing.T) {
+	fake := backend.NewFake("fake")
+	pushed(t, fake, "20240101000000_init.sql", initSQL)
+	pushed(t, fake, "20240103000000_hotfix.sql", "select 1;")
+	fakeProject(t, fake, map[string]string{
+		"20240101000000_init.sql":  initSQL,
+		"20240102000000_todos.sql": todosSQL,
+	})
+	out := captureJSON(t)
+
+	err := runPush(context.Background(), "staging", false, true, true, true, false, false, false, false)
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 1 {
+		t.Fatalf("expected exit code 1, got %v", err)
+	}
+
+	result := pushJSON(t, out, err)
+	if result.Status != "error" || result.ErrorCode != "diverged_history" {
+		t.Errorf("expected a diverged_history error, got %+v", result)
+	}
+	if len(fake.Migrations) != 2 {
+		t.Errorf("expected nothing to be applied, got %d migrations", len(fake.Migrations))
+	}
+}
+
+// failingPush sets up three pending migrations, the second of which fails
+func failingPush(t *testing.T) *backend.Fake {
+	fake := backend.NewFake("fake")
+	fake.FailMigrations = map[string]error{
+		"20240102000000_bad": errors.New(`ERROR: syntax error at or near "tabel" (SQLSTATE 42601)`),
+	}
+	fakeProject(t, fake, map[string]string{
+		"20240101000000_init.sql":  initSQL,
+		"20240102000000_bad.sql":   "select 1;\n",
+		"20240103000000_todos.sql": todosSQL,
+	})
+	return fake
+}
+
+func TestPushAppliesPendingMigrations(t *testing.T) {
+	fake := backend.NewFake("fake")
+	root := fakeProject(t, fake, map[string]string{
+		"20240101000000_init.sql":  initSQL,
+		"20240102000000_todos.sql": todosSQL,
+	})
+	out := captureJSON(t)
+
+	err := runPush(context.Background(), "staging", false, true, true, true, false, false, false, false)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	result := pushJSON(t, out, err)
+	if result.Status != "success" || result.MigrationsApplied != 2 || !result.LockUpdated {
+		t.Errorf("expected 2 migrations applied and the lock updated, got %+v", result)
+	}
+	if len(fake.Migrations) != 2 || fake.Migrations[1].Name != "20240102000000_todos" {
+		t.Errorf("expected both migrations applied in order, got %+v", fake.Migrations)
+	}
+	if _, err := os.Stat(filepath.Join(root, "supabase", "migrations.lock")); err != nil {
+		t.Errorf("expected the lock to be written, got %v", err)
+	}
+}
+
+func TestPushStopsAtFirstFailure(t *testing.T) {
+	fake := failingPush(t)
+	out := captureJSON(t)
+
+	err := runPush(context.Background(), "staging", false, true, true, true, false, false, false, false)
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 1 {
+		t.Fatalf("expected exit code 1, got %v", err)
+	}
+
+	result := pushJSON(t, out, err)
+	if result.Status != "error" || result.MigrationsApplied != 1 {
+		t.Errorf("expected an error after 1 migration, got %+v", result)
+	}
+	if len(result.Failures) != 1 || result.Failures[0].File != filepath.Join("supabase", "migrations", "20240102000000_bad.sql") {
+		t.Fatalf("expected the bad migration to fail, got %+v", result.Failures)
+	}
+	if result.Error != result.Failures[0].Error || result.Error == "" {
+		t.Errorf("expected the failure's error on the result, got %q", result.Error)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0] != "20240103000000_todos.sql" {
+		t.Errorf("expected the todos migration to be skipped, got %v", result.Skipped)
+	}
+	if len(fake.Migrations) != 1 {
+		t.Errorf("expected only init applied, got %+v", fake.Migrations)
+	}
+}
+
+func TestPushContinueOnError(t *testing.T) {
+	fake := failingPush(t)
+	out := captureJSON(t)
+
+	err := runPush(context.Background(), "staging", false, true, true, true, false, true, false, false)
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 1 {
+		t.Fatalf("expected exit code 1, got %v", err)
+	}
+
+	result := pushJSON(t, out, err)
+	if result.Status != "error" || result.MigrationsApplied != 2 || len(result.Skipped) != 0 {
+		t.Errorf("expected the migrations after the failure applied, got %+v", result)
+	}
+	if len(fake.Migrations) != 2 || fake.Migrations[1].Name != "20240103000000_todos" {
+		t.Errorf("expected init and todos applied, got %+v", fake.Migrations)
+	}
+}
+
+func TestPushBaselinesWithoutLock(t *testing.T) {
+	fake := backend.NewFake("fake")
+	root := fakeProject(t, fake, map[string]string{"20240101000000_init.sql": initSQL})
+	migrationsDir := filepath.Join(root, "supabase", "migrations")
+	lockPath := filepath.Join(root, "supabase", "migrations.lock")
+	out := captureJSON(t)
+
+	if err := runPush(context.Background(), "staging", false, true, true, true, false, false, false, false); err != nil {
+		t.Fatalf("expected the first push to succeed, got %v", err)
+	}
+	if fake.Migrations[0].Version == "20240101000000" {
+		t.Fatal("expected the fake to assign its own version")
+	}
+
+	// A lost lock is baselined from the remote history, found by name
+	if err := os.Remove(lockPath); err != nil {
+		t.Fatalf("failed to remove lock: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(migrationsDir, "20240102000000_todos.sql"), []byte(todosSQL), 0644); err != nil {
+		t.Fatalf("failed to write migration: %v", err)
+	}
+	out.Reset()
+	err := runPush(context.Background(), "staging", false, true, true, true, false, false, false, false)
+	if err != nil {
+		t.Fatalf("expected no error, got %v (%s)", err, out)
+	}
+	result := pushJSON(t, out, err)
+	if result.MigrationsApplied != 1 || !result.LockUpdated {
+		t.Errorf("expected todos applied and the lock updated, got %+v", result)
+	}
+	lock, err := os.ReadFile(lockPath)
+	if err != nil || !bytes.Contains(lock, []byte(`"20240101000000"`)) {
+		t.Errorf("expected init to be recorded, got %s %v", lock, err)
+	}
+
+	// An edit made while the lock was gone is caught
+	if err := os.Remove(lockPath); err != nil {
+		t.Fatalf("failed to remove lock: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(migrationsDir, "20240101000000_init.sql"), []byte("create table todos ();\n"), 0644); err != nil {
+		t.Fatalf("failed to write migration: %v", err)
+	}
+	out.Reset()
+	err = runPush(context.Background(), "staging", false, true, true, true, false, false, false, false)
+	result = pushJSON(t, out, err)
+	if err == nil || result.ErrorCode != "migration_tampered" || len(result.Tampered) != 1 {
+		t.Errorf("expected init to be reported as tampered, got %+v", result)
+	}
+}
+
+// watchTarget is a --json watch on fake whose events are collected in the
+// returned buffer
+func watchTarget(fake *backend.Fake) (*WatchState, *bytes.Buffer) {
+	var out bytes.Buffer
+	state := &WatchState{
+		Profile:     "staging",
+		ProjectRef:  fake.Describe(),
+		Target:      fake,
+		closeTarget: func() {},
+		events:      events.NewWriter(&out),
+	}
+	return state, &out
+}
+
+// watchEvents returns the types of the events in out, and the payloads of
+// its auto_push events
+func watchEvents(t *testing.T, out *bytes.Buffer) (types []string, pushes []events.AutoPush) {
+	t.Helper()
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var event struct {
+			Type    string          `json:"type"`
+			Payload json.RawMessage `json:"payload"`
+		}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("expected an event, got %q", line)
+		}
+		types = append(types, event.Type)
+		if event.Type == "auto_push" {
+			var push events.AutoPush
+			json.Unmarshal(event.Payload, &push)
+			pushes = append(pushes, push)
+		}
+	}
+	return types, pushes
+}
+
+var autoPushProfile = &profiles.Profile{Name: "staging", Mode: "remote", Workflow: "git", Watch: profiles.WatchConfig{AutoPush: true}}
+
+func TestAutoPushMigrations(t *testing.T) {
+	fake := backend.NewFake("fake")
+	pushed(t, fake, "20240101000000_init.sql", initSQL)
+	root := fakeProject(t, fake, map[string]string{
+		"20240101000000_init.sql":  initSQL,
+		"20240102000000_todos.sql": todosSQL,
+		"20240103000000_seed.sql":  "insert into todos (id) values (1);\n",
+		"20240104000000_index.sql": "create index todos_done on todos (done);\n",
+	})
+	fake.FailMigrations = map[string]error{"20240103000000_seed": errors.New("duplicate key value")}
+	state, out := watchTarget(fake)
+
+	replanMigrations(context.Background(), state, root, autoPushProfile, true, true)
+
+	// The first failure stops the push, leaving the later migration pending
+	types, pushes := watchEvents(t, out)
+	if len(types) != 1 || len(pushes) != 1 {
+		t.Fatalf("expected one auto_push event, got %v", types)
+	}
+	push := pushes[0]
+	if push.Kind != "migrations" || push.Status != "error" || strings.Join(push.Migrations, ",") != "20240102000000_todos.sql" {
+		t.Errorf("expected todos applied before failing, got %+v", push)
+	}
+	if !strings.Contains(push.Message, "20240103000000_seed.sql") || push.Error != "duplicate key value" {
+		t.Errorf("expected the failure at seed to be reported, got %+v", push)
+	}
+	if len(fake.Migrations) != 2 {
+		t.Errorf("expected init and todos applied, got %+v", fake.Migrations)
+	}
+
+	lock, err := migrations.LoadLock(root)
+	if err != nil {
+		t.Fatalf("failed to load lock: %v", err)
+	}
+	if lock.Migrations["20240101000000"] == "" || lock.Migrations["20240102000000"] != migrations.Checksum([]byte(todosSQL)) {
+		t.Errorf("expected init baselined and todos recorded, got %v", lock.Migrations)
+	}
+	if _, ok := lock.Migrations["20240103000000"]; ok {
+		t.Errorf("expected the failed migration not to be recorded, got %v", lock.Migrations)
+	}
+
+	// Saving again retries from the failed migration
+	delete(fake.FailMigrations, "20240103000000_seed")
+	out.Reset()
+	replanMigrations(context.Background(), state, root, autoPushProfile, true, true)
+
+	_, pushes = watchEvents(t, out)
+	if len(pushes) != 1 || pushes[0].Status != "success" || strings.Join(pushes[0].Migrations, ",") != "20240103000000_seed.sql,20240104000000_index.sql" {
+		t.Errorf("expected seed and index applied, got %+v", pushes)
+	}
+	if len(fake.Migrations) != 4 {
+		t.Errorf("expected all four migrations applied, got %d", len(fake.Migrations))
+	}
+}
+
+func TestAutoPushRefusesLintErrors(t *testing.T) {
+	fake := backend.NewFake("fake")
+	pushed(t, fake, "20240101000000_init.sql", initSQL)
+	root := fakeProject(t, fake, map[string]string{
+		"20240101000000_init.sql": initSQL,
+		"20240102000000_drop.sql": "drop table todos;\n",
+	})
+	state, out := watchTarget(fake)
+
+	replanMigrations(context.Background(), state, root, autoPushProfile, true, true)
+
+	_, pushes := watchEvents(t, out)
+	if len(pushes) != 1 || pushes[0].Status != "refused" || pushes[0].ErrorCode != "lint_failed" {
+		t.Fatalf("expected the push to be refused for lint errors, got %+v", pushes)
+	}
+	if len(fake.Migrations) != 1 {
+		t.Errorf("expected nothing to be applied, got %+v", fake.Migrations)
+	}
+}
+
+func TestAutoPushRefusesProduction(t *testing.T) {
+	fake := backend.NewFake("fake")
+	pushed(t, fake, "20240101000000_init.sql", initSQL)
+	root := fakeProject(t, fake, map[string]string{
+		"20240101000000_init.sql":  initSQL,
+		"20240102000000_todos.sql": todosSQL,
+	})
+	production := *autoPushProfile
+	production.Production = true
+	cfg := &profiles.Config{Profiles: map[string]profiles.Profile{"production": production}}
+	state, out := watchTarget(fake)
+	state.Profile = "production"
+
+	change := watcher.Change{Kind: watcher.Migrations, Paths: []string{filepath.Join(migrations.Dir, "20240102000000_todos.sql")}}
+	handleChange(context.Background(), root, cfg, state, change, true, true)
+
+	// The change is still planned, just not pushed
+	types, pushes := watchEvents(t, out)
+	if strings.Join(types, ",") != "auto_push,migrations_changed" {
+		t.Fatalf("expected a refusal and then the plan, got %v", types)
+	}
+	if pushes[0].Status != "refused" || !strings.Contains(pushes[0].Message, "production") {
+		t.Errorf("expected the push to be refused for production, got %+v", pushes[0])
+	}
+	if len(fake.Migrations) != 1 {
+		t.Errorf("expected nothing to be applied, got %+v", fake.Migrations)
+	}
+}
+
+func TestAutoPushFunctions(t *testing.T) {
+	fake := backend.NewFake("fake")
+	root := fakeProject(t, fake, nil)
+	var paths []string
+	for _, slug := range []string{"bye", "hello"} {
+		path := filepath.Join(functions.Dir, slug, "index.ts")
+		if err := os.MkdirAll(filepath.Join(root, filepath.Dir(path)), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(root, path), []byte("Deno.serve(() => new Response())\n"), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+		paths = append(paths, path)
+	}
+	fake.FailDeploys = map[string]error{"bye": errors.New("bundle too large")}
+	cfg := &profiles.Config{}
+	change := watcher.Change{Kind: watcher.Functions, Paths: paths}
+	state, out := watchTarget(fake)
+
+	// A failed deploy doesn't hold up the others
+	replanFunctions(context.Background(), state, root, cfg, change, true, true)
+
+	_, pushes := watchEvents(t, out)
+	if len(pushes) != 1 {
+		t.Fatalf("expected one auto_push event, got %+v", pushes)
+	}
+	push := pushes[0]
+	if push.Kind != "functions" || push.Status != "error" || strings.Join(push.Functions, ",") != "hello" || push.Error != "bundle too large" {
+		t.Errorf("expected hello deployed and bye failed, got %+v", push)
+	}
+	lock, err := functions.LoadLock(root)
+	if err != nil {
+		t.Fatalf("failed to load lock: %v", err)
+	}
+	if d, ok := lock.Projects["fake"]["hello"]; !ok || d.Version != 1 {
+		t.Errorf("expected hello's deployment recorded, got %+v", lock.Projects)
+	}
+	if _, ok := lock.Projects["fake"]["bye"]; ok {
+		t.Errorf("expected bye not to be recorded, got %+v", lock.Projects)
+	}
+
+	// Saving again deploys only what isn't deployed yet
+	delete(fake.FailDeploys, "bye")
+	out.Reset()
+	replanFunctions(context.Background(), state, root, cfg, change, true, true)
+
+	_, pushes = watchEvents(t, out)
+	if len(pushes) != 1 || pushes[0].Status != "success" || strings.Join(pushes[0].Functions, ",") != "bye" {
+		t.Errorf("expected only bye deployed, got %+v", pushes)
+	}
+	if fake.Versions["hello"] != 1 || fake.Versions["bye"] != 1 {
+		t.Errorf("expected one deploy of each, got %v", fake.Versions)
+	}
+}
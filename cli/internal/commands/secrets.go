@@ -0,0 +1,334 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/supabase/supabase-dx/cli/internal/api"
+	"github.com/supabase/supabase-dx/cli/internal/backend"
+	"github.com/supabase/supabase-dx/cli/internal/envfile"
+	"github.com/supabase/supabase-dx/cli/internal/git"
+	"github.com/supabase/supabase-dx/cli/internal/profiles"
+	"github.com/supabase/supabase-dx/cli/internal/secrets"
+)
+
+type SecretsResult struct {
+	Status     string         `json:"status"`
+	Message    string         `json:"message"`
+	Profile    string         `json:"profile,omitempty"`
+	ProjectRef string         `json:"project_ref,omitempty"`
+	DryRun     bool           `json:"dry_run,omitempty"`
+	Secrets    []RemoteSecret `json:"secrets,omitempty"`
+	Plan       *secrets.Plan  `json:"plan,omitempty"`
+	Set        int            `json:"set,omitempty"`
+	Deleted    int            `json:"deleted,omitempty"`
+	Error      string         `json:"error,omitempty"`
+	ErrorCode  string         `json:"error_code,omitempty"`
+}
+
+// RemoteSecret is a remote secret as listed by `supa secrets list`. The
+// API only ever returns a digest of the value.
+type RemoteSecret struct {
+	Name      string `json:"name"`
+	Digest    string `json:"digest"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+}
+
+func NewSecretsCmd(profile *string, dryRun *bool, jsonOut *bool) *cobra.Command {
+	var envFile string
+
+	cmd := &cobra.Command{
+		Use:   "secrets",
+		Short: "Manage edge function secrets",
+		Long: `Secrets are read from the profile's env file: env_file in the profile
+(relative to supabase/), otherwise supabase/.env.<profile>, falling back to
+supabase/.env. Names starting with SUPABASE_ are reserved and ignored.
+
+Remote values are never downloaded; the API reports a digest of each
+value, which is compared with the digest of the local value.`,
+	}
+
+	cmd.PersistentFlags().StringVar(&envFile, "env-file", "", "Env file to read instead of the profile's")
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List remote secrets",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSecretsList(cmd.Context(), *profile, *jsonOut)
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "diff",
+		Short: "Show how remote secrets differ from the env file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSecretsSync(cmd.Context(), *profile, envFile, true, *jsonOut, false, false)
+		},
+	})
+
+	var prune, yes bool
+	syncCmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Set remote secrets from the env file",
+		Long: `Sync adds secrets that only exist in the env file and updates those whose
+value changed. Remote secrets missing from the file are kept unless --prune
+is given.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSecretsSync(cmd.Context(), *profile, envFile, *dryRun, *jsonOut, prune, yes)
+		},
+	}
+	syncCmd.Flags().BoolVar(&prune, "prune", false, "Delete remote secrets that aren't in the env file")
+	syncCmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip confirmation prompt")
+	cmd.AddCommand(syncCmd)
+
+	return cmd
+}
+
+func runSecretsList(ctx context.Context, profileName string, jsonOut bool) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return secretsError(jsonOut, "failed to get working directory", err)
+	}
+
+	cfg, err := profiles.LoadConfig(cwd)
+	if err != nil {
+		return secretsError(jsonOut, "failed to load config", err)
+	}
+
+	currentBranch, _ := git.GetCurrentBranch(cwd)
+
+	profile, selectedName, err := cfg.GetProfileOrAuto(profileName, currentBranch)
+	if err != nil {
+		return secretsError(jsonOut, "failed to get profile", err)
+	}
+
+	// Preview profiles work on the branch mapped to the current git branch
+	target, closeTarget, err := openBackend(ctx, cfg, profile, cwd, false, jsonOut)
+	if err != nil {
+		message, cause := openFailure(err)
+		return secretsError(jsonOut, message, cause)
+	}
+	defer closeTarget()
+	projectRef, _ := describeBackend(target)
+
+	remote, err := target.ListSecrets(ctx)
+	if err != nil {
+		return secretsError(jsonOut, "failed to fetch secrets", err)
+	}
+
+	result := SecretsResult{
+		Status:     "success",
+		Profile:    selectedName,
+		ProjectRef: projectRef,
+		Secrets:    []RemoteSecret{},
+	}
+	for _, s := range remote {
+		result.Secrets = append(result.Secrets, RemoteSecret{Name: s.Name, Digest: s.Value, UpdatedAt: s.UpdatedAt})
+	}
+	result.Message = fmt.Sprintf("%d secret(s)", len(result.Secrets))
+
+	if jsonOut {
+		printJSON(result)
+		return nil
+	}
+
+	if len(result.Secrets) == 0 {
+		fmt.Println("No secrets set.")
+		return nil
+	}
+	fmt.Printf("🔑 Secrets for %s\n", projectRef)
+	fmt.Println()
+	for _, s := range result.Secrets {
+		digest := s.Digest
+		if len(digest) > 12 {
+			digest = digest[:12]
+		}
+		fmt.Printf("  %-40s %s\n", s.Name, digest)
+	}
+	return nil
+}
+
+func runSecretsSync(ctx context.Context, profileName, envFile string, dryRun, jsonOut, prune, yes bool) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return secretsError(jsonOut, "failed to get working directory", err)
+	}
+
+	cfg, err := profiles.LoadConfig(cwd)
+	if err != nil {
+		return secretsError(jsonOut, "failed to load config", err)
+	}
+
+	currentBranch, _ := git.GetCurrentBranch(cwd)
+
+	profile, selectedName, err := cfg.GetProfileOrAuto(profileName, currentBranch)
+	if err != nil {
+		return secretsError(jsonOut, "failed to get profile", err)
+	}
+
+	// Preview profiles work on the branch mapped to the current git branch
+	target, closeTarget, err := openBackend(ctx, cfg, profile, cwd, !dryRun, jsonOut)
+	if err != nil {
+		message, cause := openFailure(err)
+		return secretsError(jsonOut, message, cause)
+	}
+	defer closeTarget()
+	projectRef, _ := describeBackend(target)
+
+	if envFile == "" {
+		envFile = profile.EnvFile
+	} else if !filepath.IsAbs(envFile) {
+		// --env-file is relative to where the command runs, not supabase/
+		envFile = filepath.Join(cwd, envFile)
+	}
+
+	plan, err := loadSecretsPlan(ctx, target, cwd, selectedName, envFile)
+	if err != nil {
+		return secretsError(jsonOut, "failed to compare secrets", err)
+	}
+	if plan == nil {
+		return secretsError(jsonOut, "no env file found - create supabase/.env."+selectedName+" or set env_file in the profile", nil)
+	}
+
+	result := SecretsResult{
+		Status:     "success",
+		Profile:    selectedName,
+		ProjectRef: projectRef,
+		DryRun:     dryRun,
+		Plan:       plan,
+	}
+
+	if !plan.Pending(prune) {
+		result.Message = "Secrets are up to date"
+		if jsonOut {
+			printJSON(result)
+			return nil
+		}
+		printSecretsPlan(plan, prune)
+		fmt.Println()
+		fmt.Println("✓ Secrets are up to date")
+		return nil
+	}
+
+	if !jsonOut {
+		printSecretsPlan(plan, prune)
+		fmt.Println()
+	}
+
+	if dryRun {
+		result.Message = "Dry run - no changes applied"
+		if jsonOut {
+			printJSON(result)
+			return nil
+		}
+		fmt.Println("  (dry-run mode - no changes will be applied)")
+		return nil
+	}
+
+	if !yes && !jsonOut {
+		fmt.Print("Apply these changes? [y/N] ")
+		reader := bufio.NewReader(os.Stdin)
+		response, _ := reader.ReadString('\n')
+		response = strings.TrimSpace(strings.ToLower(response))
+		if response != "y" && response != "yes" {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	result.Set, result.Deleted, err = plan.Apply(ctx, target, prune)
+	if err != nil {
+		result.Status = "error"
+		result.Error = err.Error()
+		result.ErrorCode = api.ErrorCode(err)
+	}
+	result.Message = fmt.Sprintf("Set %d secret(s), deleted %d", result.Set, result.Deleted)
+
+	if jsonOut {
+		printJSON(result)
+		if result.Status != "success" {
+			return &ExitError{Code: 1, Err: fmt.Errorf("%s", result.Message)}
+		}
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to sync secrets: %w", err)
+	}
+	fmt.Printf("✓ %s\n", result.Message)
+	return nil
+}
+
+// loadSecretsPlan diffs the profile's env file against the remote secrets.
+// Returns nil if there is no env file. envFile is the configured path, if
+// any (see secrets.Find).
+func loadSecretsPlan(ctx context.Context, target backend.Backend, cwd, profileName, envFile string) (*secrets.Plan, error) {
+	path := secrets.Find(cwd, profileName, envFile)
+	if path == "" {
+		if envFile != "" {
+			return nil, fmt.Errorf("env file %s not found", envFile)
+		}
+		return nil, nil
+	}
+
+	local, err := envfile.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	remote, err := target.ListSecrets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rel, err := filepath.Rel(cwd, path)
+	if err != nil {
+		rel = path
+	}
+	return secrets.Diff(rel, local, remote), nil
+}
+
+func printSecretsPlan(plan *secrets.Plan, prune bool) {
+	fmt.Printf("  Secrets: %s - %d to add, %d to update, %d remote only, %d unchanged\n",
+		plan.File, plan.Count(secrets.ActionAdd), plan.Count(secrets.ActionUpdate),
+		plan.Count(secrets.ActionRemove), len(plan.Unchanged))
+	for _, c := range plan.Changes {
+		switch c.Action {
+		case secrets.ActionAdd:
+			fmt.Printf("    + %s = %s\n", c.Name, c.Masked)
+		case secrets.ActionUpdate:
+			fmt.Printf("    ~ %s = %s\n", c.Name, c.Masked)
+		case secrets.ActionRemove:
+			if prune {
+				fmt.Printf("    - %s (will be deleted)\n", c.Name)
+			} else {
+				fmt.Printf("    - %s (remote only, use --prune to delete)\n", c.Name)
+			}
+		}
+	}
+}
+
+func secretsError(jsonOut bool, message string, err error) error {
+	if jsonOut {
+		result := SecretsResult{
+			Status:  "error",
+			Message: message,
+		}
+		if err != nil {
+			result.Error = err.Error()
+			result.ErrorCode = api.ErrorCode(err)
+		}
+		printJSON(result)
+		return &ExitError{Code: 1, Err: fmt.Errorf("%s", message)}
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", message, err)
+	}
+	return fmt.Errorf("%s", message)
+}
@@ -0,0 +1,528 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/supabase/supabase-dx/cli/internal/api"
+	"github.com/supabase/supabase-dx/cli/internal/backend"
+	"github.com/supabase/supabase-dx/cli/internal/envfile"
+	"github.com/supabase/supabase-dx/cli/internal/functions"
+	"github.com/supabase/supabase-dx/cli/internal/git"
+	"github.com/supabase/supabase-dx/cli/internal/migrations"
+	"github.com/supabase/supabase-dx/cli/internal/profiles"
+	"github.com/supabase/supabase-dx/cli/internal/secrets"
+)
+
+// Function drift states
+const (
+	FunctionDeployed   = "deployed"
+	FunctionChanged    = "changed" // deployed, but local code differs
+	FunctionLocalOnly  = "local_only"
+	FunctionRemoteOnly = "remote_only"
+	FunctionBuildError = "build_error" // deployed, but local code can't be bundled
+)
+
+// Types file states
+const (
+	TypesUpToDate = "up_to_date"
+	TypesStale    = "stale"
+	TypesMissing  = "missing"
+)
+
+type StatusResult struct {
+	Status     string            `json:"status"`
+	Message    string            `json:"message"`
+	Profile    string            `json:"profile,omitempty"`
+	ProjectRef string            `json:"project_ref,omitempty"`
+	InSync     bool              `json:"in_sync"`
+	Migrations *MigrationsStatus `json:"migrations,omitempty"`
+	Functions  *FunctionsStatus  `json:"functions,omitempty"`
+	Secrets    *SecretsStatus    `json:"secrets,omitempty"`
+	Types      *TypesStatus      `json:"types,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	ErrorCode  string            `json:"error_code,omitempty"`
+}
+
+// MigrationsStatus compares supabase/migrations with the remote history
+type MigrationsStatus struct {
+	InSync   bool                  `json:"in_sync"`
+	Local    int                   `json:"local"`
+	Remote   int                   `json:"remote"`
+	Applied  []migrations.File     `json:"applied"`
+	Pending  []migrations.File     `json:"pending"`
+	Orphaned []api.Migration       `json:"orphaned"`
+	Tampered []migrations.Tampered `json:"tampered,omitempty"`
+	// Applied migrations with no lock entry yet, checked on the next push
+	Unrecorded []string `json:"unrecorded,omitempty"`
+	Invalid    []string `json:"invalid,omitempty"`
+	Error      string   `json:"error,omitempty"`
+	ErrorCode  string   `json:"error_code,omitempty"`
+}
+
+// FunctionsStatus compares supabase/functions with deployed functions
+type FunctionsStatus struct {
+	InSync    bool             `json:"in_sync"`
+	Functions []FunctionStatus `json:"functions"`
+	Error     string           `json:"error,omitempty"`
+	ErrorCode string           `json:"error_code,omitempty"`
+}
+
+type FunctionStatus struct {
+	Slug    string `json:"slug"`
+	State   string `json:"state"`             // deployed, changed, local_only, remote_only, build_error
+	Version int    `json:"version,omitempty"` // deployed version
+	Status  string `json:"status,omitempty"`  // remote status, e.g. ACTIVE
+	Error   string `json:"error,omitempty"`   // why the local code can't be bundled
+}
+
+// SecretsStatus compares secret names in the local .env file with the
+// remote project. Values are never compared or printed.
+type SecretsStatus struct {
+	InSync     bool     `json:"in_sync"`
+	File       string   `json:"file,omitempty"`
+	Synced     []string `json:"synced"`
+	LocalOnly  []string `json:"local_only"`
+	RemoteOnly []string `json:"remote_only"`
+	Error      string   `json:"error,omitempty"`
+	ErrorCode  string   `json:"error_code,omitempty"`
+}
+
+// TypesStatus reports whether the generated types match the remote schema
+type TypesStatus struct {
+	InSync    bool   `json:"in_sync"`
+	File      string `json:"file"`
+	State     string `json:"state,omitempty"` // up_to_date, stale, missing
+	Error     string `json:"error,omitempty"`
+	ErrorCode string `json:"error_code,omitempty"`
+}
+
+func NewStatusCmd(profile *string, jsonOut *bool) *cobra.Command {
+	var schemas string
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show drift between local files and the remote project",
+		Long: `Status compares your local project with the remote Supabase project
+selected by the active profile and reports what has drifted:
+
+- Migrations in supabase/migrations that are pending, remote migrations
+  with no local file, and applied migrations whose content changed since
+  they were applied (see supabase/migrations.lock)
+- Edge functions that exist only locally or only remotely, or whose code
+  changed since it was deployed, with the deployed version
+- Secret names in the profile's env file (env_file, or supabase/.env.<profile>,
+  or supabase/.env) that are missing remotely, and remote secrets not in it
+- Whether supabase/types/database.ts is out of date
+
+A local profile compares migrations and types with the local database;
+the local stack serves functions and secrets from the working tree.
+
+Nothing is changed, locally or remotely. Applied migrations not yet in
+supabase/migrations.lock are listed, and checked against the remote
+history and recorded on the next push. Run 'supa push' or 'supa pull' to
+reconcile.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStatus(cmd.Context(), *profile, *jsonOut, schemas)
+		},
+	}
+
+	cmd.Flags().StringVar(&schemas, "schemas", "public", "Schemas to include when checking types")
+
+	return cmd
+}
+
+func runStatus(ctx context.Context, profileName string, jsonOut bool, schemas string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return statusError(jsonOut, "failed to get working directory", err)
+	}
+
+	cfg, err := profiles.LoadConfig(cwd)
+	if err != nil {
+		return statusError(jsonOut, "failed to load config", err)
+	}
+
+	currentBranch, _ := git.GetCurrentBranch(cwd)
+
+	profile, selectedName, err := cfg.GetProfileOrAuto(profileName, currentBranch)
+	if err != nil {
+		return statusError(jsonOut, "failed to get profile", err)
+	}
+
+	// Preview profiles work on the branch mapped to the current git branch
+	target, closeTarget, err := openBackend(ctx, cfg, profile, cwd, false, jsonOut)
+	if err != nil {
+		message, cause := openFailure(err)
+		return statusError(jsonOut, message, cause)
+	}
+	defer closeTarget()
+	projectRef, _ := describeBackend(target)
+
+	result := StatusResult{
+		Status:     "success",
+		Profile:    selectedName,
+		ProjectRef: projectRef,
+		Migrations: migrationsStatus(ctx, target, cwd),
+		Types:      typesStatus(ctx, target, cwd, schemas),
+	}
+	// The local stack serves functions and reads secrets from the working
+	// tree, so there's nothing deployed to compare them with
+	if !profile.IsLocal() {
+		result.Functions = functionsStatus(ctx, target, cwd, cfg)
+		result.Secrets = secretsStatus(ctx, target, cwd, selectedName, profile.EnvFile)
+	}
+
+	if ctx.Err() != nil {
+		return statusError(jsonOut, "status interrupted", ctx.Err())
+	}
+
+	result.InSync = result.Migrations.InSync && result.Types.InSync &&
+		(result.Functions == nil || result.Functions.InSync) &&
+		(result.Secrets == nil || result.Secrets.InSync)
+	if result.InSync {
+		result.Message = "Local and remote are in sync"
+	} else {
+		result.Message = "Local and remote have drifted"
+	}
+
+	if jsonOut {
+		printJSON(result)
+		return nil
+	}
+
+	printStatus(&result)
+	return nil
+}
+
+func migrationsStatus(ctx context.Context, target backend.Backend, cwd string) *MigrationsStatus {
+	status := &MigrationsStatus{}
+
+	local, invalid, err := migrations.List(cwd)
+	if err != nil {
+		status.Error = fmt.Sprintf("failed to read migrations: %v", err)
+		return status
+	}
+	status.Local = len(local)
+	status.Invalid = invalid
+
+	remote, err := target.ListMigrations(ctx)
+	if err != nil {
+		status.Error = err.Error()
+		status.ErrorCode = api.ErrorCode(err)
+		return status
+	}
+	status.Remote = len(remote)
+
+	drift := migrations.Compare(local, remote)
+	status.Applied = drift.Applied
+	status.Pending = drift.Pending
+	status.Orphaned = drift.Orphaned
+	status.InSync = drift.InSync()
+
+	lock, err := migrations.LoadLock(cwd)
+	if err != nil {
+		status.Error = err.Error()
+		status.InSync = false
+		return status
+	}
+	// Status never writes the lock: push baselines unrecorded migrations
+	// from the remote history
+	tampered, unrecorded, err := lock.Verify(cwd, drift.Applied)
+	if err != nil {
+		status.Error = err.Error()
+		status.InSync = false
+		return status
+	}
+	for _, f := range unrecorded {
+		status.Unrecorded = append(status.Unrecorded, f.Version)
+	}
+	if len(tampered) > 0 {
+		tamperErr := &migrations.TamperedError{Tampered: tampered}
+		status.Tampered = tampered
+		status.InSync = false
+		status.Error = tamperErr.Error()
+		status.ErrorCode = tamperErr.ErrorCode()
+	}
+
+	return status
+}
+
+func functionsStatus(ctx context.Context, target backend.Backend, cwd string, cfg *profiles.Config) *FunctionsStatus {
+	status := &FunctionsStatus{Functions: []FunctionStatus{}}
+
+	local, err := functions.List(cwd, cfg.Functions)
+	if err != nil {
+		status.Error = fmt.Sprintf("failed to read functions: %v", err)
+		return status
+	}
+	lock, err := functions.LoadLock(cwd)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+
+	remote, err := target.ListFunctions(ctx)
+	if err != nil {
+		status.Error = err.Error()
+		status.ErrorCode = api.ErrorCode(err)
+		return status
+	}
+
+	remoteBySlug := make(map[string]api.Function)
+	for _, f := range remote {
+		remoteBySlug[f.Slug] = f
+	}
+
+	status.InSync = true
+	for _, slug := range local {
+		fn, ok := remoteBySlug[slug]
+		if !ok {
+			status.Functions = append(status.Functions, FunctionStatus{Slug: slug, State: FunctionLocalOnly})
+			status.InSync = false
+			continue
+		}
+		delete(remoteBySlug, slug)
+
+		entry := FunctionStatus{
+			Slug:    slug,
+			State:   FunctionDeployed,
+			Version: fn.Version,
+			Status:  fn.Status,
+		}
+		// Code that can't be bundled can't be what's deployed, or be pushed
+		bundle, err := functions.Build(cwd, slug, cfg.Functions[slug])
+		switch {
+		case err != nil:
+			entry.State = FunctionBuildError
+			entry.Error = err.Error()
+			status.InSync = false
+		case lock.NeedsDeploy(target.Describe(), slug, bundle.Hash(), fn.Version) != "":
+			entry.State = FunctionChanged
+			status.InSync = false
+		}
+		status.Functions = append(status.Functions, entry)
+	}
+
+	for _, fn := range remoteBySlug {
+		status.Functions = append(status.Functions, FunctionStatus{
+			Slug:    fn.Slug,
+			State:   FunctionRemoteOnly,
+			Version: fn.Version,
+			Status:  fn.Status,
+		})
+		status.InSync = false
+	}
+
+	sort.Slice(status.Functions, func(i, j int) bool {
+		return status.Functions[i].Slug < status.Functions[j].Slug
+	})
+
+	return status
+}
+
+func secretsStatus(ctx context.Context, target backend.Backend, cwd, profileName, envFile string) *SecretsStatus {
+	status := &SecretsStatus{Synced: []string{}, LocalOnly: []string{}, RemoteOnly: []string{}}
+
+	path := secrets.Find(cwd, profileName, envFile)
+	if path == "" {
+		// Secrets aren't managed locally - nothing to compare
+		status.InSync = true
+		return status
+	}
+	status.File, _ = filepath.Rel(cwd, path)
+
+	local, err := envfile.Load(path)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+
+	remote, err := target.ListSecrets(ctx)
+	if err != nil {
+		status.Error = err.Error()
+		status.ErrorCode = api.ErrorCode(err)
+		return status
+	}
+
+	remoteNames := make(map[string]bool)
+	for _, s := range remote {
+		if !secrets.IsReserved(s.Name) {
+			remoteNames[s.Name] = true
+		}
+	}
+
+	for _, name := range envfile.Keys(local) {
+		if secrets.IsReserved(name) {
+			continue
+		}
+		if remoteNames[name] {
+			status.Synced = append(status.Synced, name)
+			delete(remoteNames, name)
+		} else {
+			status.LocalOnly = append(status.LocalOnly, name)
+		}
+	}
+	for name := range remoteNames {
+		status.RemoteOnly = append(status.RemoteOnly, name)
+	}
+	sort.Strings(status.RemoteOnly)
+
+	status.InSync = len(status.LocalOnly) == 0 && len(status.RemoteOnly) == 0
+	return status
+}
+
+func typesStatus(ctx context.Context, target backend.Backend, cwd, schemas string) *TypesStatus {
+	status := &TypesStatus{File: filepath.Join("supabase", "types", "database.ts")}
+
+	local, err := os.ReadFile(filepath.Join(cwd, status.File))
+	if os.IsNotExist(err) {
+		status.State = TypesMissing
+		return status
+	}
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+
+	remote, err := target.GetTypescriptTypes(ctx, schemas)
+	if err != nil {
+		status.Error = err.Error()
+		status.ErrorCode = api.ErrorCode(err)
+		return status
+	}
+
+	// pull writes the generated types verbatim, so any difference is drift
+	if bytes.Equal(local, []byte(remote.Types)) {
+		status.State = TypesUpToDate
+		status.InSync = true
+	} else {
+		status.State = TypesStale
+	}
+
+	return status
+}
+
+func printStatus(result *StatusResult) {
+	fmt.Println("📊 Status")
+	fmt.Println()
+	fmt.Printf("  Profile:    %s\n", result.Profile)
+	fmt.Printf("  Project:    %s\n", result.ProjectRef)
+	fmt.Println()
+
+	m := result.Migrations
+	fmt.Printf("  Migrations: %d local, %d remote\n", m.Local, m.Remote)
+	if len(m.Tampered) > 0 {
+		for _, t := range m.Tampered {
+			fmt.Printf("    ✗ %s (modified after being applied)\n", t.File)
+		}
+		fmt.Println("    Restore them, or run 'supa migrations repair' to accept the changes")
+	} else if m.Error != "" {
+		fmt.Printf("    ⚠ %s\n", m.Error)
+	}
+	if m.Error == "" || len(m.Tampered) > 0 {
+		if len(m.Applied) > 0 {
+			fmt.Printf("    ✓ %d applied\n", len(m.Applied))
+		}
+		if len(m.Unrecorded) > 0 {
+			fmt.Printf("    ⚠ %d applied but not in %s yet - checked against the remote on the next push\n", len(m.Unrecorded), migrations.LockFile)
+		}
+		for _, f := range m.Pending {
+			fmt.Printf("    + %s (pending)\n", f.Filename)
+		}
+		for _, r := range m.Orphaned {
+			fmt.Printf("    - %s %s (remote only)\n", r.Version, r.Name)
+		}
+	}
+	for _, f := range m.Invalid {
+		fmt.Printf("    ⚠ %s (no version prefix, ignored)\n", f)
+	}
+	fmt.Println()
+
+	if f := result.Functions; f != nil {
+		fmt.Printf("  Functions:  %d\n", len(f.Functions))
+		if f.Error != "" {
+			fmt.Printf("    ⚠ %s\n", f.Error)
+		}
+		for _, fn := range f.Functions {
+			switch fn.State {
+			case FunctionDeployed:
+				fmt.Printf("    ✓ %s (v%d)\n", fn.Slug, fn.Version)
+			case FunctionChanged:
+				fmt.Printf("    ~ %s (v%d, changed locally)\n", fn.Slug, fn.Version)
+			case FunctionLocalOnly:
+				fmt.Printf("    + %s (not deployed)\n", fn.Slug)
+			case FunctionRemoteOnly:
+				fmt.Printf("    - %s (v%d, remote only)\n", fn.Slug, fn.Version)
+			case FunctionBuildError:
+				fmt.Printf("    ✗ %s (v%d, can't be bundled: %s)\n", fn.Slug, fn.Version, fn.Error)
+			}
+		}
+		fmt.Println()
+	}
+
+	if s := result.Secrets; s != nil {
+		if s.File == "" && s.Error == "" {
+			fmt.Println("  Secrets:    no local .env file")
+		} else {
+			fmt.Printf("  Secrets:    %s\n", s.File)
+			if s.Error != "" {
+				fmt.Printf("    ⚠ %s\n", s.Error)
+			}
+			if len(s.Synced) > 0 {
+				fmt.Printf("    ✓ %d set remotely\n", len(s.Synced))
+			}
+			for _, name := range s.LocalOnly {
+				fmt.Printf("    + %s (not set remotely)\n", name)
+			}
+			for _, name := range s.RemoteOnly {
+				fmt.Printf("    - %s (remote only)\n", name)
+			}
+		}
+		fmt.Println()
+	}
+
+	t := result.Types
+	switch {
+	case t.Error != "":
+		fmt.Printf("  Types:      ⚠ %s\n", t.Error)
+	case t.State == TypesUpToDate:
+		fmt.Printf("  Types:      ✓ %s is up to date\n", t.File)
+	case t.State == TypesStale:
+		fmt.Printf("  Types:      ⚠ %s is stale - run 'supa pull --types-only'\n", t.File)
+	case t.State == TypesMissing:
+		fmt.Printf("  Types:      ⚠ %s is missing - run 'supa pull --types-only'\n", t.File)
+	}
+	fmt.Println()
+
+	if result.InSync {
+		fmt.Println("✓ " + result.Message)
+	} else {
+		fmt.Println("⚠ " + result.Message)
+	}
+}
+
+func statusError(jsonOut bool, message string, err error) error {
+	if jsonOut {
+		result := StatusResult{
+			Status:  "error",
+			Message: message,
+		}
+		if err != nil {
+			result.Error = err.Error()
+			result.ErrorCode = api.ErrorCode(err)
+		}
+		printJSON(result)
+		return &ExitError{Code: 1, Err: fmt.Errorf("%s", message)}
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", message, err)
+	}
+	return fmt.Errorf("%s", message)
+}
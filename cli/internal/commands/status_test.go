@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/supabase/supabase-dx/cli/internal/api"
+	"github.com/supabase/supabase-dx/cli/internal/backend"
+	"github.com/supabase/supabase-dx/cli/internal/functions"
+	"github.com/supabase/supabase-dx/cli/internal/profiles"
+)
+
+func TestStatusComparesMigrations(t *testing.T) {
+	db := backend.NewFake("localhost:54322/postgres")
+	pushed(t, db, "20240101000000_init.sql", initSQL)
+	fakeProject(t, db, map[string]string{
+		"20240101000000_init.sql":  initSQL,
+		"20240102000000_todos.sql": todosSQL,
+	})
+	out := captureJSON(t)
+
+	if err := runStatus(context.Background(), "local", true, "public"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var result StatusResult
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("expected a JSON result, got %q", out.String())
+	}
+	m := result.Migrations
+	if m == nil || m.InSync || m.Local != 2 || m.Remote != 1 {
+		t.Fatalf("expected 1 of 2 migrations applied, got %+v", m)
+	}
+	if len(m.Pending) != 1 || m.Pending[0].Filename != "20240102000000_todos.sql" {
+		t.Errorf("expected only the todos migration pending, got %+v", m.Pending)
+	}
+	if result.InSync {
+		t.Error("expected the profile not to be in sync")
+	}
+}
+
+func TestStatusLocalSkipsFunctionsAndSecrets(t *testing.T) {
+	db := backend.NewFake("localhost:54322/postgres")
+	pushed(t, db, "20240101000000_init.sql", initSQL)
+	root := fakeProject(t, db, map[string]string{"20240101000000_init.sql": initSQL})
+	for name, content := range map[string]string{
+		filepath.Join("functions", "hello", "index.ts"): "Deno.serve(() => new Response())\n",
+		".env.local": "STRIPE_KEY=sk_test\n",
+	} {
+		path := filepath.Join(root, "supabase", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	out := captureJSON(t)
+
+	if err := runStatus(context.Background(), "local", true, "public"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var result StatusResult
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("expected a JSON result, got %q", out.String())
+	}
+	if result.Functions != nil || result.Secrets != nil {
+		t.Errorf("expected no functions or secrets for a local profile, got %+v %+v", result.Functions, result.Secrets)
+	}
+	if !result.Migrations.InSync {
+		t.Errorf("expected migrations in sync, got %+v", result.Migrations)
+	}
+}
+
+func TestFunctionsStatusReportsBuildErrors(t *testing.T) {
+	fake := backend.NewFake("fake")
+	fake.Functions["hello"] = []api.FunctionFile{{Name: "index.ts", Content: []byte("Deno.serve(() => new Response())\n")}}
+	fake.Versions["hello"] = 3
+	root := fakeProject(t, fake, nil)
+	path := filepath.Join(root, "supabase", "functions", "hello", "index.ts")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("Deno.serve(() => new Response())\n"), 0644); err != nil {
+		t.Fatalf("failed to write function: %v", err)
+	}
+	// An import map that doesn't exist fails the bundle
+	cfg := &profiles.Config{Functions: map[string]functions.Config{"hello": {ImportMap: "functions/missing.json"}}}
+
+	status := functionsStatus(context.Background(), fake, root, cfg)
+	if status.InSync {
+		t.Error("expected a function that can't be bundled to be out of sync")
+	}
+	if len(status.Functions) != 1 || status.Functions[0].State != FunctionBuildError || status.Functions[0].Error == "" {
+		t.Errorf("expected hello to report a build error, got %+v", status.Functions)
+	}
+}
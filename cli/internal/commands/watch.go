@@ -2,35 +2,43 @@ package commands
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"io"
 	"os"
-	"os/signal"
 	"path/filepath"
 	"strings"
-	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/supabase/supabase-dx/cli/internal/api"
-	"github.com/supabase/supabase-dx/cli/internal/config"
+	"github.com/supabase/supabase-dx/cli/internal/backend"
+	"github.com/supabase/supabase-dx/cli/internal/declarative"
+	"github.com/supabase/supabase-dx/cli/internal/events"
+	"github.com/supabase/supabase-dx/cli/internal/functions"
 	"github.com/supabase/supabase-dx/cli/internal/git"
+	"github.com/supabase/supabase-dx/cli/internal/lint"
+	"github.com/supabase/supabase-dx/cli/internal/migrations"
 	"github.com/supabase/supabase-dx/cli/internal/profiles"
+	"github.com/supabase/supabase-dx/cli/internal/watcher"
 )
 
-type WatchResult struct {
-	Status  string `json:"status"`
-	Message string `json:"message"`
-	Profile string `json:"profile,omitempty"`
-	Error   string `json:"error,omitempty"`
-}
-
 type WatchState struct {
 	Profile       string
-	ProjectRef    string
+	ProjectRef    string // what Target describes as: a project ref or local database
+	Target        backend.Backend
 	LastBranch    string
 	LastTypesGen  time.Time
-	TypesInterval time.Duration
+	TypesInterval time.Duration // 0 when types are only regenerated on changes
+
+	closeTarget func()
+	events      *events.Writer // --json output
+}
+
+// emit writes an event in JSON mode
+func (s *WatchState) emit(payload events.Payload) {
+	if s.events != nil {
+		s.events.Emit(payload)
+	}
 }
 
 func NewWatchCmd(profile *string, jsonOut *bool) *cobra.Command {
@@ -42,32 +50,47 @@ func NewWatchCmd(profile *string, jsonOut *bool) *cobra.Command {
 		Short: "Watch for changes and keep things running",
 		Long: `Watch monitors your project for changes and takes action automatically.
 
-This includes:
-- Detecting git branch changes and switching profiles
-- Regenerating TypeScript types when schema changes
-- Watching for file changes in supabase/ directory`,
+Each kind of change triggers its own action:
+- .git/HEAD: follow the git branch, switching profiles and preview branches
+- supabase/migrations: show what a push would apply
+- supabase/schemas: show the migration a push would generate (declarative profiles)
+- supabase/functions: show which functions a push would deploy
+
+With watch.auto_push = true in the profile, new or edited pending migrations
+are applied and changed functions deployed as soon as they're saved.
+Migrations with lint errors (see 'supa lint') are not applied. Profiles
+with production = true are never pushed to automatically.
+
+Types are regenerated when the target or its schema may have changed. Use
+--types-interval to also refresh them on a timer, e.g. for changes made in
+the dashboard.
+
+With --json, watch writes one event per line: {"v", "type", "ts", "seq",
+"payload"}, as described by cli/events-schema/events.schema.json.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runWatch(*profile, *jsonOut, typesInterval, noBranchWatch)
+			return runWatch(cmd.Context(), *profile, *jsonOut, os.Stdout, typesInterval, noBranchWatch)
 		},
 	}
 
-	cmd.Flags().StringVar(&typesInterval, "types-interval", "30s", "Interval for regenerating types (e.g., 30s, 1m, 5m)")
+	cmd.Flags().StringVar(&typesInterval, "types-interval", "0", "Also regenerate types on this interval (e.g., 1m, 5m; 0 disables)")
 	cmd.Flags().BoolVar(&noBranchWatch, "no-branch-watch", false, "Disable git branch watching")
 
 	return cmd
 }
 
-func runWatch(profileName string, jsonOut bool, typesInterval string, noBranchWatch bool) error {
+// runWatch watches until ctx is done. In JSON mode events are written to
+// eventsOut, one per Write.
+func runWatch(ctx context.Context, profileName string, jsonOut bool, eventsOut io.Writer, typesInterval string, noBranchWatch bool) error {
 	// Get current working directory
 	cwd, err := os.Getwd()
 	if err != nil {
-		return watchError(jsonOut, "failed to get working directory", err)
+		return watchError(jsonOut, eventsOut, "failed to get working directory", err)
 	}
 
 	// Load project config
 	cfg, err := profiles.LoadConfig(cwd)
 	if err != nil {
-		return watchError(jsonOut, "failed to load config", err)
+		return watchError(jsonOut, eventsOut, "failed to load config", err)
 	}
 
 	// Get current git branch
@@ -76,105 +99,466 @@ func runWatch(profileName string, jsonOut bool, typesInterval string, noBranchWa
 	// Get profile
 	profile, selectedName, err := cfg.GetProfileOrAuto(profileName, currentBranch)
 	if err != nil {
-		return watchError(jsonOut, "failed to get profile", err)
+		return watchError(jsonOut, eventsOut, "failed to get profile", err)
 	}
 
-	// Get project ref
-	projectRef := profile.GetProjectRef(cfg)
-	if projectRef == "" {
-		return watchError(jsonOut, "no project ref configured", nil)
+	// Parse types interval
+	interval, err := time.ParseDuration(typesInterval)
+	if err != nil || interval < 0 {
+		return watchError(jsonOut, eventsOut, fmt.Sprintf("invalid --types-interval %q", typesInterval), nil)
 	}
 
-	// Get access token
-	token, err := config.GetAccessToken()
+	// Preview profiles work on the branch mapped to the current git branch
+	target, closeTarget, err := openBackend(ctx, cfg, profile, cwd, true, jsonOut)
 	if err != nil {
-		return watchError(jsonOut, "authentication required", err)
+		message, cause := openFailure(err)
+		return watchError(jsonOut, eventsOut, message, cause)
 	}
 
-	// Parse types interval
-	interval, err := time.ParseDuration(typesInterval)
+	// Initialize state
+	state := &WatchState{
+		Profile:       selectedName,
+		ProjectRef:    target.Describe(),
+		Target:        target,
+		LastBranch:    currentBranch,
+		TypesInterval: interval,
+		closeTarget:   closeTarget,
+	}
+	if jsonOut {
+		state.events = events.NewWriter(eventsOut)
+	}
+	// The target changes as the profile follows the git branch
+	defer func() { state.closeTarget() }()
+
+	w, err := watcher.New(cwd, watcher.DefaultDebounce)
 	if err != nil {
-		interval = 30 * time.Second
+		return watchError(jsonOut, eventsOut, "failed to watch project files", err)
 	}
+	defer w.Close()
 
-	// JSON mode - just output status and exit
 	if jsonOut {
-		result := WatchResult{
-			Status:  "running",
-			Message: "Watch mode started",
-			Profile: selectedName,
+		started := events.WatchStarted{
+			Profile:    selectedName,
+			ProjectRef: state.ProjectRef,
+			Branch:     currentBranch,
+			AutoPush:   profile.AutoPushes(),
+		}
+		if interval > 0 {
+			started.TypesInterval = interval.String()
 		}
-		out, _ := json.MarshalIndent(result, "", "  ")
-		fmt.Println(string(out))
-		// Continue to watch loop but output events as JSON
+		state.emit(started)
 	} else {
 		fmt.Println("👀 Watch mode started")
 		fmt.Println()
 		fmt.Printf("  Profile:       %s\n", selectedName)
-		fmt.Printf("  Project:       %s\n", projectRef)
+		fmt.Printf("  Project:       %s\n", target.Describe())
 		fmt.Printf("  Git branch:    %s\n", currentBranch)
-		fmt.Printf("  Types refresh: every %s\n", interval)
+		if interval > 0 {
+			fmt.Printf("  Types refresh: on changes and every %s\n", interval)
+		} else {
+			fmt.Printf("  Types refresh: on changes\n")
+		}
+		switch {
+		case profile.AutoPushes():
+			fmt.Printf("  Auto-push:     on\n")
+		case profile.Watch.AutoPush:
+			fmt.Printf("  Auto-push:     off (production profile)\n")
+		}
 		fmt.Println()
 		fmt.Println("Press Ctrl+C to stop")
 		fmt.Println()
 	}
 
-	// Create API client
-	client := api.NewClient(token)
+	// Start from up-to-date types
+	regenerateTypes(ctx, state, cwd, jsonOut)
 
-	// Initialize state
-	state := &WatchState{
-		Profile:       selectedName,
-		ProjectRef:    projectRef,
-		LastBranch:    currentBranch,
-		TypesInterval: interval,
+	// A nil channel never fires, leaving types to changes
+	var typesTick <-chan time.Time
+	if interval > 0 {
+		typesTicker := time.NewTicker(interval)
+		defer typesTicker.Stop()
+		typesTick = typesTicker.C
 	}
 
-	// Set up signal handling
-	ctx, cancel := context.WithCancel(context.Background())
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	go func() {
-		<-sigChan
-		cancel()
-	}()
-
-	// Main watch loop
-	ticker := time.NewTicker(5 * time.Second)
-	typesTicker := time.NewTicker(interval)
-	defer ticker.Stop()
-	defer typesTicker.Stop()
-
 	for {
 		select {
 		case <-ctx.Done():
 			if jsonOut {
-				result := WatchResult{
-					Status:  "stopped",
-					Message: "Watch mode stopped",
-					Profile: state.Profile,
-				}
-				out, _ := json.MarshalIndent(result, "", "  ")
-				fmt.Println(string(out))
+				state.emit(events.WatchStopped{Profile: state.Profile})
 			} else {
 				fmt.Println("\n👋 Watch mode stopped")
 			}
 			return nil
 
-		case <-ticker.C:
-			// Check for git branch changes
-			if !noBranchWatch {
-				checkBranchChange(cwd, cfg, state, jsonOut)
+		case change := <-w.Changes:
+			handleChange(ctx, cwd, cfg, state, change, noBranchWatch, jsonOut)
+
+		case err := <-w.Errors:
+			if jsonOut {
+				state.emit(events.WatchError{Message: "file watching failed", Error: err.Error()})
+			} else {
+				fmt.Printf("  ⚠ File watching: %v\n", err)
 			}
 
-		case <-typesTicker.C:
-			// Regenerate types
-			regenerateTypes(client, state.ProjectRef, cwd, jsonOut)
+		case <-typesTick:
+			regenerateTypes(ctx, state, cwd, jsonOut)
+		}
+	}
+}
+
+// handleChange runs the action for one kind of change
+func handleChange(ctx context.Context, cwd string, cfg *profiles.Config, state *WatchState, change watcher.Change, noBranchWatch, jsonOut bool) {
+	if change.Kind == watcher.Head {
+		if noBranchWatch {
+			return
+		}
+		before := state.ProjectRef
+		checkBranchChange(ctx, cwd, cfg, state, jsonOut)
+		if state.ProjectRef != before {
+			regenerateTypes(ctx, state, cwd, jsonOut)
+		}
+		return
+	}
+
+	// The profile follows the branch, so look it up for every change
+	profile, err := cfg.GetProfile(state.Profile)
+	if err != nil {
+		return
+	}
+
+	switch change.Kind {
+	case watcher.Migrations:
+		replanMigrations(ctx, state, cwd, profile, autoPush(state, profile, change.Kind, jsonOut), jsonOut)
+		// The new migration may already be applied, e.g. by the local stack
+		regenerateTypes(ctx, state, cwd, jsonOut)
+
+	case watcher.Schemas:
+		if !profile.IsDeclarative() {
+			// Written by pull in the dashboard workflow - nothing to plan
+			return
+		}
+		replanSchemas(ctx, state, cwd, profile, jsonOut)
+
+	case watcher.Functions:
+		if profile.IsLocal() {
+			// The local stack serves functions from the working tree
+			return
+		}
+		replanFunctions(ctx, state, cwd, cfg, change, autoPush(state, profile, change.Kind, jsonOut), jsonOut)
+	}
+}
+
+// autoPush reports whether a change is pushed as soon as it's saved. A
+// production profile with auto_push set is refused, and told so.
+func autoPush(state *WatchState, profile *profiles.Profile, kind watcher.Kind, jsonOut bool) bool {
+	if profile.Watch.AutoPush && profile.Production {
+		reportAutoPush(state, events.AutoPush{Kind: string(kind), Status: "refused", Message: fmt.Sprintf("profile %s is production - run 'supa push' instead", profile.Name)}, nil, jsonOut)
+		return false
+	}
+	return profile.AutoPushes()
+}
+
+// replanMigrations reports the migrations a push would apply, or with push
+// set, applies them
+func replanMigrations(ctx context.Context, state *WatchState, cwd string, profile *profiles.Profile, push, jsonOut bool) {
+	if push {
+		// A daemon push must not apply the same migrations, or write
+		// migrations.lock, while this one plans and applies
+		commandMu.Lock()
+		defer commandMu.Unlock()
+	}
+
+	local, _, err := migrations.List(cwd)
+	if err != nil {
+		watchEventError(state, watcher.Migrations, "Could not read migrations", err)
+		return
+	}
+	remote, err := state.Target.ListMigrations(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			watchEventError(state, watcher.Migrations, "Could not fetch remote migrations", err)
+		}
+		return
+	}
+	drift := migrations.Compare(local, remote)
+
+	if push && len(drift.Pending) > 0 {
+		autoPushMigrations(ctx, state, cwd, profile, drift, jsonOut)
+		return
+	}
+
+	if jsonOut {
+		event := events.MigrationsChanged{}
+		for _, f := range drift.Pending {
+			event.Pending = append(event.Pending, f.Filename)
+		}
+		if err := drift.Check(); err != nil {
+			event.Error = err.Error()
+		}
+		state.emit(event)
+		return
+	}
+
+	if len(drift.Pending) == 0 {
+		fmt.Println("📋 Migrations changed - nothing to push")
+	} else {
+		fmt.Printf("📋 Migrations changed - %d to push:\n", len(drift.Pending))
+		for _, f := range drift.Pending {
+			fmt.Printf("    + %s\n", f.Filename)
+		}
+	}
+	if err := drift.Check(); err != nil {
+		fmt.Printf("  ⚠ %v\n", err)
+	}
+}
+
+// replanSchemas reports the migration push would generate from
+// supabase/schemas
+func replanSchemas(ctx context.Context, state *WatchState, cwd string, profile *profiles.Profile, jsonOut bool) {
+	diff, err := diffSchemas(ctx, state.Target, cwd, profile)
+	if err != nil {
+		if ctx.Err() == nil {
+			watchEventError(state, watcher.Schemas, "Could not diff declarative schemas", err)
+		}
+		return
+	}
+
+	if jsonOut {
+		state.emit(events.SchemaChanged{Statements: diff.Statements, Warnings: diff.Warnings})
+		return
+	}
+
+	if diff.Empty() {
+		fmt.Printf("🧬 %s matches the database\n", declarative.Dir)
+	} else {
+		fmt.Printf("🧬 %s changed - push would generate %d statement(s)\n", declarative.Dir, len(diff.Statements))
+	}
+	for _, w := range diff.Warnings {
+		fmt.Printf("  ⚠ %s\n", w)
+	}
+}
+
+// replanFunctions reports the functions a change touches whose bundle
+// differs from what's deployed, or with push set, deploys them
+func replanFunctions(ctx context.Context, state *WatchState, cwd string, cfg *profiles.Config, change watcher.Change, push, jsonOut bool) {
+	if push {
+		// As for migrations: no daemon push deploys alongside this one
+		commandMu.Lock()
+		defer commandMu.Unlock()
+	}
+
+	slugs, err := functions.List(cwd, cfg.Functions)
+	if err != nil {
+		watchEventError(state, watcher.Functions, "Could not read functions", err)
+		return
+	}
+	touched := change.Slugs(slugs)
+	if len(touched) == 0 {
+		return
+	}
+
+	remote, err := state.Target.ListFunctions(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			watchEventError(state, watcher.Functions, "Could not fetch deployed functions", err)
+		}
+		return
+	}
+	deployedVersions := make(map[string]int)
+	for _, fn := range remote {
+		deployedVersions[fn.Slug] = fn.Version
+	}
+
+	lock, err := functions.LoadLock(cwd)
+	if err != nil {
+		watchEventError(state, watcher.Functions, "Could not read "+functions.LockFile, err)
+		return
+	}
+
+	var deploys []FunctionDeploy
+	for _, slug := range touched {
+		bundle, err := functions.Build(cwd, slug, cfg.Functions[slug])
+		if err != nil {
+			watchEventError(state, watcher.Functions, "Could not bundle "+slug, err)
+			continue
 		}
+		hash := bundle.Hash()
+		reason := lock.NeedsDeploy(state.Target.Describe(), slug, hash, deployedVersions[slug])
+		if reason == "" {
+			continue
+		}
+		deploys = append(deploys, FunctionDeploy{Slug: slug, Reason: reason, Status: DeployPending, Hash: hash, bundle: bundle})
+	}
+	if len(deploys) == 0 {
+		return
+	}
+
+	if push {
+		autoPushFunctions(ctx, state, cwd, deploys, jsonOut)
+		return
+	}
+
+	if jsonOut {
+		event := events.FunctionsChanged{Functions: make([]events.FunctionChange, len(deploys))}
+		for i, d := range deploys {
+			event.Functions[i] = events.FunctionChange{Slug: d.Slug, Reason: d.Reason}
+		}
+		state.emit(event)
+		return
+	}
+	fmt.Printf("⚡ Functions changed - %d to deploy:\n", len(deploys))
+	for _, d := range deploys {
+		fmt.Printf("    + %s (%s)\n", d.Slug, d.Reason)
 	}
 }
 
-func checkBranchChange(cwd string, cfg *profiles.Config, state *WatchState, jsonOut bool) {
+// autoPushMigrations applies pending migrations in order, stopping at the
+// first failure, with the same history, lockfile and lint checks as push
+func autoPushMigrations(ctx context.Context, state *WatchState, cwd string, profile *profiles.Profile, drift *migrations.Drift, jsonOut bool) {
+	result := events.AutoPush{Kind: string(watcher.Migrations), Status: "error"}
+
+	if err := drift.Check(); err != nil {
+		result.Message = "remote migration history has diverged - run 'supa pull'"
+		reportAutoPush(state, result, err, jsonOut)
+		return
+	}
+
+	lock, err := migrations.LoadLock(cwd)
+	if err != nil {
+		result.Message = "failed to load migrations lock"
+		reportAutoPush(state, result, err, jsonOut)
+		return
+	}
+	tampered, added, err := verifyLock(ctx, state.Target, cwd, lock, drift)
+	if err != nil {
+		result.Message = "failed to verify applied migrations"
+		reportAutoPush(state, result, err, jsonOut)
+		return
+	}
+	if len(tampered) > 0 {
+		result.Message = "applied migrations were modified - restore them and add a new migration"
+		reportAutoPush(state, result, &migrations.TamperedError{Tampered: tampered}, jsonOut)
+		return
+	}
+
+	findings, err := lintMigrations(ctx, state.Target, cwd, profile, drift.Pending)
+	if err != nil {
+		result.Message = "failed to lint pending migrations"
+		reportAutoPush(state, result, err, jsonOut)
+		return
+	}
+	if lint.Errors(findings) > 0 {
+		// Fixing the file, or the profile's lint rules, retries it on the next save
+		if !jsonOut {
+			printLintFindings(findings)
+		}
+		result.Status = "refused"
+		result.Message = "pending migrations failed lint - fix them or run 'supa push --no-lint'"
+		reportAutoPush(state, result, &lint.FailedError{Findings: findings}, jsonOut)
+		return
+	}
+
+	var failure *MigrationFailure
+	var failErr error
+	for _, migration := range drift.Pending {
+		content, err := applyMigration(ctx, state.Target, cwd, migration)
+		if err != nil {
+			f := newMigrationFailure(migration, content, err)
+			failure, failErr = &f, err
+			break
+		}
+		result.Migrations = append(result.Migrations, migration.Filename)
+		lock.Migrations[migration.Version] = migrations.Checksum(content)
+		if !jsonOut {
+			fmt.Printf("  ✓ Applied %s\n", migration.Filename)
+		}
+	}
+
+	if len(result.Migrations) > 0 || added > 0 {
+		if err := lock.Save(cwd); err != nil && !jsonOut {
+			fmt.Printf("  ⚠ Could not update %s: %v\n", migrations.LockFile, err)
+		}
+	}
+
+	if failure != nil {
+		// Fixing the file retries it on the next save
+		result.Message = fmt.Sprintf("applied %d of %d migrations; failed at %s", len(result.Migrations), len(drift.Pending), failure.Location())
+		reportAutoPush(state, result, failErr, jsonOut)
+		return
+	}
+	result.Status = "success"
+	result.Message = fmt.Sprintf("applied %d migration(s) to %s", len(result.Migrations), state.ProjectRef)
+	reportAutoPush(state, result, nil, jsonOut)
+}
+
+// autoPushFunctions deploys changed functions, carrying on past failures
+// since functions don't depend on each other
+func autoPushFunctions(ctx context.Context, state *WatchState, cwd string, deploys []FunctionDeploy, jsonOut bool) {
+	deployed := deployFunctions(ctx, state.Target, cwd, deploys, false, true, jsonOut)
+
+	result := events.AutoPush{Kind: string(watcher.Functions), Status: "success"}
+	var failed *FunctionDeploy
+	for i, d := range deploys {
+		if d.Status == DeployDeployed {
+			result.Functions = append(result.Functions, d.Slug)
+		} else if d.Status == DeployFailed && failed == nil {
+			failed = &deploys[i]
+		}
+	}
+
+	if failed != nil {
+		result.Status = "error"
+		result.Message = fmt.Sprintf("deployed %d of %d function(s); failed to deploy %s", deployed, len(deploys), failed.Slug)
+		result.Error = failed.Error
+		result.ErrorCode = failed.ErrorCode
+		reportAutoPush(state, result, nil, jsonOut)
+		return
+	}
+	result.Message = fmt.Sprintf("deployed %d function(s) to %s", deployed, state.ProjectRef)
+	reportAutoPush(state, result, nil, jsonOut)
+}
+
+// reportAutoPush emits the result of an automatic push: success, error or
+// refused. err, if set, fills in the error fields.
+func reportAutoPush(state *WatchState, result events.AutoPush, err error, jsonOut bool) {
+	if err != nil {
+		result.Error = err.Error()
+		result.ErrorCode = api.ErrorCode(err)
+	}
+	if jsonOut {
+		state.emit(result)
+		return
+	}
+
+	switch result.Status {
+	case "success":
+		fmt.Printf("🚀 Auto-push: %s\n", result.Message)
+	case "refused":
+		fmt.Printf("🛑 Auto-push refused: %s\n", result.Message)
+	default:
+		if result.Error != "" {
+			fmt.Printf("  ✗ Auto-push failed: %s: %s\n", result.Message, result.Error)
+		} else {
+			fmt.Printf("  ✗ Auto-push failed: %s\n", result.Message)
+		}
+	}
+}
+
+// watchEventError reports a failed action without stopping the watch
+func watchEventError(state *WatchState, kind watcher.Kind, message string, err error) {
+	if state.events != nil {
+		state.emit(events.PlanError{
+			Kind:      string(kind),
+			Message:   message,
+			Error:     err.Error(),
+			ErrorCode: api.ErrorCode(err),
+		})
+		return
+	}
+	fmt.Printf("  ⚠ %s: %v\n", message, err)
+}
+
+func checkBranchChange(ctx context.Context, cwd string, cfg *profiles.Config, state *WatchState, jsonOut bool) {
 	currentBranch, err := git.GetCurrentBranch(cwd)
 	if err != nil {
 		return
@@ -186,54 +570,90 @@ func checkBranchChange(cwd string, cfg *profiles.Config, state *WatchState, json
 		// Try to find a matching profile
 		if profile, name := cfg.GetProfileForBranch(currentBranch); profile != nil {
 			if name != state.Profile {
+				// Profiles can belong to different accounts and targets
+				if err := switchTarget(ctx, cwd, cfg, profile, state, jsonOut); err != nil {
+					if !jsonOut {
+						fmt.Printf("  ⚠ Could not switch to profile %s: %v\n", name, err)
+					}
+					return
+				}
 				state.Profile = name
-				state.ProjectRef = profile.GetProjectRef(cfg)
 
 				if jsonOut {
-					event := map[string]string{
-						"event":      "profile_changed",
-						"branch":     currentBranch,
-						"profile":    name,
-						"project_ref": state.ProjectRef,
-					}
-					out, _ := json.Marshal(event)
-					fmt.Println(string(out))
+					state.emit(events.ProfileChanged{Branch: currentBranch, Profile: name, ProjectRef: state.ProjectRef})
 				} else {
 					fmt.Printf("🔄 Branch changed to %s → switched to profile %s\n", currentBranch, name)
 				}
+				return
 			}
 		} else {
 			if jsonOut {
-				event := map[string]string{
-					"event":   "branch_changed",
-					"branch":  currentBranch,
-					"profile": state.Profile,
-				}
-				out, _ := json.Marshal(event)
-				fmt.Println(string(out))
+				state.emit(events.BranchChanged{Branch: currentBranch, Profile: state.Profile})
 			} else {
 				fmt.Printf("🌿 Branch changed to %s (keeping profile %s)\n", currentBranch, state.Profile)
 			}
 		}
+
+		// Preview profiles follow the git branch to its own Supabase branch
+		if profile, err := cfg.GetProfile(state.Profile); err == nil && profile.IsPreview() {
+			switchPreviewBranch(ctx, cwd, cfg, profile, state, jsonOut)
+		}
+	}
+}
+
+func switchPreviewBranch(ctx context.Context, cwd string, cfg *profiles.Config, profile *profiles.Profile, state *WatchState, jsonOut bool) {
+	before := state.ProjectRef
+	if err := switchTarget(ctx, cwd, cfg, profile, state, jsonOut); err != nil {
+		if jsonOut {
+			state.emit(events.PreviewBranchError{Branch: state.LastBranch, Error: err.Error(), ErrorCode: api.ErrorCode(err)})
+		} else {
+			fmt.Printf("  ⚠ Could not resolve preview branch: %v\n", err)
+		}
+		return
+	}
+
+	if state.ProjectRef == before {
+		return
+	}
+
+	if jsonOut {
+		state.emit(events.PreviewBranchChanged{Branch: state.LastBranch, Profile: state.Profile, ProjectRef: state.ProjectRef})
+	} else {
+		fmt.Printf("🌱 Now targeting preview branch %s\n", state.ProjectRef)
 	}
 }
 
-func regenerateTypes(client *api.Client, projectRef, cwd string, jsonOut bool) {
-	typesResp, err := client.GetTypescriptTypes(projectRef, "public")
+// switchTarget opens the backend for profile and makes it the watch target,
+// releasing the previous one. The current target is kept on error.
+func switchTarget(ctx context.Context, cwd string, cfg *profiles.Config, profile *profiles.Profile, state *WatchState, jsonOut bool) error {
+	target, closeTarget, err := openBackend(ctx, cfg, profile, cwd, true, jsonOut)
 	if err != nil {
+		return err
+	}
+
+	state.closeTarget()
+	state.Target = target
+	state.ProjectRef = target.Describe()
+	state.closeTarget = closeTarget
+	return nil
+}
+
+func regenerateTypes(ctx context.Context, state *WatchState, cwd string, jsonOut bool) {
+	state.LastTypesGen = time.Now()
+	typesResp, err := state.Target.GetTypescriptTypes(ctx, "public")
+	if err != nil {
+		if ctx.Err() != nil {
+			// Cancelled by Ctrl+C - not worth reporting
+			return
+		}
 		if jsonOut {
-			event := map[string]string{
-				"event": "types_error",
-				"error": err.Error(),
-			}
-			out, _ := json.Marshal(event)
-			fmt.Println(string(out))
+			state.emit(events.TypesError{Error: err.Error(), ErrorCode: api.ErrorCode(err)})
 		}
 		return
 	}
 
 	typesPath := filepath.Join(cwd, "supabase", "types", "database.ts")
-	
+
 	// Check if types changed
 	existingTypes, _ := os.ReadFile(typesPath)
 	if string(existingTypes) == typesResp.Types {
@@ -249,12 +669,7 @@ func regenerateTypes(client *api.Client, projectRef, cwd string, jsonOut bool) {
 	}
 
 	if jsonOut {
-		event := map[string]string{
-			"event": "types_updated",
-			"path":  typesPath,
-		}
-		out, _ := json.Marshal(event)
-		fmt.Println(string(out))
+		state.emit(events.TypesUpdated{Path: typesPath})
 	} else {
 		// Get relative path for cleaner output
 		relPath := strings.TrimPrefix(typesPath, cwd+"/")
@@ -262,18 +677,17 @@ func regenerateTypes(client *api.Client, projectRef, cwd string, jsonOut bool) {
 	}
 }
 
-func watchError(jsonOut bool, message string, err error) error {
+// watchError reports a failure to start watching. In JSON mode it's a fatal
+// watch_error event, the only one the watch writes.
+func watchError(jsonOut bool, eventsOut io.Writer, message string, err error) error {
 	if jsonOut {
-		result := WatchResult{
-			Status:  "error",
-			Message: message,
-		}
+		event := events.WatchError{Message: message, Fatal: true}
 		if err != nil {
-			result.Error = err.Error()
+			event.Error = err.Error()
+			event.ErrorCode = api.ErrorCode(err)
 		}
-		out, _ := json.MarshalIndent(result, "", "  ")
-		fmt.Println(string(out))
-		return nil
+		events.NewWriter(eventsOut).Emit(event)
+		return &ExitError{Code: 1, Err: fmt.Errorf("%s", message)}
 	}
 
 	if err != nil {
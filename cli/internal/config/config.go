@@ -2,14 +2,197 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 )
 
+// DefaultOAuthClientID is the OAuth app used by `supa login`. It can be set at
+// build time with -ldflags "-X .../config.DefaultOAuthClientID=..." or
+// overridden with SUPABASE_OAUTH_CLIENT_ID.
+var DefaultOAuthClientID = ""
+
+// DefaultAccountName is used when logging in without --account
+const DefaultAccountName = "default"
+
 // GlobalConfig stores global CLI configuration (auth tokens, etc.)
 type GlobalConfig struct {
+	// CurrentAccount is used when a profile doesn't name an account
+	CurrentAccount string              `json:"current_account,omitempty"`
+	Accounts       map[string]*Account `json:"accounts,omitempty"`
+
+	// CredentialStore selects where tokens live: file (default), keyring
+	// or encrypted. With non-file stores, Accounts only keeps metadata.
+	CredentialStore string `json:"credential_store,omitempty"`
+
+	// AccessToken is the pre-accounts single token. Load migrates it into
+	// the default account.
+	AccessToken string `json:"access_token,omitempty"`
+}
+
+// Account holds the credentials for one Supabase login. ClientID and
+// ExpiresAt are metadata and are kept in config.json for every store.
+type Account struct {
 	AccessToken string `json:"access_token,omitempty"`
+
+	// OAuth session - empty when logged in with a personal access token
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresAt    int64  `json:"expires_at,omitempty"` // unix seconds
+	ClientID     string `json:"client_id,omitempty"`
+}
+
+// IsOAuth reports whether the stored token came from the OAuth flow
+func (a *Account) IsOAuth() bool {
+	return a.RefreshToken != "" || a.ClientID != ""
+}
+
+// metadata returns a copy of the account without secrets
+func (a *Account) metadata() *Account {
+	return &Account{ExpiresAt: a.ExpiresAt, ClientID: a.ClientID}
+}
+
+// ResolveAccount returns name, or the current account if name is empty,
+// checking that the account exists
+func (c *GlobalConfig) ResolveAccount(name string) (string, error) {
+	if name == "" {
+		name = c.CurrentAccount
+	}
+	if name == "" {
+		return "", fmt.Errorf("not logged in. Run 'supa login' first or set SUPABASE_ACCESS_TOKEN")
+	}
+
+	if _, ok := c.Accounts[name]; !ok {
+		return name, fmt.Errorf("account %q is not logged in. Run 'supa login --account %s'", name, name)
+	}
+
+	return name, nil
+}
+
+// GetAccount loads the credentials of the named account (or the current
+// account if name is empty) from the credential store, along with the
+// resolved name
+func (c *GlobalConfig) GetAccount(name string) (*Account, string, error) {
+	name, err := c.ResolveAccount(name)
+	if err != nil {
+		return nil, name, err
+	}
+
+	store, err := OpenStore(c)
+	if err != nil {
+		return nil, name, err
+	}
+
+	account, err := store.Get(name)
+	if errors.Is(err, ErrCredentialsNotFound) {
+		return nil, name, fmt.Errorf("account %q has no stored credentials in the %s store. Run 'supa login --account %s'", name, store.Name(), name)
+	}
+	if err != nil {
+		return nil, name, err
+	}
+
+	return account, name, nil
+}
+
+// StoreAccount writes credentials to the credential store, records the
+// account and saves the config
+func (c *GlobalConfig) StoreAccount(name string, account *Account) error {
+	store, err := OpenStore(c)
+	if err != nil {
+		return err
+	}
+
+	if err := store.Set(name, account); err != nil {
+		return fmt.Errorf("failed to store credentials: %w", err)
+	}
+
+	if store.Name() == StoreFile {
+		c.SetAccount(name, account)
+	} else {
+		c.SetAccount(name, account.metadata())
+	}
+
+	return Save(c)
+}
+
+// ForgetAccount deletes credentials from the credential store, removes the
+// account and saves the config
+func (c *GlobalConfig) ForgetAccount(name string) error {
+	store, err := OpenStore(c)
+	if err != nil {
+		return err
+	}
+
+	if err := store.Delete(name); err != nil && !errors.Is(err, ErrCredentialsNotFound) {
+		return fmt.Errorf("failed to delete credentials: %w", err)
+	}
+
+	if err := c.RemoveAccount(name); err != nil {
+		return err
+	}
+
+	return Save(c)
+}
+
+// SetAccount stores credentials under name. The first account becomes current.
+func (c *GlobalConfig) SetAccount(name string, account *Account) {
+	if c.Accounts == nil {
+		c.Accounts = make(map[string]*Account)
+	}
+	c.Accounts[name] = account
+	if c.CurrentAccount == "" {
+		c.CurrentAccount = name
+	}
+}
+
+// RemoveAccount deletes an account. If it was current, another remaining
+// account (alphabetically first) becomes current.
+func (c *GlobalConfig) RemoveAccount(name string) error {
+	if _, ok := c.Accounts[name]; !ok {
+		return fmt.Errorf("account %q not found", name)
+	}
+	delete(c.Accounts, name)
+
+	if c.CurrentAccount == name {
+		c.CurrentAccount = ""
+		names := c.AccountNames()
+		if len(names) > 0 {
+			c.CurrentAccount = names[0]
+		}
+	}
+	return nil
+}
+
+// AccountNames returns all account names, sorted
+func (c *GlobalConfig) AccountNames() []string {
+	names := make([]string, 0, len(c.Accounts))
+	for name := range c.Accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// migrate moves a legacy single token into the default account
+func (c *GlobalConfig) migrate() {
+	if c.AccessToken == "" {
+		return
+	}
+	if _, exists := c.Accounts[DefaultAccountName]; !exists {
+		c.SetAccount(DefaultAccountName, &Account{AccessToken: c.AccessToken})
+	}
+	c.AccessToken = ""
+}
+
+// GetOAuthClient returns the OAuth client ID and secret from the environment,
+// falling back to the built-in client ID
+func GetOAuthClient() (clientID, clientSecret string) {
+	clientID = os.Getenv("SUPABASE_OAUTH_CLIENT_ID")
+	if clientID == "" {
+		clientID = DefaultOAuthClientID
+	}
+	return clientID, os.Getenv("SUPABASE_OAUTH_CLIENT_SECRET")
 }
 
 // GetConfigDir returns the path to the CLI config directory
@@ -49,6 +232,7 @@ func Load() (*GlobalConfig, error) {
 	if err := json.Unmarshal(data, &cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
+	cfg.migrate()
 
 	return &cfg, nil
 }
@@ -81,8 +265,9 @@ func Save(cfg *GlobalConfig) error {
 	return nil
 }
 
-// GetAccessToken returns the stored access token
-func GetAccessToken() (string, error) {
+// GetAccessToken returns the access token for the named account, or the
+// current account if name is empty
+func GetAccessToken(account string) (string, error) {
 	// First check environment variable
 	if token := os.Getenv("SUPABASE_ACCESS_TOKEN"); token != "" {
 		return token, nil
@@ -93,9 +278,10 @@ func GetAccessToken() (string, error) {
 		return "", err
 	}
 
-	if cfg.AccessToken == "" {
-		return "", fmt.Errorf("not logged in. Run 'supa login' first or set SUPABASE_ACCESS_TOKEN")
+	acct, _, err := cfg.GetAccount(account)
+	if err != nil {
+		return "", err
 	}
 
-	return cfg.AccessToken, nil
+	return acct.AccessToken, nil
 }
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadMigratesLegacyToken(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	dir := filepath.Join(home, ".supabase-dx")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatalf("failed to create config dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"access_token": "legacy"}`), 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if cfg.AccessToken != "" {
+		t.Error("expected legacy token to be cleared")
+	}
+
+	if cfg.CurrentAccount != DefaultAccountName {
+		t.Errorf("expected current account %q, got %q", DefaultAccountName, cfg.CurrentAccount)
+	}
+
+	acct, _, err := cfg.GetAccount("")
+	if err != nil {
+		t.Fatalf("expected default account, got %v", err)
+	}
+	if acct.AccessToken != "legacy" {
+		t.Errorf("expected migrated token, got %q", acct.AccessToken)
+	}
+}
+
+func TestAccounts(t *testing.T) {
+	cfg := &GlobalConfig{}
+
+	if _, _, err := cfg.GetAccount(""); err == nil {
+		t.Error("expected error when not logged in")
+	}
+
+	cfg.SetAccount("personal", &Account{AccessToken: "p"})
+	cfg.SetAccount("work", &Account{AccessToken: "w", RefreshToken: "r"})
+
+	if cfg.CurrentAccount != "personal" {
+		t.Errorf("expected first account to become current, got %q", cfg.CurrentAccount)
+	}
+
+	acct, name, err := cfg.GetAccount("work")
+	if err != nil || name != "work" || acct.AccessToken != "w" {
+		t.Errorf("unexpected work account: %+v %q %v", acct, name, err)
+	}
+	if !acct.IsOAuth() {
+		t.Error("expected work account to be OAuth")
+	}
+
+	if _, _, err := cfg.GetAccount("missing"); err == nil {
+		t.Error("expected error for unknown account")
+	}
+
+	if err := cfg.RemoveAccount("personal"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg.CurrentAccount != "work" {
+		t.Errorf("expected current to fall back to work, got %q", cfg.CurrentAccount)
+	}
+}
+
+func TestGetAccessTokenPrefersEnv(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("SUPABASE_ACCESS_TOKEN", "from-env")
+
+	token, err := GetAccessToken("work")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if token != "from-env" {
+		t.Errorf("expected env token, got %q", token)
+	}
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Credential store backends
+const (
+	StoreFile      = "file"
+	StoreKeyring   = "keyring"
+	StoreEncrypted = "encrypted"
+)
+
+// ErrCredentialsNotFound is returned when a store has no entry for an account
+var ErrCredentialsNotFound = errors.New("credentials not found")
+
+// CredentialStore persists account credentials
+type CredentialStore interface {
+	// Name returns the backend name (file, keyring, encrypted)
+	Name() string
+	Get(account string) (*Account, error)
+	Set(account string, creds *Account) error
+	Delete(account string) error
+}
+
+// StoreNames lists the available backends
+func StoreNames() []string {
+	return []string{StoreFile, StoreKeyring, StoreEncrypted}
+}
+
+// OpenStore returns the backend selected in cfg
+func OpenStore(cfg *GlobalConfig) (CredentialStore, error) {
+	return NewStore(cfg.CredentialStore, cfg)
+}
+
+// NewStore returns the named backend. The file store reads and writes
+// cfg.Accounts directly; callers are responsible for saving cfg.
+func NewStore(name string, cfg *GlobalConfig) (CredentialStore, error) {
+	switch name {
+	case "", StoreFile:
+		return &fileStore{cfg: cfg}, nil
+	case StoreKeyring:
+		return &keyringStore{service: keyringService}, nil
+	case StoreEncrypted:
+		path, err := GetEncryptedStorePath()
+		if err != nil {
+			return nil, err
+		}
+		return &encryptedStore{path: path}, nil
+	default:
+		return nil, fmt.Errorf("unknown credential store %q (expected one of: file, keyring, encrypted)", name)
+	}
+}
+
+// =============================================================================
+// Plaintext File Store
+// =============================================================================
+
+// fileStore keeps tokens in config.json, protected only by file mode 0600
+type fileStore struct {
+	cfg *GlobalConfig
+}
+
+func (s *fileStore) Name() string { return StoreFile }
+
+func (s *fileStore) Get(account string) (*Account, error) {
+	acct, ok := s.cfg.Accounts[account]
+	if !ok || acct.AccessToken == "" {
+		return nil, ErrCredentialsNotFound
+	}
+	return acct, nil
+}
+
+func (s *fileStore) Set(account string, creds *Account) error {
+	s.cfg.SetAccount(account, creds)
+	return nil
+}
+
+func (s *fileStore) Delete(account string) error {
+	acct, ok := s.cfg.Accounts[account]
+	if !ok {
+		return ErrCredentialsNotFound
+	}
+	// Keep the record (and its metadata) - only drop the secrets
+	s.cfg.Accounts[account] = acct.metadata()
+	return nil
+}
+
+// =============================================================================
+// Migration
+// =============================================================================
+
+// MigrateStore moves every account's credentials into the target backend and
+// switches the config to it. Credentials are copied and verified before the
+// config is switched, so a failure part-way leaves the old store intact.
+func MigrateStore(cfg *GlobalConfig, target string) (int, error) {
+	from, err := OpenStore(cfg)
+	if err != nil {
+		return 0, err
+	}
+	to, err := NewStore(target, cfg)
+	if err != nil {
+		return 0, err
+	}
+	if from.Name() == to.Name() {
+		return 0, fmt.Errorf("credentials are already in the %s store", to.Name())
+	}
+
+	creds := make(map[string]*Account)
+	for _, name := range cfg.AccountNames() {
+		acct, err := from.Get(name)
+		if errors.Is(err, ErrCredentialsNotFound) {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read %q from the %s store: %w", name, from.Name(), err)
+		}
+		// Copy so the file store's in-place updates don't alias
+		c := *acct
+		creds[name] = &c
+	}
+
+	for name, acct := range creds {
+		if err := to.Set(name, acct); err != nil {
+			return 0, fmt.Errorf("failed to write %q to the %s store: %w", name, to.Name(), err)
+		}
+		got, err := to.Get(name)
+		if err != nil || got.AccessToken != acct.AccessToken {
+			return 0, fmt.Errorf("failed to verify %q in the %s store: %v", name, to.Name(), err)
+		}
+	}
+
+	// Switch over. For the file store, saving the stripped config is what
+	// removes the plaintext tokens.
+	cfg.CredentialStore = to.Name()
+	for name, acct := range creds {
+		if to.Name() == StoreFile {
+			cfg.Accounts[name] = acct
+		} else {
+			cfg.Accounts[name] = acct.metadata()
+		}
+	}
+
+	if err := Save(cfg); err != nil {
+		return 0, err
+	}
+
+	if from.Name() != StoreFile {
+		for name := range creds {
+			if err := from.Delete(name); err != nil && !errors.Is(err, ErrCredentialsNotFound) {
+				return len(creds), fmt.Errorf("migrated, but failed to remove %q from the %s store: %w", name, from.Name(), err)
+			}
+		}
+	}
+
+	return len(creds), nil
+}
@@ -0,0 +1,235 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+)
+
+// scrypt parameters (N=2^15, r=8, p=1), the interactive-login recommendation
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+)
+
+// PassphraseFunc returns the passphrase for the encrypted store. By default
+// it reads SUPABASE_DX_PASSPHRASE, then prompts on the terminal.
+var PassphraseFunc = defaultPassphrase
+
+var (
+	passphraseOnce sync.Once
+	passphrase     string
+	passphraseErr  error
+)
+
+// GetEncryptedStorePath returns the path of the encrypted credentials file
+func GetEncryptedStorePath() (string, error) {
+	dir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "credentials.enc"), nil
+}
+
+// encryptedStore keeps all credentials in a single AES-256-GCM encrypted
+// file, with the key derived from a passphrase via scrypt. Meant for
+// headless machines without a keyring daemon.
+type encryptedStore struct {
+	path string
+}
+
+// encryptedFile is the on-disk envelope
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func (s *encryptedStore) Name() string { return StoreEncrypted }
+
+func (s *encryptedStore) Get(account string) (*Account, error) {
+	accounts, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	acct, ok := accounts[account]
+	if !ok {
+		return nil, ErrCredentialsNotFound
+	}
+	return acct, nil
+}
+
+func (s *encryptedStore) Set(account string, creds *Account) error {
+	accounts, err := s.load()
+	if err != nil {
+		return err
+	}
+	accounts[account] = creds
+	return s.save(accounts)
+}
+
+func (s *encryptedStore) Delete(account string) error {
+	accounts, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := accounts[account]; !ok {
+		return ErrCredentialsNotFound
+	}
+	delete(accounts, account)
+	return s.save(accounts)
+}
+
+func (s *encryptedStore) load() (map[string]*Account, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return make(map[string]*Account), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+
+	var file encryptedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials file: %w", err)
+	}
+	if file.Version != 1 || file.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported credentials file (version %d, kdf %q)", file.Version, file.KDF)
+	}
+
+	pass, err := getPassphrase()
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := decrypt(pass, file.Salt, file.Nonce, file.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make(map[string]*Account)
+	if err := json.Unmarshal(plaintext, &accounts); err != nil {
+		return nil, fmt.Errorf("failed to parse decrypted credentials: %w", err)
+	}
+	return accounts, nil
+}
+
+func (s *encryptedStore) save(accounts map[string]*Account) error {
+	plaintext, err := json.Marshal(accounts)
+	if err != nil {
+		return fmt.Errorf("failed to serialize credentials: %w", err)
+	}
+
+	pass, err := getPassphrase()
+	if err != nil {
+		return err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	nonce, ciphertext, err := encrypt(pass, salt, plaintext)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(encryptedFile{
+		Version:    1,
+		KDF:        "scrypt",
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: ciphertext,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize credentials file: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(s.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write credentials file: %w", err)
+	}
+	return nil
+}
+
+func newGCM(pass string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(pass), salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encrypt(pass string, salt, plaintext []byte) (nonce, ciphertext []byte, err error) {
+	gcm, err := newGCM(pass, salt)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return nonce, gcm.Seal(nil, nonce, plaintext, nil), nil
+}
+
+func decrypt(pass string, salt, nonce, ciphertext []byte) ([]byte, error) {
+	gcm, err := newGCM(pass, salt)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("failed to decrypt credentials: wrong passphrase or corrupted file")
+	}
+	return plaintext, nil
+}
+
+// getPassphrase asks PassphraseFunc once per process
+func getPassphrase() (string, error) {
+	passphraseOnce.Do(func() {
+		passphrase, passphraseErr = PassphraseFunc()
+		if passphraseErr == nil && passphrase == "" {
+			passphraseErr = errors.New("passphrase cannot be empty")
+		}
+	})
+	return passphrase, passphraseErr
+}
+
+func defaultPassphrase() (string, error) {
+	if pass := os.Getenv("SUPABASE_DX_PASSPHRASE"); pass != "" {
+		return pass, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", errors.New("the encrypted credential store needs a passphrase: set SUPABASE_DX_PASSPHRASE")
+	}
+
+	fmt.Fprint(os.Stderr, "Credentials passphrase: ")
+	pass, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return string(pass), nil
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/zalando/go-keyring"
+)
+
+// keyringService is the service name entries are stored under
+const keyringService = "supabase-dx"
+
+// keyringStore keeps credentials in the OS keyring: the Secret Service API
+// over D-Bus on Linux (GNOME Keyring, KWallet), Keychain on macOS and the
+// Credential Manager on Windows
+type keyringStore struct {
+	service string
+}
+
+func (s *keyringStore) Name() string { return StoreKeyring }
+
+func (s *keyringStore) Get(account string) (*Account, error) {
+	data, err := keyring.Get(s.service, account)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil, ErrCredentialsNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from keyring: %w", err)
+	}
+
+	var acct Account
+	if err := json.Unmarshal([]byte(data), &acct); err != nil {
+		return nil, fmt.Errorf("failed to parse keyring entry: %w", err)
+	}
+	return &acct, nil
+}
+
+func (s *keyringStore) Set(account string, creds *Account) error {
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return fmt.Errorf("failed to serialize credentials: %w", err)
+	}
+	if err := keyring.Set(s.service, account, string(data)); err != nil {
+		return fmt.Errorf("failed to write to keyring: %w", err)
+	}
+	return nil
+}
+
+func (s *keyringStore) Delete(account string) error {
+	err := keyring.Delete(s.service, account)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return ErrCredentialsNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete from keyring: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// usePassphrase swaps in a fixed passphrase for the encrypted store
+func usePassphrase(t *testing.T, pass string) {
+	t.Helper()
+	orig := PassphraseFunc
+	PassphraseFunc = func() (string, error) { return pass, nil }
+	passphraseOnce = sync.Once{}
+	t.Cleanup(func() {
+		PassphraseFunc = orig
+		passphraseOnce = sync.Once{}
+	})
+}
+
+func TestNewStoreUnknown(t *testing.T) {
+	if _, err := NewStore("vault", &GlobalConfig{}); err == nil {
+		t.Error("expected error for unknown store")
+	}
+}
+
+func TestFileStoreDeleteKeepsMetadata(t *testing.T) {
+	cfg := &GlobalConfig{}
+	store, _ := NewStore(StoreFile, cfg)
+
+	if err := store.Set("work", &Account{AccessToken: "a", RefreshToken: "r", ClientID: "c"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := store.Delete("work"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if _, err := store.Get("work"); !errors.Is(err, ErrCredentialsNotFound) {
+		t.Errorf("expected ErrCredentialsNotFound, got %v", err)
+	}
+	if acct := cfg.Accounts["work"]; acct == nil || acct.ClientID != "c" || acct.RefreshToken != "" {
+		t.Errorf("expected metadata without secrets, got %+v", acct)
+	}
+}
+
+func TestEncryptedStore(t *testing.T) {
+	path := t.TempDir() + "/credentials.enc"
+	usePassphrase(t, "hunter2")
+
+	store := &encryptedStore{path: path}
+	if _, err := store.Get("work"); !errors.Is(err, ErrCredentialsNotFound) {
+		t.Errorf("expected ErrCredentialsNotFound, got %v", err)
+	}
+
+	if err := store.Set("work", &Account{AccessToken: "secret-token"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read store: %v", err)
+	}
+	if strings.Contains(string(data), "secret-token") {
+		t.Error("expected token to be encrypted at rest")
+	}
+
+	acct, err := store.Get("work")
+	if err != nil || acct.AccessToken != "secret-token" {
+		t.Errorf("unexpected account: %+v %v", acct, err)
+	}
+
+	usePassphrase(t, "wrong")
+	if _, err := store.Get("work"); err == nil {
+		t.Error("expected error with wrong passphrase")
+	}
+}
+
+func TestMigrateStore(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	usePassphrase(t, "hunter2")
+
+	cfg := &GlobalConfig{}
+	if err := cfg.StoreAccount("work", &Account{AccessToken: "w", RefreshToken: "r", ClientID: "c"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	n, err := MigrateStore(cfg, StoreEncrypted)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 migrated account, got %d", n)
+	}
+
+	loaded, err := Load()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if loaded.CredentialStore != StoreEncrypted {
+		t.Errorf("expected encrypted store, got %q", loaded.CredentialStore)
+	}
+	if meta := loaded.Accounts["work"]; meta.AccessToken != "" || !meta.IsOAuth() {
+		t.Errorf("expected only metadata in config.json, got %+v", meta)
+	}
+
+	acct, _, err := loaded.GetAccount("work")
+	if err != nil || acct.AccessToken != "w" || acct.RefreshToken != "r" {
+		t.Errorf("unexpected account after migration: %+v %v", acct, err)
+	}
+
+	if _, err := MigrateStore(loaded, StoreEncrypted); err == nil {
+		t.Error("expected error migrating to the current store")
+	}
+}
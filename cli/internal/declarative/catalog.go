@@ -0,0 +1,298 @@
+package declarative
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Catalog is the introspected definition of the managed schemas. Names are
+// quoted and schema-qualified as Postgres renders them (quote_ident), and
+// definitions come from pg_get_*def, so two catalogs read from servers of
+// the same major version compare textually.
+type Catalog struct {
+	Schemas   []string
+	Enums     []Enum
+	Tables    []Table
+	Views     []View     // in creation order, which respects dependencies
+	Functions []Function // in creation order
+}
+
+// Enum is an enum type and its labels, as quoted literals in sort order
+type Enum struct {
+	Name   string   `json:"name"`
+	Values []string `json:"enum_values"`
+}
+
+// Table is a table with everything defined on it
+type Table struct {
+	Name        string `json:"name"`
+	RLS         bool   `json:"rls"`
+	Columns     []Column
+	Constraints []Constraint
+	Indexes     []Index
+	Triggers    []Trigger
+	Policies    []Policy
+}
+
+// Column is a table column. Default holds the generation expression of
+// generated columns.
+type Column struct {
+	Table     string `json:"table_name"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	NotNull   bool   `json:"not_null"`
+	Default   string `json:"default_expr"`
+	Identity  string `json:"identity"`  // "a" (always), "d" (by default) or ""
+	Generated string `json:"generated"` // "s" (stored) or ""
+	Serial    bool   `json:"serial"`    // default is nextval() of a sequence the column owns
+}
+
+// Constraint is a primary key, unique, exclusion, check or foreign key
+// constraint. Not-null is a column property.
+type Constraint struct {
+	Table      string `json:"table_name"`
+	Name       string `json:"name"`
+	Type       string `json:"type"` // p, u, x, c or f
+	Definition string `json:"definition"`
+}
+
+// Index is an index not backing a constraint
+type Index struct {
+	Table      string `json:"table_name"`
+	Name       string `json:"name"`
+	Definition string `json:"definition"`
+}
+
+// Trigger is a user-defined trigger on a table
+type Trigger struct {
+	Table      string `json:"table_name"`
+	Name       string `json:"name"`
+	Definition string `json:"definition"`
+}
+
+// Policy is a row level security policy
+type Policy struct {
+	Table      string   `json:"table_name"`
+	Name       string   `json:"name"`
+	Permissive bool     `json:"permissive"`
+	Command    string   `json:"command"` // r, a, w, d or *
+	Roles      []string `json:"roles"`   // empty for public
+	Using      string   `json:"using_expr"`
+	Check      string   `json:"check_expr"`
+}
+
+// View is a view or materialized view
+type View struct {
+	Name         string   `json:"name"`
+	Materialized bool     `json:"materialized"`
+	Options      []string `json:"options"`
+	Definition   string   `json:"definition"`
+}
+
+// Function is a function or procedure. Name includes the identity
+// arguments, e.g. public.add(a integer, b integer).
+type Function struct {
+	Name       string `json:"name"`
+	Definition string `json:"definition"`
+}
+
+// Querier runs a read-only query and returns its rows as a JSON array of
+// objects. Both the Management API and a direct connection provide it.
+type Querier interface {
+	RunQuery(ctx context.Context, query string) (json.RawMessage, error)
+}
+
+// QueryFunc adapts a function to Querier, e.g. a backend's
+// RunReadOnlyQuery
+type QueryFunc func(ctx context.Context, query string) (json.RawMessage, error)
+
+func (f QueryFunc) RunQuery(ctx context.Context, query string) (json.RawMessage, error) {
+	return f(ctx, query)
+}
+
+// notExtension excludes objects that belong to an extension
+func notExtension(catalog, oid string) string {
+	return fmt.Sprintf("not exists (select 1 from pg_depend e where e.classid = '%s'::regclass and e.objid = %s and e.deptype = 'e')", catalog, oid)
+}
+
+const qualifiedTable = "quote_ident(n.nspname) || '.' || quote_ident(c.relname)"
+
+// tableFilter selects the managed tables as c, in namespace n
+const tableFilter = `join pg_namespace n on n.oid = c.relnamespace
+where n.nspname = any(%[1]s) and c.relkind in ('r', 'p') and not c.relispartition`
+
+var (
+	schemasQuery = `select quote_ident(nspname) as name from pg_namespace where nspname = any(%[1]s) order by nspname`
+
+	enumsQuery = `select quote_ident(n.nspname) || '.' || quote_ident(t.typname) as name,
+	to_json(array_agg(quote_literal(e.enumlabel) order by e.enumsortorder)) as enum_values
+from pg_type t
+join pg_namespace n on n.oid = t.typnamespace
+join pg_enum e on e.enumtypid = t.oid
+where n.nspname = any(%[1]s) and ` + notExtension("pg_type", "t.oid") + `
+group by n.nspname, t.typname
+order by n.nspname, t.typname`
+
+	tablesQuery = `select ` + qualifiedTable + ` as name, c.relrowsecurity as rls
+from pg_class c
+` + tableFilter + ` and ` + notExtension("pg_class", "c.oid") + `
+order by n.nspname, c.relname`
+
+	columnsQuery = `select ` + qualifiedTable + ` as table_name, quote_ident(a.attname) as name,
+	format_type(a.atttypid, a.atttypmod) as type, a.attnotnull as not_null,
+	coalesce(pg_get_expr(d.adbin, d.adrelid), '') as default_expr,
+	a.attidentity::text as identity, a.attgenerated::text as generated,
+	a.attidentity = '' and coalesce(pg_get_serial_sequence(` + qualifiedTable + `, a.attname) is not null, false) as serial
+from pg_attribute a
+join pg_class c on c.oid = a.attrelid
+left join pg_attrdef d on d.adrelid = a.attrelid and d.adnum = a.attnum
+` + tableFilter + ` and a.attnum > 0 and not a.attisdropped
+order by n.nspname, c.relname, a.attnum`
+
+	constraintsQuery = `select ` + qualifiedTable + ` as table_name, quote_ident(con.conname) as name,
+	con.contype::text as type, pg_get_constraintdef(con.oid) as definition
+from pg_constraint con
+join pg_class c on c.oid = con.conrelid
+` + tableFilter + ` and con.contype in ('p', 'u', 'x', 'c', 'f')
+order by n.nspname, c.relname, con.conname`
+
+	indexesQuery = `select ` + qualifiedTable + ` as table_name,
+	quote_ident(n.nspname) || '.' || quote_ident(i.relname) as name, pg_get_indexdef(x.indexrelid) as definition
+from pg_index x
+join pg_class i on i.oid = x.indexrelid
+join pg_class c on c.oid = x.indrelid
+` + tableFilter + `
+	and not exists (select 1 from pg_constraint con where con.conindid = x.indexrelid and con.conrelid = x.indrelid and con.contype in ('p', 'u', 'x'))
+order by n.nspname, c.relname, i.relname`
+
+	triggersQuery = `select ` + qualifiedTable + ` as table_name, quote_ident(t.tgname) as name,
+	pg_get_triggerdef(t.oid) as definition
+from pg_trigger t
+join pg_class c on c.oid = t.tgrelid
+` + tableFilter + ` and not t.tgisinternal
+order by n.nspname, c.relname, t.tgname`
+
+	policiesQuery = `select ` + qualifiedTable + ` as table_name, quote_ident(p.polname) as name,
+	p.polpermissive as permissive, p.polcmd::text as command,
+	to_json(coalesce((select array_agg(quote_ident(r.rolname) order by r.rolname) from pg_roles r where r.oid = any(p.polroles)), '{}'::text[])) as roles,
+	coalesce(pg_get_expr(p.polqual, p.polrelid), '') as using_expr,
+	coalesce(pg_get_expr(p.polwithcheck, p.polrelid), '') as check_expr
+from pg_policy p
+join pg_class c on c.oid = p.polrelid
+` + tableFilter + `
+order by n.nspname, c.relname, p.polname`
+
+	viewsQuery = `select ` + qualifiedTable + ` as name, c.relkind = 'm' as materialized,
+	to_json(coalesce(c.reloptions, '{}'::text[])) as options, pg_get_viewdef(c.oid) as definition
+from pg_class c
+join pg_namespace n on n.oid = c.relnamespace
+where n.nspname = any(%[1]s) and c.relkind in ('v', 'm') and ` + notExtension("pg_class", "c.oid") + `
+order by c.oid`
+
+	functionsQuery = `select quote_ident(n.nspname) || '.' || quote_ident(p.proname) || '(' || pg_get_function_identity_arguments(p.oid) || ')' as name,
+	pg_get_functiondef(p.oid) as definition
+from pg_proc p
+join pg_namespace n on n.oid = p.pronamespace
+where n.nspname = any(%[1]s) and p.prokind in ('f', 'p') and ` + notExtension("pg_proc", "p.oid") + `
+order by p.oid`
+)
+
+// Introspect reads the definition of the given schemas
+func Introspect(ctx context.Context, db Querier, schemas []string) (*Catalog, error) {
+	literals := make([]string, len(schemas))
+	for i, s := range schemas {
+		literals[i] = "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	}
+	array := "array[" + strings.Join(literals, ", ") + "]::text[]"
+
+	query := func(sql string, rows any) error {
+		out, err := db.RunQuery(ctx, fmt.Sprintf(sql, array))
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(out, rows)
+	}
+
+	catalog := &Catalog{}
+
+	var names []struct {
+		Name string `json:"name"`
+	}
+	if err := query(schemasQuery, &names); err != nil {
+		return nil, fmt.Errorf("failed to read schemas: %w", err)
+	}
+	for _, n := range names {
+		catalog.Schemas = append(catalog.Schemas, n.Name)
+	}
+
+	if err := query(enumsQuery, &catalog.Enums); err != nil {
+		return nil, fmt.Errorf("failed to read enums: %w", err)
+	}
+	if err := query(tablesQuery, &catalog.Tables); err != nil {
+		return nil, fmt.Errorf("failed to read tables: %w", err)
+	}
+
+	var (
+		columns     []Column
+		constraints []Constraint
+		indexes     []Index
+		triggers    []Trigger
+		policies    []Policy
+	)
+	if err := query(columnsQuery, &columns); err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+	if err := query(constraintsQuery, &constraints); err != nil {
+		return nil, fmt.Errorf("failed to read constraints: %w", err)
+	}
+	if err := query(indexesQuery, &indexes); err != nil {
+		return nil, fmt.Errorf("failed to read indexes: %w", err)
+	}
+	if err := query(triggersQuery, &triggers); err != nil {
+		return nil, fmt.Errorf("failed to read triggers: %w", err)
+	}
+	if err := query(policiesQuery, &policies); err != nil {
+		return nil, fmt.Errorf("failed to read policies: %w", err)
+	}
+
+	tables := make(map[string]*Table)
+	for i := range catalog.Tables {
+		tables[catalog.Tables[i].Name] = &catalog.Tables[i]
+	}
+	for _, c := range columns {
+		if t := tables[c.Table]; t != nil {
+			t.Columns = append(t.Columns, c)
+		}
+	}
+	for _, c := range constraints {
+		if t := tables[c.Table]; t != nil {
+			t.Constraints = append(t.Constraints, c)
+		}
+	}
+	for _, x := range indexes {
+		if t := tables[x.Table]; t != nil {
+			t.Indexes = append(t.Indexes, x)
+		}
+	}
+	for _, tg := range triggers {
+		if t := tables[tg.Table]; t != nil {
+			t.Triggers = append(t.Triggers, tg)
+		}
+	}
+	for _, p := range policies {
+		if t := tables[p.Table]; t != nil {
+			t.Policies = append(t.Policies, p)
+		}
+	}
+
+	if err := query(viewsQuery, &catalog.Views); err != nil {
+		return nil, fmt.Errorf("failed to read views: %w", err)
+	}
+	if err := query(functionsQuery, &catalog.Functions); err != nil {
+		return nil, fmt.Errorf("failed to read functions: %w", err)
+	}
+
+	return catalog, nil
+}
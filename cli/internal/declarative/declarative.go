@@ -0,0 +1,105 @@
+package declarative
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+// Dir is the declarative schema directory, relative to the project root
+var Dir = filepath.Join("supabase", "schemas")
+
+// Source is a schema file and its SQL
+type Source struct {
+	Path string // relative to the project root
+	SQL  string
+}
+
+// Load reads the .sql files under <root>/supabase/schemas, including
+// subdirectories, in lexical order of their path - the order they're
+// applied in. A missing directory has no files.
+func Load(root string) ([]Source, error) {
+	dir := filepath.Join(root, Dir)
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(d.Name(), ".sql") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(paths, func(i, j int) bool {
+		return filepath.ToSlash(paths[i]) < filepath.ToSlash(paths[j])
+	})
+
+	sources := make([]Source, 0, len(paths))
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		rel, _ := filepath.Rel(root, path)
+		sources = append(sources, Source{Path: rel, SQL: string(content)})
+	}
+	return sources, nil
+}
+
+// transactionKeywords names each kind of transaction control statement
+var transactionKeywords = map[pg_query.TransactionStmtKind]string{
+	pg_query.TransactionStmtKind_TRANS_STMT_BEGIN:             "begin",
+	pg_query.TransactionStmtKind_TRANS_STMT_START:             "start transaction",
+	pg_query.TransactionStmtKind_TRANS_STMT_COMMIT:            "commit",
+	pg_query.TransactionStmtKind_TRANS_STMT_ROLLBACK:          "rollback",
+	pg_query.TransactionStmtKind_TRANS_STMT_SAVEPOINT:         "savepoint",
+	pg_query.TransactionStmtKind_TRANS_STMT_RELEASE:           "release savepoint",
+	pg_query.TransactionStmtKind_TRANS_STMT_ROLLBACK_TO:       "rollback to savepoint",
+	pg_query.TransactionStmtKind_TRANS_STMT_PREPARE:           "prepare transaction",
+	pg_query.TransactionStmtKind_TRANS_STMT_COMMIT_PREPARED:   "commit prepared",
+	pg_query.TransactionStmtKind_TRANS_STMT_ROLLBACK_PREPARED: "rollback prepared",
+}
+
+// CheckTransactions returns an error for the first schema file that begins,
+// commits or rolls back a transaction: the files are applied together, as
+// one script. Files that don't parse are left for the database to report.
+func CheckTransactions(sources []Source) error {
+	for _, source := range sources {
+		tree, err := pg_query.Parse(source.SQL)
+		if err != nil {
+			continue
+		}
+		for _, raw := range tree.GetStmts() {
+			stmt := raw.GetStmt().GetTransactionStmt()
+			if stmt == nil {
+				continue
+			}
+			// The statement's location includes the whitespace and comments
+			// before it; report the line of its first token
+			start := int(raw.StmtLocation)
+			if scan, err := pg_query.Scan(source.SQL[start:]); err == nil {
+				for _, token := range scan.Tokens {
+					if token.Token != pg_query.Token_SQL_COMMENT && token.Token != pg_query.Token_C_COMMENT {
+						start += int(token.Start)
+						break
+					}
+				}
+			}
+			line := strings.Count(source.SQL[:start], "\n") + 1
+			return fmt.Errorf("%s:%d: schema files can't control transactions (%s)", source.Path, line, transactionKeywords[stmt.Kind])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package declarative
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	root := t.TempDir()
+
+	if sources, err := Load(root); err != nil || len(sources) != 0 {
+		t.Fatalf("expected no files for a missing directory, got %v %v", sources, err)
+	}
+
+	files := map[string]string{
+		"b_tables.sql":          "create table b ();",
+		"a_types.sql":           "create type a as enum ('x');",
+		"nested/c_policies.sql": "-- policies",
+		"notes.md":              "not sql",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, Dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+
+	sources, err := Load(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var paths []string
+	for _, s := range sources {
+		paths = append(paths, filepath.ToSlash(s.Path))
+	}
+	expected := "supabase/schemas/a_types.sql,supabase/schemas/b_tables.sql,supabase/schemas/nested/c_policies.sql"
+	if strings.Join(paths, ",") != expected {
+		t.Errorf("expected %s, got %s", expected, strings.Join(paths, ","))
+	}
+	if sources[0].SQL != files["a_types.sql"] {
+		t.Errorf("expected file content, got %q", sources[0].SQL)
+	}
+}
+
+func TestCheckTransactions(t *testing.T) {
+	sources := []Source{
+		{Path: "supabase/schemas/a.sql", SQL: "create table a ();"},
+		{Path: "supabase/schemas/b.sql", SQL: "create table b ();\n\nBEGIN;\ncreate table c ();\ncommit;"},
+	}
+	err := CheckTransactions(sources)
+	if err == nil || err.Error() != "supabase/schemas/b.sql:3: schema files can't control transactions (begin)" {
+		t.Errorf("expected the begin to be refused, got %v", err)
+	}
+
+	if err := CheckTransactions(sources[:1]); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	// Comments before the statement don't hide it
+	commented := []Source{{Path: "supabase/schemas/c.sql", SQL: "create table c ();\n-- done\n/* really */ COMMIT;"}}
+	err = CheckTransactions(commented)
+	if err == nil || err.Error() != "supabase/schemas/c.sql:3: schema files can't control transactions (commit)" {
+		t.Errorf("expected the commit to be refused, got %v", err)
+	}
+}
+
+// cannedQuerier answers catalog queries by the first matching fragment
+type cannedQuerier map[string]string
+
+func (q cannedQuerier) RunQuery(ctx context.Context, query string) (json.RawMessage, error) {
+	for fragment, rows := range q {
+		if strings.Contains(query, fragment) {
+			return json.RawMessage(rows), nil
+		}
+	}
+	return json.RawMessage("[]"), nil
+}
+
+func TestIntrospect(t *testing.T) {
+	db := cannedQuerier{
+		"from pg_namespace where": `[{"name": "public"}]`,
+		"c.relrowsecurity":        `[{"name": "public.todos", "rls": true}]`,
+		"format_type":             `[{"table_name": "public.todos", "name": "id", "type": "bigint", "not_null": true, "identity": "a", "generated": "", "default_expr": "", "serial": false}, {"table_name": "public.other", "name": "x", "type": "text"}]`,
+		"pg_get_constraintdef":    `[{"table_name": "public.todos", "name": "todos_pkey", "type": "p", "definition": "PRIMARY KEY (id)"}]`,
+		"from pg_policy":          `[{"table_name": "public.todos", "name": "owner", "permissive": true, "command": "r", "roles": ["authenticated"], "using_expr": "true", "check_expr": ""}]`,
+		"pg_enum":                 `[{"name": "public.status", "enum_values": ["'open'", "'done'"]}]`,
+	}
+
+	catalog, err := Introspect(context.Background(), db, []string{"public"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(catalog.Schemas) != 1 || catalog.Schemas[0] != "public" {
+		t.Errorf("expected [public], got %v", catalog.Schemas)
+	}
+	if len(catalog.Enums) != 1 || len(catalog.Enums[0].Values) != 2 {
+		t.Errorf("expected the status enum, got %+v", catalog.Enums)
+	}
+	if len(catalog.Tables) != 1 {
+		t.Fatalf("expected 1 table, got %d", len(catalog.Tables))
+	}
+	todos := catalog.Tables[0]
+	if !todos.RLS || len(todos.Columns) != 1 || len(todos.Constraints) != 1 || len(todos.Policies) != 1 {
+		t.Errorf("expected columns, constraints and policies grouped under todos, got %+v", todos)
+	}
+	if todos.Columns[0].Identity != "a" {
+		t.Errorf("expected an identity column, got %+v", todos.Columns[0])
+	}
+}
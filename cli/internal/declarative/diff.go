@@ -0,0 +1,519 @@
+package declarative
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Migration is the SQL that takes a database from one catalog to another
+type Migration struct {
+	Statements []string `json:"statements"`
+	// Changes that lose data, or that the diff can't express and leaves out
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Empty reports whether the catalogs matched
+func (m *Migration) Empty() bool {
+	return len(m.Statements) == 0
+}
+
+// SQL renders the migration as a file, warnings first as comments
+func (m *Migration) SQL() string {
+	var b strings.Builder
+	b.WriteString("-- Generated by supa\n")
+	for _, w := range m.Warnings {
+		b.WriteString("-- warning: " + w + "\n")
+	}
+	for _, s := range m.Statements {
+		b.WriteString("\n" + s + "\n")
+	}
+	return b.String()
+}
+
+func (m *Migration) add(format string, args ...any) {
+	m.Statements = append(m.Statements, fmt.Sprintf(format, args...))
+}
+
+func (m *Migration) warn(format string, args ...any) {
+	m.Warnings = append(m.Warnings, fmt.Sprintf(format, args...))
+}
+
+// Diff returns the migration that turns the current catalog into the
+// desired one. Statements are ordered so that dependents are dropped before
+// what they depend on and created after it: enums, then tables and their
+// columns, functions, constraints, indexes, views, triggers and policies.
+// Changed views, triggers, policies, indexes and constraints are dropped and
+// recreated; functions are replaced in place.
+func Diff(current, desired *Catalog) *Migration {
+	m := &Migration{}
+
+	// Schemas
+	for _, s := range desired.Schemas {
+		if !contains(current.Schemas, s) {
+			m.add("create schema if not exists %s;", s)
+		}
+	}
+
+	// Enums: labels can be added but not removed
+	currentEnums := make(map[string]Enum)
+	for _, e := range current.Enums {
+		currentEnums[e.Name] = e
+	}
+	for _, e := range desired.Enums {
+		old, ok := currentEnums[e.Name]
+		if !ok {
+			m.add("create type %s as enum (%s);", e.Name, strings.Join(e.Values, ", "))
+			continue
+		}
+		for i, v := range e.Values {
+			if contains(old.Values, v) {
+				continue
+			}
+			// Keep the declared order by placing it before the next label
+			// that already exists
+			position := ""
+			for _, next := range e.Values[i+1:] {
+				if contains(old.Values, next) {
+					position = " before " + next
+					break
+				}
+			}
+			m.add("alter type %s add value %s%s;", e.Name, v, position)
+		}
+		for _, v := range old.Values {
+			if !contains(e.Values, v) {
+				m.warn("enum %s: label %s can't be removed by a migration and was left in place", e.Name, v)
+			}
+		}
+	}
+
+	currentTables := make(map[string]*Table)
+	for i := range current.Tables {
+		currentTables[current.Tables[i].Name] = &current.Tables[i]
+	}
+	desiredTables := make(map[string]*Table)
+	for i := range desired.Tables {
+		desiredTables[desired.Tables[i].Name] = &desired.Tables[i]
+	}
+
+	// Drop what depends on tables and columns that change, surviving
+	// tables first. Dropped tables take their dependents with them.
+	for _, old := range current.Tables {
+		t := desiredTables[old.Name]
+		if t == nil {
+			continue
+		}
+		for _, p := range old.Policies {
+			if np, ok := findPolicy(t.Policies, p.Name); !ok || !equalPolicy(p, np) {
+				m.add("drop policy %s on %s;", p.Name, old.Name)
+			}
+		}
+		for _, tg := range old.Triggers {
+			if nt, ok := findTrigger(t.Triggers, tg.Name); !ok || nt.Definition != tg.Definition {
+				m.add("drop trigger %s on %s;", tg.Name, old.Name)
+			}
+		}
+	}
+
+	// Views created after a changed one may depend on it, so they're
+	// recreated too
+	recreateViews := make(map[string]bool)
+	changed := false
+	for _, v := range current.Views {
+		nv, ok := findView(desired.Views, v.Name)
+		if changed || !ok || !equalView(v, nv) {
+			changed = true
+			recreateViews[v.Name] = true
+		}
+	}
+	for i := len(current.Views) - 1; i >= 0; i-- {
+		if v := current.Views[i]; recreateViews[v.Name] {
+			m.add("drop %s %s;", viewKind(v), v.Name)
+		}
+	}
+
+	for _, old := range current.Tables {
+		t := desiredTables[old.Name]
+		if t == nil {
+			continue
+		}
+		for _, x := range old.Indexes {
+			if nx, ok := findIndex(t.Indexes, x.Name); !ok || nx.Definition != x.Definition {
+				m.add("drop index %s;", x.Name)
+			}
+		}
+	}
+
+	// Foreign keys go first, as they depend on the referenced table's keys
+	var dropConstraints []Constraint
+	for _, old := range current.Tables {
+		t := desiredTables[old.Name]
+		if t == nil {
+			continue
+		}
+		for _, c := range old.Constraints {
+			if nc, ok := findConstraint(t.Constraints, c.Name); !ok || nc.Definition != c.Definition || nc.Type != c.Type {
+				dropConstraints = append(dropConstraints, c)
+			}
+		}
+	}
+	sort.SliceStable(dropConstraints, func(i, j int) bool {
+		return dropConstraints[i].Type == "f" && dropConstraints[j].Type != "f"
+	})
+	for _, c := range dropConstraints {
+		m.add("alter table %s drop constraint %s;", c.Table, c.Name)
+	}
+
+	// Tables dropped together, in case they reference each other
+	var dropTables []string
+	for _, old := range current.Tables {
+		if desiredTables[old.Name] == nil {
+			dropTables = append(dropTables, old.Name)
+			m.warn("drops table %s and its data", old.Name)
+		}
+	}
+	if len(dropTables) > 0 {
+		m.add("drop table %s;", strings.Join(dropTables, ", "))
+	}
+
+	// Tables and columns
+	for _, t := range desired.Tables {
+		old := currentTables[t.Name]
+		if old == nil {
+			m.add("%s", createTable(t))
+			if t.RLS {
+				m.add("alter table %s enable row level security;", t.Name)
+			}
+			continue
+		}
+
+		for _, c := range t.Columns {
+			oc, ok := findColumn(old.Columns, c.Name)
+			if !ok {
+				m.add("alter table %s add column %s;", t.Name, columnDefinition(c))
+				continue
+			}
+			alterColumn(m, t.Name, oc, c)
+		}
+		for _, oc := range old.Columns {
+			if _, ok := findColumn(t.Columns, oc.Name); !ok {
+				m.add("alter table %s drop column %s;", t.Name, oc.Name)
+				m.warn("drops column %s.%s and its data", t.Name, oc.Name)
+			}
+		}
+
+		if t.RLS != old.RLS {
+			if t.RLS {
+				m.add("alter table %s enable row level security;", t.Name)
+			} else {
+				m.add("alter table %s disable row level security;", t.Name)
+			}
+		}
+	}
+
+	// Functions, before the constraints, indexes and views that may call
+	// them. Bodies aren't checked, so their order doesn't matter.
+	functions := false
+	for _, f := range desired.Functions {
+		if of, ok := findFunction(current.Functions, f.Name); ok && of.Definition == f.Definition {
+			continue
+		}
+		if !functions {
+			m.add("set check_function_bodies = off;")
+			functions = true
+		}
+		m.add("%s", createFunction(f))
+	}
+
+	// Keys before the foreign keys that reference them
+	var addConstraints []Constraint
+	for _, t := range desired.Tables {
+		old := currentTables[t.Name]
+		for _, c := range t.Constraints {
+			if old != nil {
+				if oc, ok := findConstraint(old.Constraints, c.Name); ok && oc.Definition == c.Definition && oc.Type == c.Type {
+					continue
+				}
+			}
+			addConstraints = append(addConstraints, c)
+		}
+	}
+	sort.SliceStable(addConstraints, func(i, j int) bool {
+		return addConstraints[i].Type != "f" && addConstraints[j].Type == "f"
+	})
+	for _, c := range addConstraints {
+		m.add("%s", addConstraint(c))
+	}
+
+	for _, t := range desired.Tables {
+		old := currentTables[t.Name]
+		for _, x := range t.Indexes {
+			if old != nil {
+				if ox, ok := findIndex(old.Indexes, x.Name); ok && ox.Definition == x.Definition {
+					continue
+				}
+			}
+			m.add("%s;", x.Definition)
+		}
+	}
+
+	for _, v := range desired.Views {
+		if _, ok := findView(current.Views, v.Name); ok && !recreateViews[v.Name] {
+			continue
+		}
+		m.add("%s", createView(v))
+	}
+
+	for _, t := range desired.Tables {
+		old := currentTables[t.Name]
+		for _, tg := range t.Triggers {
+			if old != nil {
+				if ot, ok := findTrigger(old.Triggers, tg.Name); ok && ot.Definition == tg.Definition {
+					continue
+				}
+			}
+			m.add("%s;", tg.Definition)
+		}
+		for _, p := range t.Policies {
+			if old != nil {
+				if op, ok := findPolicy(old.Policies, p.Name); ok && equalPolicy(op, p) {
+					continue
+				}
+			}
+			m.add("%s", policyDefinition(p))
+		}
+	}
+
+	// What nothing depends on anymore
+	for _, f := range current.Functions {
+		if _, ok := findFunction(desired.Functions, f.Name); !ok {
+			m.add("drop function %s;", f.Name)
+		}
+	}
+	for _, e := range current.Enums {
+		found := false
+		for _, ne := range desired.Enums {
+			if ne.Name == e.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			m.add("drop type %s;", e.Name)
+		}
+	}
+
+	return m
+}
+
+// alterColumn changes a column's type, default and nullability in place.
+// Identity and generated columns can only change between identity kinds.
+func alterColumn(m *Migration, table string, old, c Column) {
+	if old.Generated != c.Generated || (c.Generated != "" && old.Default != c.Default) || old.Serial != c.Serial {
+		m.warn("column %s.%s: changing a generated or serial column isn't supported - drop and re-add it", table, c.Name)
+		return
+	}
+
+	prefix := fmt.Sprintf("alter table %s alter column %s", table, c.Name)
+	defaultChanged := c.Generated == "" && !c.Serial && old.Default != c.Default
+
+	if defaultChanged && old.Default != "" {
+		m.add("%s drop default;", prefix)
+	}
+	if old.Type != c.Type {
+		m.add("%s type %s using %s::%s;", prefix, c.Type, c.Name, c.Type)
+	}
+	if defaultChanged && c.Default != "" {
+		m.add("%s set default %s;", prefix, c.Default)
+	}
+
+	if old.NotNull != c.NotNull {
+		if c.NotNull {
+			m.add("%s set not null;", prefix)
+		} else {
+			m.add("%s drop not null;", prefix)
+		}
+	}
+
+	switch {
+	case old.Identity == c.Identity:
+	case old.Identity == "":
+		m.add("%s add generated %s as identity;", prefix, identityKind(c.Identity))
+	case c.Identity == "":
+		m.add("%s drop identity;", prefix)
+	default:
+		m.add("%s set generated %s;", prefix, identityKind(c.Identity))
+	}
+}
+
+func createTable(t Table) string {
+	columns := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		columns[i] = "  " + columnDefinition(c)
+	}
+	return fmt.Sprintf("create table %s (\n%s\n);", t.Name, strings.Join(columns, ",\n"))
+}
+
+func createFunction(f Function) string {
+	return strings.TrimSpace(f.Definition) + ";"
+}
+
+func addConstraint(c Constraint) string {
+	return fmt.Sprintf("alter table %s add constraint %s %s;", c.Table, c.Name, c.Definition)
+}
+
+func createView(v View) string {
+	options := ""
+	if len(v.Options) > 0 {
+		options = " with (" + strings.Join(v.Options, ", ") + ")"
+	}
+	definition := strings.TrimSuffix(strings.TrimSpace(v.Definition), ";")
+	return fmt.Sprintf("create %s %s%s as\n%s;", viewKind(v), v.Name, options, definition)
+}
+
+// columnDefinition renders a column for create table and add column
+func columnDefinition(c Column) string {
+	def := c.Name + " " + c.Type
+	switch {
+	case c.Serial:
+		def = c.Name + " " + serialType(c.Type)
+	case c.Generated != "":
+		def += " generated always as (" + c.Default + ") stored"
+	case c.Identity != "":
+		def += " generated " + identityKind(c.Identity) + " as identity"
+	case c.Default != "":
+		def += " default " + c.Default
+	}
+	if c.NotNull && !c.Serial && c.Identity == "" {
+		def += " not null"
+	}
+	return def
+}
+
+func serialType(t string) string {
+	switch t {
+	case "bigint":
+		return "bigserial"
+	case "smallint":
+		return "smallserial"
+	}
+	return "serial"
+}
+
+func identityKind(identity string) string {
+	if identity == "a" {
+		return "always"
+	}
+	return "by default"
+}
+
+func viewKind(v View) string {
+	if v.Materialized {
+		return "materialized view"
+	}
+	return "view"
+}
+
+func policyDefinition(p Policy) string {
+	commands := map[string]string{"r": "select", "a": "insert", "w": "update", "d": "delete", "*": "all"}
+	kind := "permissive"
+	if !p.Permissive {
+		kind = "restrictive"
+	}
+	roles := "public"
+	if len(p.Roles) > 0 {
+		roles = strings.Join(p.Roles, ", ")
+	}
+
+	def := fmt.Sprintf("create policy %s on %s as %s for %s to %s", p.Name, p.Table, kind, commands[p.Command], roles)
+	if p.Using != "" {
+		def += " using (" + p.Using + ")"
+	}
+	if p.Check != "" {
+		def += " with check (" + p.Check + ")"
+	}
+	return def + ";"
+}
+
+func equalPolicy(a, b Policy) bool {
+	return a.Permissive == b.Permissive && a.Command == b.Command && a.Using == b.Using &&
+		a.Check == b.Check && strings.Join(a.Roles, ",") == strings.Join(b.Roles, ",")
+}
+
+func equalView(a, b View) bool {
+	return a.Materialized == b.Materialized && a.Definition == b.Definition &&
+		strings.Join(a.Options, ",") == strings.Join(b.Options, ",")
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func findColumn(columns []Column, name string) (Column, bool) {
+	for _, c := range columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Column{}, false
+}
+
+func findConstraint(constraints []Constraint, name string) (Constraint, bool) {
+	for _, c := range constraints {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Constraint{}, false
+}
+
+func findIndex(indexes []Index, name string) (Index, bool) {
+	for _, x := range indexes {
+		if x.Name == name {
+			return x, true
+		}
+	}
+	return Index{}, false
+}
+
+func findTrigger(triggers []Trigger, name string) (Trigger, bool) {
+	for _, t := range triggers {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Trigger{}, false
+}
+
+func findPolicy(policies []Policy, name string) (Policy, bool) {
+	for _, p := range policies {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Policy{}, false
+}
+
+func findView(views []View, name string) (View, bool) {
+	for _, v := range views {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return View{}, false
+}
+
+func findFunction(functions []Function, name string) (Function, bool) {
+	for _, f := range functions {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Function{}, false
+}
@@ -0,0 +1,198 @@
+package declarative
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func todos() Table {
+	return Table{
+		Name: "public.todos",
+		RLS:  true,
+		Columns: []Column{
+			{Table: "public.todos", Name: "id", Type: "bigint", NotNull: true, Identity: "a"},
+			{Table: "public.todos", Name: "title", Type: "text", NotNull: true},
+			{Table: "public.todos", Name: "done", Type: "boolean", NotNull: true, Default: "false"},
+		},
+		Constraints: []Constraint{
+			{Table: "public.todos", Name: "todos_pkey", Type: "p", Definition: "PRIMARY KEY (id)"},
+		},
+		Policies: []Policy{
+			{Table: "public.todos", Name: "read", Permissive: true, Command: "r", Roles: []string{"authenticated"}, Using: "true"},
+		},
+	}
+}
+
+func TestDiffEmpty(t *testing.T) {
+	catalog := &Catalog{Schemas: []string{"public"}, Tables: []Table{todos()}}
+	if m := Diff(catalog, catalog); !m.Empty() || len(m.Warnings) != 0 {
+		t.Errorf("expected no changes, got %+v", m)
+	}
+}
+
+func TestDiffCreate(t *testing.T) {
+	current := &Catalog{Schemas: []string{"public"}}
+	desired := &Catalog{
+		Schemas: []string{"public"},
+		Enums:   []Enum{{Name: "public.status", Values: []string{"'open'", "'done'"}}},
+		Tables:  []Table{todos()},
+		Views:   []View{{Name: "public.open_todos", Options: []string{"security_invoker=true"}, Definition: " SELECT id FROM todos;"}},
+	}
+
+	expected := []string{
+		"create type public.status as enum ('open', 'done');",
+		"create table public.todos (\n  id bigint generated always as identity,\n  title text not null,\n  done boolean default false not null\n);",
+		"alter table public.todos enable row level security;",
+		"alter table public.todos add constraint todos_pkey PRIMARY KEY (id);",
+		"create view public.open_todos with (security_invoker=true) as\nSELECT id FROM todos;",
+		"create policy read on public.todos as permissive for select to authenticated using (true);",
+	}
+	m := Diff(current, desired)
+	if strings.Join(m.Statements, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(m.Statements, "\n"))
+	}
+}
+
+func TestDiffAlter(t *testing.T) {
+	current := &Catalog{Schemas: []string{"public"}, Tables: []Table{todos()}}
+
+	table := todos()
+	table.Columns[1].Type = "character varying(200)"
+	table.Columns[2].Default = "true"
+	table.Columns = append(table.Columns, Column{Table: "public.todos", Name: "owner", Type: "uuid"})
+	table.Constraints = append(table.Constraints, Constraint{Table: "public.todos", Name: "todos_owner_fkey", Type: "f", Definition: "FOREIGN KEY (owner) REFERENCES auth.users(id)"})
+	table.Policies[0].Using = "(owner = auth.uid())"
+	desired := &Catalog{Schemas: []string{"public"}, Tables: []Table{table}}
+
+	expected := []string{
+		"drop policy read on public.todos;",
+		"alter table public.todos alter column title type character varying(200) using title::character varying(200);",
+		"alter table public.todos alter column done drop default;",
+		"alter table public.todos alter column done set default true;",
+		"alter table public.todos add column owner uuid;",
+		"alter table public.todos add constraint todos_owner_fkey FOREIGN KEY (owner) REFERENCES auth.users(id);",
+		"create policy read on public.todos as permissive for select to authenticated using ((owner = auth.uid()));",
+	}
+	m := Diff(current, desired)
+	if strings.Join(m.Statements, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(m.Statements, "\n"))
+	}
+}
+
+func TestDiffDrop(t *testing.T) {
+	other := Table{Name: "public.notes", Columns: []Column{{Table: "public.notes", Name: "body", Type: "text"}}}
+	current := &Catalog{
+		Schemas:   []string{"public"},
+		Enums:     []Enum{{Name: "public.status", Values: []string{"'open'", "'done'"}}},
+		Tables:    []Table{todos(), other},
+		Functions: []Function{{Name: "public.touch()", Definition: "CREATE OR REPLACE FUNCTION public.touch() ..."}},
+	}
+
+	table := todos()
+	table.Columns = table.Columns[:2]
+	desired := &Catalog{
+		Schemas: []string{"public"},
+		Enums:   []Enum{{Name: "public.status", Values: []string{"'open'", "'blocked'"}}},
+		Tables:  []Table{table},
+	}
+
+	expected := []string{
+		"alter type public.status add value 'blocked';",
+		"drop table public.notes;",
+		"alter table public.todos drop column done;",
+		"drop function public.touch();",
+	}
+	m := Diff(current, desired)
+	if strings.Join(m.Statements, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(m.Statements, "\n"))
+	}
+	if len(m.Warnings) != 3 {
+		t.Errorf("expected warnings for the enum label, table and column, got %v", m.Warnings)
+	}
+}
+
+func TestDiffEnumLabelOrder(t *testing.T) {
+	current := &Catalog{Enums: []Enum{{Name: "public.status", Values: []string{"'open'", "'done'"}}}}
+	desired := &Catalog{Enums: []Enum{{Name: "public.status", Values: []string{"'open'", "'doing'", "'done'"}}}}
+
+	m := Diff(current, desired)
+	if len(m.Statements) != 1 || m.Statements[0] != "alter type public.status add value 'doing' before 'done';" {
+		t.Errorf("expected the label added before 'done', got %v", m.Statements)
+	}
+}
+
+func TestDiffRecreatesDependentViews(t *testing.T) {
+	current := &Catalog{Views: []View{
+		{Name: "public.a", Definition: " SELECT 1 AS x;"},
+		{Name: "public.b", Definition: " SELECT x FROM a;"},
+	}}
+	desired := &Catalog{Views: []View{
+		{Name: "public.a", Definition: " SELECT 2 AS x;"},
+		{Name: "public.b", Definition: " SELECT x FROM a;"},
+	}}
+
+	expected := []string{
+		"drop view public.b;",
+		"drop view public.a;",
+		"create view public.a as\nSELECT 2 AS x;",
+		"create view public.b as\nSELECT x FROM a;",
+	}
+	m := Diff(current, desired)
+	if strings.Join(m.Statements, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(m.Statements, "\n"))
+	}
+}
+
+func TestMigrationSQL(t *testing.T) {
+	m := &Migration{Statements: []string{"drop table public.notes;"}, Warnings: []string{"drops table public.notes and its data"}}
+	sql := m.SQL()
+	if !strings.Contains(sql, "-- warning: drops table public.notes and its data\n") || !strings.HasSuffix(sql, "drop table public.notes;\n") {
+		t.Errorf("unexpected migration file:\n%s", sql)
+	}
+}
+
+func TestDump(t *testing.T) {
+	table := todos()
+	table.Constraints = append(table.Constraints, Constraint{Table: "public.todos", Name: "todos_owner_fkey", Type: "f", Definition: "FOREIGN KEY (owner) REFERENCES auth.users(id)"})
+	catalog := &Catalog{
+		Schemas:   []string{"app", "public"},
+		Tables:    []Table{table},
+		Functions: []Function{{Name: "public.z()", Definition: "CREATE FUNCTION z"}, {Name: "public.a()", Definition: "CREATE FUNCTION a\n"}},
+	}
+
+	sources := Dump(catalog)
+	if len(sources) != 9 {
+		t.Fatalf("expected 9 files, got %d", len(sources))
+	}
+	files := make(map[string]string)
+	for _, s := range sources {
+		files[filepath.Base(s.Path)] = s.SQL
+	}
+
+	if !strings.Contains(files["00_schemas.sql"], "create schema if not exists app;") || strings.Contains(files["00_schemas.sql"], "public") {
+		t.Errorf("expected only the app schema to be created, got:\n%s", files["00_schemas.sql"])
+	}
+	if !strings.Contains(files["02_tables.sql"], "alter table public.todos enable row level security;") {
+		t.Errorf("expected RLS enabled with the table, got:\n%s", files["02_tables.sql"])
+	}
+	constraints := files["04_constraints.sql"]
+	if strings.Index(constraints, "todos_pkey") > strings.Index(constraints, "todos_owner_fkey") {
+		t.Errorf("expected keys before foreign keys, got:\n%s", constraints)
+	}
+	functions := files["03_functions.sql"]
+	if !strings.Contains(functions, "check_function_bodies = off") || strings.Index(functions, "CREATE FUNCTION a;") > strings.Index(functions, "CREATE FUNCTION z;") {
+		t.Errorf("expected functions sorted by name, got:\n%s", functions)
+	}
+	if files["06_views.sql"] != "-- Generated by supa pull from the database schema\n" {
+		t.Errorf("expected an empty views file, got:\n%s", files["06_views.sql"])
+	}
+
+	// Dumping is deterministic
+	again := Dump(catalog)
+	for i := range sources {
+		if sources[i] != again[i] {
+			t.Errorf("expected identical output for %s", sources[i].Path)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package declarative
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Dump renders a catalog as schema files, one per kind of object, numbered
+// in the order they apply in: a key or foreign key can't be added before
+// the tables it spans exist, and views, triggers and policies need their
+// tables and functions. The output depends only on the catalog, so dumping
+// an unchanged database yields identical files.
+func Dump(catalog *Catalog) []Source {
+	var schemas, types, tables, functions, constraints, indexes, views, triggers, policies []string
+
+	for _, s := range catalog.Schemas {
+		if s != "public" {
+			schemas = append(schemas, fmt.Sprintf("create schema if not exists %s;", s))
+		}
+	}
+
+	for _, e := range catalog.Enums {
+		types = append(types, fmt.Sprintf("create type %s as enum (%s);", e.Name, strings.Join(e.Values, ", ")))
+	}
+
+	var keys, foreignKeys []string
+	for _, t := range catalog.Tables {
+		statement := createTable(t)
+		if t.RLS {
+			statement += fmt.Sprintf("\nalter table %s enable row level security;", t.Name)
+		}
+		tables = append(tables, statement)
+
+		for _, c := range t.Constraints {
+			if c.Type == "f" {
+				foreignKeys = append(foreignKeys, addConstraint(c))
+			} else {
+				keys = append(keys, addConstraint(c))
+			}
+		}
+		for _, x := range t.Indexes {
+			indexes = append(indexes, x.Definition+";")
+		}
+		for _, tg := range t.Triggers {
+			triggers = append(triggers, tg.Definition+";")
+		}
+		for _, p := range t.Policies {
+			policies = append(policies, policyDefinition(p))
+		}
+	}
+	constraints = append(keys, foreignKeys...)
+
+	// Creation order depends on the database's history; functions don't
+	// depend on each other once bodies aren't checked, so sort them
+	sorted := append([]Function(nil), catalog.Functions...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	if len(sorted) > 0 {
+		functions = append(functions, "set check_function_bodies = off;")
+	}
+	for _, f := range sorted {
+		functions = append(functions, createFunction(f))
+	}
+
+	for _, v := range catalog.Views {
+		views = append(views, createView(v))
+	}
+
+	files := []struct {
+		name       string
+		statements []string
+	}{
+		{"00_schemas.sql", schemas},
+		{"01_types.sql", types},
+		{"02_tables.sql", tables},
+		{"03_functions.sql", functions},
+		{"04_constraints.sql", constraints},
+		{"05_indexes.sql", indexes},
+		{"06_views.sql", views},
+		{"07_triggers.sql", triggers},
+		{"08_policies.sql", policies},
+	}
+
+	sources := make([]Source, len(files))
+	for i, f := range files {
+		var b strings.Builder
+		b.WriteString("-- Generated by supa pull from the database schema\n")
+		for _, s := range f.statements {
+			b.WriteString("\n" + s + "\n")
+		}
+		sources[i] = Source{Path: filepath.Join(Dir, f.name), SQL: b.String()}
+	}
+	return sources
+}
@@ -0,0 +1,57 @@
+package envfile
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	data := []byte(`# Stripe
+STRIPE_KEY=sk_test_123
+export OPENAI_KEY = "sk-\"quoted\"\n"
+SINGLE='raw \n # not a comment'
+INLINE=value # trailing comment
+EMPTY=
+
+`)
+
+	vars, err := Parse(data)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := map[string]string{
+		"STRIPE_KEY": "sk_test_123",
+		"OPENAI_KEY": "sk-\"quoted\"\n",
+		"SINGLE":     `raw \n # not a comment`,
+		"INLINE":     "value",
+		"EMPTY":      "",
+	}
+	for k, v := range expected {
+		if vars[k] != v {
+			t.Errorf("expected %s=%q, got %q", k, v, vars[k])
+		}
+	}
+	if len(vars) != len(expected) {
+		t.Errorf("expected %d vars, got %d", len(expected), len(vars))
+	}
+
+	keys := Keys(vars)
+	if keys[0] != "EMPTY" || keys[len(keys)-1] != "STRIPE_KEY" {
+		t.Errorf("expected sorted keys, got %v", keys)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"NO_EQUALS",
+		"1BAD=x",
+		`OPEN="unterminated`,
+		"OPEN='unterminated",
+	}
+
+	for _, input := range tests {
+		if _, err := Parse([]byte(input)); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+}
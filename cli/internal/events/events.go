@@ -0,0 +1,67 @@
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+//go:generate go run ./gen ../../events-schema/events.schema.json
+
+// Version is the protocol version, sent with every event as "v". It's
+// bumped when a field is removed or changes meaning. New event types and
+// optional fields are added without a bump, so consumers should ignore
+// what they don't know.
+const Version = 1
+
+// Type identifies an event and the shape of its payload
+type Type string
+
+// Event is one line of `supa watch --json` output
+type Event struct {
+	Version int       `json:"v"`
+	Type    Type      `json:"type"`
+	Time    time.Time `json:"ts"`
+	Seq     uint64    `json:"seq"` // 1 for the first event, then one more per event
+	Payload Payload   `json:"payload"`
+}
+
+// Payload is the data of one type of event
+type Payload interface {
+	EventType() Type
+}
+
+// Writer writes events as newline-delimited JSON, one object per line, and
+// numbers them. It's safe for concurrent use.
+type Writer struct {
+	mu  sync.Mutex
+	out io.Writer
+	seq uint64
+	now func() time.Time
+}
+
+// NewWriter returns a Writer that writes to out
+func NewWriter(out io.Writer) *Writer {
+	return &Writer{out: out, now: time.Now}
+}
+
+// Emit writes one event
+func (w *Writer) Emit(payload Payload) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.seq++
+	line, err := json.Marshal(Event{
+		Version: Version,
+		Type:    payload.EventType(),
+		Time:    w.now().UTC(),
+		Seq:     w.seq,
+		Payload: payload,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.out.Write(append(line, '\n'))
+	return err
+}
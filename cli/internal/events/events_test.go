@@ -0,0 +1,93 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	if err := w.Emit(TypesUpdated{Path: "supabase/types/database.ts"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := w.Emit(MigrationsChanged{Pending: []string{"20240101000000_init.sql"}}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d:\n%s", len(lines), buf.String())
+	}
+
+	expected := `{"v":1,"type":"types_updated","ts":"2024-01-02T03:04:05Z","seq":1,"payload":{"path":"supabase/types/database.ts"}}`
+	if lines[0] != expected {
+		t.Errorf("expected %s, got %s", expected, lines[0])
+	}
+
+	var second struct {
+		Type    Type              `json:"type"`
+		Seq     uint64            `json:"seq"`
+		Payload MigrationsChanged `json:"payload"`
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &second); err != nil {
+		t.Fatalf("expected valid JSON, got %v", err)
+	}
+	if second.Type != "migrations_changed" || second.Seq != 2 || len(second.Payload.Pending) != 1 {
+		t.Errorf("unexpected event: %+v", second)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	schema, err := Schema()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var parsed struct {
+		Properties struct {
+			Type struct {
+				Enum []string `json:"enum"`
+			} `json:"type"`
+		} `json:"properties"`
+		Defs map[string]struct {
+			Required []string `json:"required"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(schema, &parsed); err != nil {
+		t.Fatalf("expected valid JSON, got %v", err)
+	}
+	if len(parsed.Properties.Type.Enum) != len(All) {
+		t.Errorf("expected %d event types, got %v", len(All), parsed.Properties.Type.Enum)
+	}
+	if _, ok := parsed.Defs["FunctionChange"]; !ok {
+		t.Error("expected nested structs in $defs")
+	}
+	if required := parsed.Defs["TypesError"].Required; len(required) != 1 || required[0] != "error" {
+		t.Errorf("expected only error required, got %v", required)
+	}
+}
+
+// The committed schema must match the event types
+func TestSchemaUpToDate(t *testing.T) {
+	path := filepath.Join("..", "..", "events-schema", "events.schema.json")
+	committed, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+
+	schema, err := Schema()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !bytes.Equal(committed, schema) {
+		t.Errorf("%s is out of date - run go generate ./internal/events", path)
+	}
+}
@@ -0,0 +1,26 @@
+// Command gen writes the JSON Schema of watch events to the given path
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/supabase/supabase-dx/cli/internal/events"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: gen <output path>")
+		os.Exit(2)
+	}
+
+	schema, err := events.Schema()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(os.Args[1], schema, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
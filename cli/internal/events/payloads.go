@@ -0,0 +1,158 @@
+package events
+
+// ============================================================================
+// Lifecycle
+// ============================================================================
+
+// WatchStarted is the first event, once the target is open and files are
+// watched
+type WatchStarted struct {
+	Profile       string `json:"profile"`
+	ProjectRef    string `json:"project_ref"` // project ref, or host:port/dbname for local targets
+	Branch        string `json:"branch,omitempty"`
+	AutoPush      bool   `json:"auto_push"`
+	TypesInterval string `json:"types_interval,omitempty"` // types are also refreshed on this timer
+}
+
+// WatchStopped is the last event of a watch interrupted by the user
+type WatchStopped struct {
+	Profile string `json:"profile"`
+}
+
+// WatchError is a failure of the watch itself. A fatal error ends it; it's
+// then the last event.
+type WatchError struct {
+	Message   string `json:"message"`
+	Error     string `json:"error,omitempty"`
+	ErrorCode string `json:"error_code,omitempty"`
+	Fatal     bool   `json:"fatal"`
+}
+
+// ============================================================================
+// Git branch
+// ============================================================================
+
+// ProfileChanged is a git branch switch to a branch another profile matches
+type ProfileChanged struct {
+	Branch     string `json:"branch"`
+	Profile    string `json:"profile"`
+	ProjectRef string `json:"project_ref"`
+}
+
+// BranchChanged is a git branch switch that keeps the profile
+type BranchChanged struct {
+	Branch  string `json:"branch"`
+	Profile string `json:"profile"`
+}
+
+// PreviewBranchChanged is a preview profile moving to the Supabase branch of
+// a new git branch
+type PreviewBranchChanged struct {
+	Branch     string `json:"branch"`
+	Profile    string `json:"profile"`
+	ProjectRef string `json:"project_ref"`
+}
+
+// PreviewBranchError is a failure to resolve or create a preview branch
+type PreviewBranchError struct {
+	Branch    string `json:"branch"`
+	Error     string `json:"error"`
+	ErrorCode string `json:"error_code,omitempty"`
+}
+
+// ============================================================================
+// Types
+// ============================================================================
+
+// TypesUpdated is a rewrite of the generated TypeScript types
+type TypesUpdated struct {
+	Path string `json:"path"`
+}
+
+// TypesError is a failure to generate types
+type TypesError struct {
+	Error     string `json:"error"`
+	ErrorCode string `json:"error_code,omitempty"`
+}
+
+// ============================================================================
+// Plans and pushes
+// ============================================================================
+
+// MigrationsChanged is the push plan after a change to supabase/migrations
+type MigrationsChanged struct {
+	Pending []string `json:"pending,omitempty"` // files push would apply, in order
+	Error   string   `json:"error,omitempty"`   // why push would refuse, e.g. a diverged history
+}
+
+// SchemaChanged is the migration push would generate after a change to
+// supabase/schemas
+type SchemaChanged struct {
+	Statements []string `json:"statements,omitempty"`
+	Warnings   []string `json:"warnings,omitempty"`
+}
+
+// FunctionsChanged is the functions push would deploy after a change to
+// supabase/functions
+type FunctionsChanged struct {
+	Functions []FunctionChange `json:"functions"`
+}
+
+// FunctionChange is a function whose bundle differs from what's deployed
+type FunctionChange struct {
+	Slug   string `json:"slug"`
+	Reason string `json:"reason"`
+}
+
+// PlanError is a failure to work out what a change means for the target
+type PlanError struct {
+	Kind      string `json:"kind"` // migrations, schemas or functions
+	Message   string `json:"message"`
+	Error     string `json:"error"`
+	ErrorCode string `json:"error_code,omitempty"`
+}
+
+// AutoPush is the result of pushing a change as it was saved
+type AutoPush struct {
+	Kind       string   `json:"kind"`   // migrations or functions
+	Status     string   `json:"status"` // success, error or refused
+	Message    string   `json:"message"`
+	Migrations []string `json:"migrations,omitempty"` // applied
+	Functions  []string `json:"functions,omitempty"`  // deployed
+	Error      string   `json:"error,omitempty"`
+	ErrorCode  string   `json:"error_code,omitempty"`
+}
+
+func (WatchStarted) EventType() Type         { return "watch_started" }
+func (WatchStopped) EventType() Type         { return "watch_stopped" }
+func (WatchError) EventType() Type           { return "watch_error" }
+func (ProfileChanged) EventType() Type       { return "profile_changed" }
+func (BranchChanged) EventType() Type        { return "branch_changed" }
+func (PreviewBranchChanged) EventType() Type { return "preview_branch_changed" }
+func (PreviewBranchError) EventType() Type   { return "preview_branch_error" }
+func (TypesUpdated) EventType() Type         { return "types_updated" }
+func (TypesError) EventType() Type           { return "types_error" }
+func (MigrationsChanged) EventType() Type    { return "migrations_changed" }
+func (SchemaChanged) EventType() Type        { return "schema_changed" }
+func (FunctionsChanged) EventType() Type     { return "functions_changed" }
+func (PlanError) EventType() Type            { return "plan_error" }
+func (AutoPush) EventType() Type             { return "auto_push" }
+
+// All lists an instance of every payload, in the order the schema
+// documents them
+var All = []Payload{
+	WatchStarted{},
+	WatchStopped{},
+	WatchError{},
+	ProfileChanged{},
+	BranchChanged{},
+	PreviewBranchChanged{},
+	PreviewBranchError{},
+	TypesUpdated{},
+	TypesError{},
+	MigrationsChanged{},
+	SchemaChanged{},
+	FunctionsChanged{},
+	PlanError{},
+	AutoPush{},
+}
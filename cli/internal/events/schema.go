@@ -0,0 +1,122 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Schema returns the JSON Schema (draft 2020-12) of an event line, derived
+// from Event and the payloads in All. The committed events.schema.json is
+// generated from it with `go generate`.
+func Schema() ([]byte, error) {
+	defs := make(map[string]any)
+	var types []string
+	var variants []any
+
+	for _, p := range All {
+		t := reflect.TypeOf(p)
+		def, err := objectSchema(t, defs)
+		if err != nil {
+			return nil, err
+		}
+		defs[t.Name()] = def
+
+		types = append(types, string(p.EventType()))
+		variants = append(variants, map[string]any{
+			"if": map[string]any{
+				"properties": map[string]any{"type": map[string]any{"const": p.EventType()}},
+			},
+			"then": map[string]any{
+				"properties": map[string]any{"payload": map[string]any{"$ref": "#/$defs/" + t.Name()}},
+			},
+		})
+	}
+
+	schema := map[string]any{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"title":       "supa watch --json event",
+		"description": fmt.Sprintf("One line of `supa watch --json` output, protocol version %d. Unknown types and fields may be added without a version bump.", Version),
+		"type":        "object",
+		"required":    []string{"v", "type", "ts", "seq", "payload"},
+		"properties": map[string]any{
+			"v":       map[string]any{"const": Version},
+			"type":    map[string]any{"type": "string", "enum": types},
+			"ts":      map[string]any{"type": "string", "format": "date-time"},
+			"seq":     map[string]any{"type": "integer", "minimum": 1},
+			"payload": map[string]any{"type": "object"},
+		},
+		"allOf": variants,
+		"$defs": defs,
+	}
+
+	out, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// objectSchema describes a struct by its JSON fields. Fields without
+// omitempty are required. Nested structs are added to defs.
+func objectSchema(t reflect.Type, defs map[string]any) (map[string]any, error) {
+	properties := make(map[string]any)
+	required := []string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+
+		property, err := valueSchema(field.Type, defs)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t.Name(), field.Name, err)
+		}
+		properties[name] = property
+		if options != "omitempty" {
+			required = append(required, name)
+		}
+	}
+
+	return map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": true,
+	}, nil
+}
+
+func valueSchema(t reflect.Type, defs map[string]any) (map[string]any, error) {
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Slice:
+		items, err := valueSchema(t.Elem(), defs)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case reflect.Struct:
+		if _, ok := defs[t.Name()]; !ok {
+			def, err := objectSchema(t, defs)
+			if err != nil {
+				return nil, err
+			}
+			defs[t.Name()] = def
+		}
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+}
@@ -0,0 +1,317 @@
+package functions
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Unpack extracts the source files from a function body downloaded from the
+// Management API. The body is either multipart/form-data (a metadata part
+// plus one part per file) or an eszip archive, optionally brotli-compressed
+// with an "EZBR" prefix. Returned names are relative to the project root.
+func Unpack(slug, contentType string, body []byte) ([]File, error) {
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+
+	var (
+		sources map[string][]byte
+		err     error
+	)
+	if strings.HasPrefix(mediaType, "multipart/") {
+		sources, err = readMultipart(body, params["boundary"])
+	} else {
+		sources, err = readEszip(body)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack %s: %w", slug, err)
+	}
+
+	return placeFiles(slug, sources), nil
+}
+
+// readMultipart returns file parts keyed by their (unmodified) filename.
+// mime/multipart's FileName() strips directories, so the Content-Disposition
+// header is parsed directly.
+func readMultipart(body []byte, boundary string) (map[string][]byte, error) {
+	if boundary == "" {
+		return nil, errors.New("multipart body without boundary")
+	}
+
+	sources := make(map[string][]byte)
+	r := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		_, params, _ := mime.ParseMediaType(part.Header.Get("Content-Disposition"))
+		name := params["filename"]
+		if name == "" {
+			// metadata and other form fields
+			continue
+		}
+
+		content, err := io.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+		sources[name] = content
+	}
+	return sources, nil
+}
+
+// =============================================================================
+// eszip
+// =============================================================================
+
+const (
+	eszipModule   = 0
+	eszipRedirect = 1
+	eszipNpm      = 2
+)
+
+var brotliMagic = []byte("EZBR")
+
+// readEszip returns the local (file://) module sources of an eszip v2
+// archive keyed by specifier. Remote modules, redirects and npm packages are
+// skipped.
+func readEszip(data []byte) (map[string][]byte, error) {
+	if bytes.HasPrefix(data, brotliMagic) {
+		decompressed, err := io.ReadAll(brotli.NewReader(bytes.NewReader(data[len(brotliMagic):])))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress body: %w", err)
+		}
+		data = decompressed
+	}
+
+	if len(data) < 8 {
+		return nil, errors.New("body is not an eszip archive")
+	}
+	magic, r := string(data[:8]), &eszipReader{data: data[8:]}
+
+	// Checksums follow every section; sha256 unless the options header says
+	// otherwise
+	checksumSize := 32
+	switch magic {
+	case "ESZIP_V2", "ESZIP2.1":
+	case "ESZIP2.2", "ESZIP2.3":
+		options, err := r.section(checksumSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid options header: %w", err)
+		}
+		for i := 0; i+1 < len(options); i += 2 {
+			switch options[i] {
+			case 0: // checksum type
+				if options[i+1] == 0 {
+					checksumSize = 0
+				}
+			case 1: // checksum size
+				checksumSize = int(options[i+1])
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported eszip version %q", magic)
+	}
+
+	header, err := r.section(checksumSize)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modules header: %w", err)
+	}
+
+	type module struct {
+		specifier      string
+		offset, length uint32
+	}
+	var modules []module
+
+	h := &eszipReader{data: header}
+	for h.len() > 0 {
+		specifier, err := h.bytes()
+		if err != nil {
+			return nil, err
+		}
+		kind, err := h.byte()
+		if err != nil {
+			return nil, err
+		}
+		switch kind {
+		case eszipModule:
+			var fields [4]uint32
+			for i := range fields {
+				if fields[i], err = h.u32(); err != nil {
+					return nil, err
+				}
+			}
+			if _, err := h.byte(); err != nil { // module kind
+				return nil, err
+			}
+			modules = append(modules, module{string(specifier), fields[0], fields[1]})
+		case eszipRedirect:
+			if _, err := h.bytes(); err != nil {
+				return nil, err
+			}
+		case eszipNpm:
+			if _, err := h.u32(); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unknown eszip entry kind %d", kind)
+		}
+	}
+
+	if magic != "ESZIP_V2" {
+		// npm snapshot
+		if _, err := r.section(checksumSize); err != nil {
+			return nil, fmt.Errorf("invalid npm section: %w", err)
+		}
+	}
+
+	sourcesLen, err := r.u32()
+	if err != nil {
+		return nil, fmt.Errorf("invalid sources section: %w", err)
+	}
+	blob, err := r.next(int(sourcesLen))
+	if err != nil {
+		return nil, fmt.Errorf("invalid sources section: %w", err)
+	}
+
+	sources := make(map[string][]byte)
+	for _, m := range modules {
+		if !strings.HasPrefix(m.specifier, "file://") || m.length == 0 {
+			continue
+		}
+		end := uint64(m.offset) + uint64(m.length)
+		if end > uint64(len(blob)) {
+			return nil, fmt.Errorf("source for %s is out of range", m.specifier)
+		}
+		sources[m.specifier] = blob[m.offset:end]
+	}
+	return sources, nil
+}
+
+type eszipReader struct {
+	data []byte
+}
+
+func (r *eszipReader) len() int { return len(r.data) }
+
+func (r *eszipReader) next(n int) ([]byte, error) {
+	if n < 0 || n > len(r.data) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b, nil
+}
+
+func (r *eszipReader) byte() (byte, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *eszipReader) u32() (uint32, error) {
+	b, err := r.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+// bytes reads a u32 length-prefixed byte string
+func (r *eszipReader) bytes() ([]byte, error) {
+	n, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	return r.next(int(n))
+}
+
+// section reads a length-prefixed section followed by its checksum
+func (r *eszipReader) section(checksumSize int) ([]byte, error) {
+	content, err := r.bytes()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.next(checksumSize); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// =============================================================================
+// Paths
+// =============================================================================
+
+// placeFiles maps the paths in a downloaded body to the project. Paths in
+// the function's own directory or _shared under supabase/functions/ keep
+// their place; other supabase/ paths are dropped, so a body can't overwrite
+// another function, config, migrations or locks. Anything else is assumed to
+// be relative to the function's directory, after stripping the prefix the
+// remaining paths share (e.g. a temporary build directory). Cleaning each
+// path as absolute keeps ".." from escaping the project.
+func placeFiles(slug string, sources map[string][]byte) []File {
+	var files []File
+	var loose []string
+	cleaned := make(map[string]string)
+	owned := []string{
+		path.Join(filepath.ToSlash(Dir), slug) + "/",
+		path.Join(filepath.ToSlash(Dir), SharedDir) + "/",
+	}
+
+	for name := range sources {
+		p := name
+		if u, err := url.Parse(name); err == nil && u.Scheme == "file" {
+			p = u.Path
+		}
+		p = path.Clean("/" + strings.ReplaceAll(p, "\\", "/"))
+		cleaned[name] = p
+
+		switch i := strings.LastIndex(p, "/supabase/functions/"); {
+		case i >= 0:
+			if rel := p[i+1:]; strings.HasPrefix(rel, owned[0]) || strings.HasPrefix(rel, owned[1]) {
+				files = append(files, File{Name: rel, Content: sources[name]})
+			}
+		case strings.Contains(p, "/supabase/"):
+			// Not a function file
+		default:
+			loose = append(loose, name)
+		}
+	}
+
+	if len(loose) > 0 {
+		prefix := path.Dir(cleaned[loose[0]])
+		for _, name := range loose[1:] {
+			for !strings.HasPrefix(cleaned[name], strings.TrimSuffix(prefix, "/")+"/") {
+				prefix = path.Dir(prefix)
+			}
+		}
+		base := path.Join(filepath.ToSlash(Dir), slug)
+		for _, name := range loose {
+			rel := strings.TrimPrefix(cleaned[name], strings.TrimSuffix(prefix, "/")+"/")
+			files = append(files, File{Name: path.Join(base, rel), Content: sources[name]})
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+	return files
+}
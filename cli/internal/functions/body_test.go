@@ -0,0 +1,222 @@
+package functions
+
+import (
+	"bytes"
+	"encoding/binary"
+	"mime/multipart"
+	"net/textproto"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func fileNames(files []File) []string {
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+func TestUnpackMultipart(t *testing.T) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	w.WriteField("metadata", `{"entrypoint_path":"index.ts"}`)
+	for name, content := range map[string]string{
+		"/tmp/build/source/index.ts":  "serve()",
+		"/tmp/build/source/lib/db.ts": "export {}",
+	} {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", `form-data; name="file"; filename="`+name+`"`)
+		part, _ := w.CreatePart(h)
+		part.Write([]byte(content))
+	}
+	w.Close()
+
+	files, err := Unpack("hello", w.FormDataContentType(), body.Bytes())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	names := fileNames(files)
+	expected := []string{
+		"supabase/functions/hello/index.ts",
+		"supabase/functions/hello/lib/db.ts",
+	}
+	if len(names) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, names)
+			break
+		}
+	}
+}
+
+func TestUnpackMultipartProjectPaths(t *testing.T) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, name := range []string{
+		"file:///home/me/app/supabase/functions/hello/index.ts",
+		"file:///home/me/app/supabase/functions/_shared/cors.ts",
+		"../../../etc/passwd",
+		"file:///tmp/build/supabase/config.toml",
+		"supabase/migrations/20240101000000_init.sql",
+		"/home/me/app/supabase/migrations.lock",
+		"file:///home/me/app/supabase/functions/../.env",
+		"file:///home/me/app/supabase/functions/other/index.ts",
+		"file:///home/me/app/supabase/functions/hello-world/index.ts",
+		"file:///home/me/app/supabase/functions/import_map.json",
+	} {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", `form-data; name="file"; filename="`+name+`"`)
+		part, _ := w.CreatePart(h)
+		part.Write([]byte(name))
+	}
+	w.Close()
+
+	files, err := Unpack("hello", w.FormDataContentType(), body.Bytes())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Paths outside supabase/ can't escape the function directory, and
+	// nothing outside its own directory and _shared is written - not even
+	// a sibling function's files
+	names := fileNames(files)
+	expected := []string{
+		"supabase/functions/_shared/cors.ts",
+		"supabase/functions/hello/index.ts",
+		"supabase/functions/hello/passwd",
+	}
+	if len(names) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, names)
+			break
+		}
+	}
+}
+
+// buildEszip writes a minimal ESZIP2.2 archive with sha256-sized (zeroed)
+// checksums
+func buildEszip(modules map[string]string, remote string) []byte {
+	u32 := func(b *bytes.Buffer, n int) {
+		binary.Write(b, binary.BigEndian, uint32(n))
+	}
+	checksum := make([]byte, 32)
+
+	var header, sources bytes.Buffer
+	for specifier, source := range modules {
+		u32(&header, len(specifier))
+		header.WriteString(specifier)
+		header.WriteByte(eszipModule)
+		u32(&header, sources.Len())
+		u32(&header, len(source))
+		u32(&header, 0)
+		u32(&header, 0)
+		header.WriteByte(0)
+		sources.WriteString(source)
+		sources.Write(checksum)
+	}
+	u32(&header, len(remote))
+	header.WriteString(remote)
+	header.WriteByte(eszipRedirect)
+	u32(&header, len(remote))
+	header.WriteString(remote)
+
+	var out bytes.Buffer
+	out.WriteString("ESZIP2.2")
+	options := []byte{0, 1, 1, 32}
+	u32(&out, len(options))
+	out.Write(options)
+	out.Write(checksum)
+	u32(&out, header.Len())
+	out.Write(header.Bytes())
+	out.Write(checksum)
+	u32(&out, 0) // empty npm snapshot
+	out.Write(checksum)
+	u32(&out, sources.Len())
+	out.Write(sources.Bytes())
+	u32(&out, 0) // source maps
+	return out.Bytes()
+}
+
+func TestUnpackEszip(t *testing.T) {
+	archive := buildEszip(map[string]string{
+		"file:///tmp/user_fn/supabase/functions/hello/index.ts":  "serve()",
+		"file:///tmp/user_fn/supabase/functions/_shared/cors.ts": "export const cors = {}",
+	}, "https://deno.land/std/http/server.ts")
+
+	var compressed bytes.Buffer
+	compressed.WriteString("EZBR")
+	bw := brotli.NewWriter(&compressed)
+	bw.Write(archive)
+	bw.Close()
+
+	for name, body := range map[string][]byte{"plain": archive, "brotli": compressed.Bytes()} {
+		files, err := Unpack("hello", "application/octet-stream", body)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", name, err)
+		}
+		if len(files) != 2 {
+			t.Fatalf("%s: expected 2 files, got %v", name, fileNames(files))
+		}
+		if files[1].Name != "supabase/functions/hello/index.ts" || string(files[1].Content) != "serve()" {
+			t.Errorf("%s: unexpected file %s: %q", name, files[1].Name, files[1].Content)
+		}
+	}
+}
+
+func TestUnpackRejectsUnknownFormat(t *testing.T) {
+	if _, err := Unpack("hello", "application/octet-stream", []byte("not an archive")); err == nil {
+		t.Error("expected an error for an unknown body format")
+	}
+}
+
+func TestCompare3(t *testing.T) {
+	base := map[string][]byte{
+		"a.ts": []byte("a1"),
+		"b.ts": []byte("b1"),
+		"c.ts": []byte("c1"),
+		"d.ts": []byte("d1"),
+		"e.ts": []byte("e1"),
+	}
+	local := map[string][]byte{
+		"a.ts":   []byte("a1"), // unchanged
+		"b.ts":   []byte("b2"), // local edit
+		"c.ts":   []byte("c1"), // remote change
+		"d.ts":   []byte("d2"), // both
+		"e.ts":   []byte("e1"), // not shipped remotely
+		"new.ts": []byte("n"),  // new local file
+	}
+	remote := map[string][]byte{
+		"a.ts": []byte("a1"),
+		"b.ts": []byte("b1"),
+		"c.ts": []byte("c2"),
+		"d.ts": []byte("d3"),
+	}
+
+	changes := Compare3(base, local, remote)
+	expected := []FileChange{
+		{"b.ts", ChangedLocal},
+		{"c.ts", ChangedRemote},
+		{"d.ts", ChangedConflict},
+		{"new.ts", ChangedLocal},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, changes)
+			break
+		}
+	}
+	if !HasConflicts(changes) {
+		t.Error("expected a conflict")
+	}
+}
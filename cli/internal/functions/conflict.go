@@ -0,0 +1,78 @@
+package functions
+
+import (
+	"bytes"
+	"sort"
+)
+
+// Change states from a three-way comparison of a function's files
+const (
+	ChangedLocal    = "local"    // edited locally, unchanged remotely
+	ChangedRemote   = "remote"   // changed remotely, not edited locally
+	ChangedConflict = "conflict" // changed on both sides, differently
+)
+
+// FileChange is a file that differs between the local and remote copies of
+// a function
+type FileChange struct {
+	File  string `json:"file"`
+	State string `json:"state"`
+}
+
+// Compare3 compares the base (last committed), local (working tree) and
+// remote copies of a function's files, keyed by project-relative name. A
+// missing key means the file doesn't exist on that side. Files the remote
+// doesn't ship and that weren't edited locally are ignored, since a
+// deployment only carries the files it needs.
+func Compare3(base, local, remote map[string][]byte) []FileChange {
+	names := make(map[string]bool)
+	for _, m := range []map[string][]byte{base, local, remote} {
+		for name := range m {
+			names[name] = true
+		}
+	}
+
+	var changes []FileChange
+	for name := range names {
+		b, bok := base[name]
+		l, lok := local[name]
+		r, rok := remote[name]
+
+		if same(l, lok, r, rok) {
+			continue
+		}
+		localChanged := !same(b, bok, l, lok)
+		remoteChanged := !same(b, bok, r, rok)
+		if !rok && !localChanged {
+			continue
+		}
+
+		state := ChangedRemote
+		switch {
+		case localChanged && remoteChanged:
+			state = ChangedConflict
+		case localChanged:
+			state = ChangedLocal
+		}
+		changes = append(changes, FileChange{File: name, State: state})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].File < changes[j].File
+	})
+	return changes
+}
+
+// HasConflicts reports whether any file changed on both sides
+func HasConflicts(changes []FileChange) bool {
+	for _, c := range changes {
+		if c.State == ChangedConflict {
+			return true
+		}
+	}
+	return false
+}
+
+func same(a []byte, aok bool, b []byte, bok bool) bool {
+	return aok == bok && bytes.Equal(a, b)
+}
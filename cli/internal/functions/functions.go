@@ -0,0 +1,223 @@
+package functions
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Dir is the functions directory, relative to the project root
+var Dir = filepath.Join("supabase", "functions")
+
+// SharedDir holds code shared between functions. It's bundled with every
+// function and is never deployed on its own.
+const SharedDir = "_shared"
+
+// Config is the per-function configuration from [functions.<slug>] in
+// supabase/config.toml. Paths are relative to the supabase directory, as in
+// the Supabase CLI (e.g. "./functions/hello/index.ts").
+type Config struct {
+	VerifyJWT   *bool    `toml:"verify_jwt"`
+	Entrypoint  string   `toml:"entrypoint"`
+	ImportMap   string   `toml:"import_map"`
+	StaticFiles []string `toml:"static_files"`
+}
+
+// File is a bundled source file. Name is the slash-separated path relative
+// to the project root.
+type File struct {
+	Name    string
+	Content []byte
+}
+
+// Bundle is everything needed to deploy one function
+type Bundle struct {
+	Slug           string
+	Entrypoint     string // relative to the project root, slash-separated
+	ImportMap      string // relative to the project root, "" if none
+	VerifyJWT      bool
+	StaticPatterns []string
+	Files          []File
+}
+
+// List returns the slugs of deployable functions under supabase/functions:
+// directories with an index.ts, or with an entrypoint configured in cfg
+func List(root string, cfg map[string]Config) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(root, Dir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var slugs []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || strings.HasPrefix(name, ".") || name == SharedDir {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(root, entrypointPath(name, cfg[name]))); err == nil {
+			slugs = append(slugs, name)
+		}
+	}
+	sort.Strings(slugs)
+	return slugs, nil
+}
+
+// Build collects a function's files, the _shared directory and its import
+// map into a bundle
+func Build(root, slug string, cfg Config) (*Bundle, error) {
+	bundle := &Bundle{
+		Slug:           slug,
+		Entrypoint:     filepath.ToSlash(entrypointPath(slug, cfg)),
+		VerifyJWT:      true,
+		StaticPatterns: cfg.StaticFiles,
+	}
+	if cfg.VerifyJWT != nil {
+		bundle.VerifyJWT = *cfg.VerifyJWT
+	}
+
+	if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(bundle.Entrypoint))); err != nil {
+		return nil, fmt.Errorf("entrypoint for %s not found: %w", slug, err)
+	}
+
+	importMap, err := importMapPath(root, slug, cfg)
+	if err != nil {
+		return nil, err
+	}
+	bundle.ImportMap = filepath.ToSlash(importMap)
+
+	seen := make(map[string]bool)
+	add := func(rel string) error {
+		name := filepath.ToSlash(rel)
+		if seen[name] {
+			return nil
+		}
+		content, err := os.ReadFile(filepath.Join(root, rel))
+		if err != nil {
+			return err
+		}
+		seen[name] = true
+		bundle.Files = append(bundle.Files, File{Name: name, Content: content})
+		return nil
+	}
+
+	dirs := []string{filepath.Join(Dir, slug), filepath.Join(Dir, SharedDir)}
+	// An entrypoint outside the function directory brings its own directory
+	if entryDir := filepath.Dir(filepath.FromSlash(bundle.Entrypoint)); entryDir != dirs[0] {
+		dirs = append(dirs, entryDir)
+	}
+
+	for _, dir := range dirs {
+		err := filepath.WalkDir(filepath.Join(root, dir), func(p string, d fs.DirEntry, err error) error {
+			if os.IsNotExist(err) && p == filepath.Join(root, dir) {
+				return filepath.SkipDir
+			}
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if p != filepath.Join(root, dir) && skipDir(d.Name()) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasPrefix(d.Name(), ".") {
+				return nil
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			return add(rel)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to bundle %s: %w", slug, err)
+		}
+	}
+
+	if importMap != "" {
+		if err := add(importMap); err != nil {
+			return nil, fmt.Errorf("failed to read import map for %s: %w", slug, err)
+		}
+	}
+
+	sort.Slice(bundle.Files, func(i, j int) bool {
+		return bundle.Files[i].Name < bundle.Files[j].Name
+	})
+
+	return bundle, nil
+}
+
+// Hash returns a content hash over the bundle's files and deploy metadata,
+// so changing verify_jwt or the entrypoint also counts as a change
+func (b *Bundle) Hash() string {
+	h := sha256.New()
+
+	meta, _ := json.Marshal(struct {
+		Entrypoint     string   `json:"entrypoint"`
+		ImportMap      string   `json:"import_map"`
+		VerifyJWT      bool     `json:"verify_jwt"`
+		StaticPatterns []string `json:"static_patterns"`
+	}{b.Entrypoint, b.ImportMap, b.VerifyJWT, b.StaticPatterns})
+	h.Write(meta)
+	h.Write([]byte{0})
+
+	for _, f := range b.Files {
+		fmt.Fprintf(h, "%s\x00%d\x00", f.Name, len(f.Content))
+		h.Write(f.Content)
+	}
+
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// entrypointPath returns the entrypoint relative to the project root
+func entrypointPath(slug string, cfg Config) string {
+	if cfg.Entrypoint != "" {
+		return fromSupabaseDir(cfg.Entrypoint)
+	}
+	return filepath.Join(Dir, slug, "index.ts")
+}
+
+// importMapPath returns the configured import map, or the first of the
+// function's deno.json / deno.jsonc / import_map.json and the shared
+// supabase/functions/import_map.json that exists
+func importMapPath(root, slug string, cfg Config) (string, error) {
+	if cfg.ImportMap != "" {
+		p := fromSupabaseDir(cfg.ImportMap)
+		if _, err := os.Stat(filepath.Join(root, p)); err != nil {
+			return "", fmt.Errorf("import map for %s not found: %w", slug, err)
+		}
+		return p, nil
+	}
+
+	candidates := []string{
+		filepath.Join(Dir, slug, "deno.json"),
+		filepath.Join(Dir, slug, "deno.jsonc"),
+		filepath.Join(Dir, slug, "import_map.json"),
+		filepath.Join(Dir, "import_map.json"),
+	}
+	for _, p := range candidates {
+		if _, err := os.Stat(filepath.Join(root, p)); err == nil {
+			return p, nil
+		}
+	}
+	return "", nil
+}
+
+// fromSupabaseDir resolves a config path relative to supabase/
+func fromSupabaseDir(p string) string {
+	return filepath.Join("supabase", filepath.FromSlash(path.Clean(p)))
+}
+
+func skipDir(name string) bool {
+	return strings.HasPrefix(name, ".") || name == "node_modules"
+}
@@ -0,0 +1,147 @@
+package functions
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, root, rel, content string) {
+	t.Helper()
+	path := filepath.Join(root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", rel, err)
+	}
+}
+
+func TestList(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "supabase/functions/hello/index.ts", "")
+	writeFile(t, root, "supabase/functions/custom/main.ts", "")
+	writeFile(t, root, "supabase/functions/no-entrypoint/util.ts", "")
+	writeFile(t, root, "supabase/functions/_shared/cors.ts", "")
+
+	slugs, err := List(root, map[string]Config{
+		"custom": {Entrypoint: "./functions/custom/main.ts"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(slugs) != 2 || slugs[0] != "custom" || slugs[1] != "hello" {
+		t.Errorf("expected [custom hello], got %v", slugs)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "supabase/functions/hello/index.ts", "import { cors } from '../_shared/cors.ts'")
+	writeFile(t, root, "supabase/functions/hello/.env", "SECRET=1")
+	writeFile(t, root, "supabase/functions/hello/node_modules/x/index.js", "")
+	writeFile(t, root, "supabase/functions/_shared/cors.ts", "export const cors = {}")
+	writeFile(t, root, "supabase/functions/import_map.json", "{}")
+
+	verifyJWT := false
+	bundle, err := Build(root, "hello", Config{VerifyJWT: &verifyJWT})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if bundle.Entrypoint != "supabase/functions/hello/index.ts" {
+		t.Errorf("unexpected entrypoint %q", bundle.Entrypoint)
+	}
+	if bundle.ImportMap != "supabase/functions/import_map.json" {
+		t.Errorf("expected shared import map, got %q", bundle.ImportMap)
+	}
+	if bundle.VerifyJWT {
+		t.Error("expected verify_jwt from config")
+	}
+
+	var names []string
+	for _, f := range bundle.Files {
+		names = append(names, f.Name)
+	}
+	expected := []string{
+		"supabase/functions/_shared/cors.ts",
+		"supabase/functions/hello/index.ts",
+		"supabase/functions/import_map.json",
+	}
+	if len(names) != len(expected) {
+		t.Fatalf("expected files %v, got %v", expected, names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("expected files %v, got %v", expected, names)
+			break
+		}
+	}
+}
+
+func TestHashChangesWithSharedCode(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "supabase/functions/hello/index.ts", "")
+	writeFile(t, root, "supabase/functions/_shared/cors.ts", "v1")
+
+	before, err := Build(root, "hello", Config{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	writeFile(t, root, "supabase/functions/_shared/cors.ts", "v2")
+	after, err := Build(root, "hello", Config{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if before.Hash() == after.Hash() {
+		t.Error("expected editing _shared to change the hash")
+	}
+}
+
+func TestNeedsDeploy(t *testing.T) {
+	lock := &Lock{}
+	lock.Record("staging", "hello", Deployment{Hash: "h1", Version: 3})
+
+	tests := []struct {
+		ref      string
+		slug     string
+		hash     string
+		deployed int
+		want     string
+	}{
+		{"staging", "hello", "h1", 3, ""},
+		{"staging", "hello", "h2", 3, ReasonChanged},
+		{"staging", "hello", "h1", 4, ReasonUntracked},
+		{"staging", "other", "h1", 1, ReasonUntracked},
+		{"staging", "new", "h1", 0, ReasonNew},
+		// Another project at the same version number isn't up to date
+		{"production", "hello", "h1", 3, ReasonUntracked},
+	}
+
+	for _, tt := range tests {
+		if got := lock.NeedsDeploy(tt.ref, tt.slug, tt.hash, tt.deployed); got != tt.want {
+			t.Errorf("NeedsDeploy(%s, %s, %s, %d) = %q, want %q", tt.ref, tt.slug, tt.hash, tt.deployed, got, tt.want)
+		}
+	}
+}
+
+func TestLoadLockVersion1(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "supabase"), 0755); err != nil {
+		t.Fatalf("failed to create supabase dir: %v", err)
+	}
+	v1 := `{"version": 1, "functions": {"hello": {"hash": "h1", "version": 3}}}`
+	if err := os.WriteFile(filepath.Join(root, LockFile), []byte(v1), 0644); err != nil {
+		t.Fatalf("failed to write lock: %v", err)
+	}
+
+	lock, err := LoadLock(root)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if lock.Version != 2 || len(lock.Projects) != 0 {
+		t.Errorf("expected an empty version 2 lock, got %+v", lock)
+	}
+}
@@ -0,0 +1,107 @@
+package functions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LockFile records what was last deployed for each function, relative to
+// the project root
+var LockFile = filepath.Join("supabase", "functions.lock")
+
+// Lock records the last deployment of each function, per target: one
+// checkout deploys to several projects and preview branches, whose version
+// numbers are unrelated
+type Lock struct {
+	Version  int                              `json:"version"`
+	Projects map[string]map[string]Deployment `json:"projects"` // project ref -> slug -> deployment
+}
+
+// lockVersion is the current lock format. Version 1 keyed deployments by
+// slug alone, without saying which project they went to.
+const lockVersion = 2
+
+// Deployment is the bundle hash deployed as a given remote version
+type Deployment struct {
+	Hash       string `json:"hash"`
+	Version    int    `json:"version"`
+	EzbrSha256 string `json:"ezbr_sha256,omitempty"`
+}
+
+// LoadLock reads <root>/supabase/functions.lock. A missing file returns an
+// empty lock, as does a version 1 lock: its entries can't be matched to a
+// target, so functions are redeployed once and recorded again.
+func LoadLock(root string) (*Lock, error) {
+	lock := &Lock{Version: lockVersion, Projects: make(map[string]map[string]Deployment)}
+
+	data, err := os.ReadFile(filepath.Join(root, LockFile))
+	if os.IsNotExist(err) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", LockFile, err)
+	}
+
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", LockFile, err)
+	}
+	if lock.Version < lockVersion || lock.Projects == nil {
+		lock.Version = lockVersion
+		lock.Projects = make(map[string]map[string]Deployment)
+	}
+	return lock, nil
+}
+
+// Record stores a deployment of slug to the project ref
+func (l *Lock) Record(ref, slug string, d Deployment) {
+	if l.Projects == nil {
+		l.Projects = make(map[string]map[string]Deployment)
+	}
+	if l.Projects[ref] == nil {
+		l.Projects[ref] = make(map[string]Deployment)
+	}
+	l.Version = lockVersion
+	l.Projects[ref][slug] = d
+}
+
+// Save writes the lock to <root>/supabase/functions.lock
+func (l *Lock) Save(root string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	path := filepath.Join(root, LockFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Deploy reasons
+const (
+	ReasonNew       = "new"       // not deployed yet
+	ReasonChanged   = "changed"   // local content differs from the last deploy
+	ReasonUntracked = "untracked" // deployed, but not by us (or the lock is missing)
+)
+
+// NeedsDeploy compares a bundle hash with the lock entry for the project
+// ref and the version deployed there. deployedVersion is 0 if the function
+// doesn't exist remotely. Returns "" when the deployed function is up to
+// date.
+func (l *Lock) NeedsDeploy(ref, slug, hash string, deployedVersion int) string {
+	if deployedVersion == 0 {
+		return ReasonNew
+	}
+	last, ok := l.Projects[ref][slug]
+	if !ok || last.Version != deployedVersion {
+		return ReasonUntracked
+	}
+	if last.Hash != hash {
+		return ReasonChanged
+	}
+	return ""
+}
@@ -1,6 +1,7 @@
 package git
 
 import (
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -65,9 +66,49 @@ func GetRemoteURL(dir string) (string, error) {
 	return strings.TrimSpace(string(output)), nil
 }
 
-// HasUncommittedChanges checks if there are uncommitted changes
-func HasUncommittedChanges(dir string) (bool, error) {
-	cmd := exec.Command("git", "status", "--porcelain")
+// HasUncommittedChanges checks if there are uncommitted changes, optionally
+// limited to the given paths (relative to dir)
+func HasUncommittedChanges(dir string, paths ...string) (bool, error) {
+	args := []string{"status", "--porcelain"}
+	if len(paths) > 0 {
+		args = append(append(args, "--"), paths...)
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return false, err
+	}
+	return len(strings.TrimSpace(string(output))) > 0, nil
+}
+
+// ShowHead returns the content of a file at HEAD. path is relative to dir.
+// ok is false if the file isn't tracked at HEAD.
+func ShowHead(dir, path string) (content []byte, ok bool, err error) {
+	cmd := exec.Command("git", "show", "HEAD:./"+filepath.ToSlash(path))
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return output, true, nil
+}
+
+// BranchExists reports whether a local branch exists
+func BranchExists(dir, branch string) bool {
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	cmd.Dir = dir
+	return cmd.Run() == nil
+}
+
+// RemoteBranchExists reports whether a branch exists on a remote. This asks
+// the remote itself, so it fails when offline.
+func RemoteBranchExists(dir, remote, branch string) (bool, error) {
+	cmd := exec.Command("git", "ls-remote", "--heads", remote, "refs/heads/"+branch)
 	cmd.Dir = dir
 	output, err := cmd.Output()
 	if err != nil {
@@ -121,3 +121,86 @@ func TestGetCurrentBranchWithGit(t *testing.T) {
 		t.Errorf("expected branch 'test-branch', got '%s'", branch)
 	}
 }
+
+func TestHasUncommittedChangesInPaths(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	tmpDir := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = tmpDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	run("init")
+	run("config", "user.email", "test@test.com")
+	run("config", "user.name", "Test")
+
+	os.MkdirAll(filepath.Join(tmpDir, "a"), 0755)
+	os.MkdirAll(filepath.Join(tmpDir, "b"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "a", "file.txt"), []byte("v1"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "b", "file.txt"), []byte("v1"), 0644)
+	run("add", ".")
+	run("commit", "-m", "initial")
+
+	os.WriteFile(filepath.Join(tmpDir, "a", "file.txt"), []byte("v2"), 0644)
+
+	if dirty, err := HasUncommittedChanges(tmpDir, "b"); err != nil || dirty {
+		t.Errorf("expected b to be clean, got %v %v", dirty, err)
+	}
+	if dirty, err := HasUncommittedChanges(tmpDir, "a"); err != nil || !dirty {
+		t.Errorf("expected a to be dirty, got %v %v", dirty, err)
+	}
+
+	content, ok, err := ShowHead(tmpDir, filepath.Join("a", "file.txt"))
+	if err != nil || !ok || string(content) != "v1" {
+		t.Errorf("expected committed content v1, got %q %v %v", content, ok, err)
+	}
+	if _, ok, err := ShowHead(tmpDir, "missing.txt"); err != nil || ok {
+		t.Errorf("expected missing file to be reported as untracked, got %v %v", ok, err)
+	}
+}
+
+func TestBranchExists(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	origin := t.TempDir()
+	tmpDir := t.TempDir()
+	run := func(dir string, args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	run(origin, "init", "--bare")
+	run(tmpDir, "init")
+	run(tmpDir, "config", "user.email", "test@test.com")
+	run(tmpDir, "config", "user.name", "Test")
+	run(tmpDir, "commit", "--allow-empty", "-m", "initial")
+	run(tmpDir, "branch", "local-only")
+	run(tmpDir, "remote", "add", "origin", origin)
+	run(tmpDir, "push", "origin", "HEAD:refs/heads/pushed")
+
+	if !BranchExists(tmpDir, "local-only") {
+		t.Error("expected local-only to exist locally")
+	}
+	if BranchExists(tmpDir, "pushed") {
+		t.Error("expected pushed not to exist locally")
+	}
+
+	if ok, err := RemoteBranchExists(tmpDir, "origin", "pushed"); err != nil || !ok {
+		t.Errorf("expected pushed to exist on origin, got %v %v", ok, err)
+	}
+	if ok, err := RemoteBranchExists(tmpDir, "origin", "local-only"); err != nil || ok {
+		t.Errorf("expected local-only not to exist on origin, got %v %v", ok, err)
+	}
+	if _, err := RemoteBranchExists(tmpDir, "missing", "pushed"); err == nil {
+		t.Error("expected an error for an unknown remote")
+	}
+}
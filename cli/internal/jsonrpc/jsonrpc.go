@@ -0,0 +1,203 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Version is the only protocol version served
+const Version = "2.0"
+
+// Error codes defined by JSON-RPC 2.0
+const (
+	ParseError     = -32700
+	InvalidRequest = -32600
+	MethodNotFound = -32601
+	InvalidParams  = -32602
+	InternalError  = -32603
+)
+
+// Message is a request, notification or response. Requests have an ID and
+// a Method, notifications only a Method, responses an ID and a Result or
+// Error.
+type Message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// IsNotification reports whether a message expects no response
+func (m *Message) IsNotification() bool {
+	return m.ID == nil && m.Method != ""
+}
+
+// Error is a JSON-RPC error object. Handlers return it to choose the code.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.Code)
+}
+
+// Handler answers a request or notification. For notifications the result
+// is discarded.
+type Handler func(ctx context.Context, method string, params json.RawMessage) (any, error)
+
+// Conn is a JSON-RPC connection over a stream, with messages framed by
+// Content-Length headers as in the Language Server Protocol. Writes are
+// safe for concurrent use, so handlers can send notifications while a
+// request is being served.
+type Conn struct {
+	r  *bufio.Reader
+	w  io.Writer
+	mu sync.Mutex
+}
+
+// NewConn returns a connection reading from r and writing to w
+func NewConn(r io.Reader, w io.Writer) *Conn {
+	return &Conn{r: bufio.NewReader(r), w: w}
+}
+
+// Serve reads messages and answers them in order until the stream ends or
+// ctx is done. A batch is answered with one array once all of it is done.
+func (c *Conn) Serve(ctx context.Context, handler Handler) error {
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		body, err := c.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		trimmed := bytes.TrimSpace(body)
+		if len(trimmed) > 0 && trimmed[0] == '[' {
+			var batch []json.RawMessage
+			if err := json.Unmarshal(trimmed, &batch); err != nil || len(batch) == 0 {
+				c.reply(errorResponse(nil, &Error{Code: InvalidRequest, Message: "invalid batch"}))
+				continue
+			}
+			var responses []*Message
+			for _, raw := range batch {
+				if resp := c.handle(ctx, handler, raw); resp != nil {
+					responses = append(responses, resp)
+				}
+			}
+			if len(responses) > 0 {
+				c.reply(responses)
+			}
+			continue
+		}
+
+		if resp := c.handle(ctx, handler, trimmed); resp != nil {
+			c.reply(resp)
+		}
+	}
+}
+
+// Notify sends a notification
+func (c *Conn) Notify(method string, params any) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return c.reply(&Message{JSONRPC: Version, Method: method, Params: raw})
+}
+
+// handle answers one message, returning nil for notifications
+func (c *Conn) handle(ctx context.Context, handler Handler, raw json.RawMessage) *Message {
+	var msg Message
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return errorResponse(nil, &Error{Code: ParseError, Message: err.Error()})
+	}
+	if msg.JSONRPC != Version || msg.Method == "" {
+		if msg.Method == "" && (msg.Result != nil || msg.Error != nil) {
+			// A response to something we never send
+			return nil
+		}
+		return errorResponse(msg.ID, &Error{Code: InvalidRequest, Message: "not a JSON-RPC 2.0 request"})
+	}
+
+	result, err := handler(ctx, msg.Method, msg.Params)
+	if msg.IsNotification() {
+		return nil
+	}
+	if err != nil {
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &Error{Code: InternalError, Message: err.Error()}
+		}
+		return errorResponse(msg.ID, rpcErr)
+	}
+
+	out, err := json.Marshal(result)
+	if err != nil {
+		return errorResponse(msg.ID, &Error{Code: InternalError, Message: err.Error()})
+	}
+	return &Message{JSONRPC: Version, ID: msg.ID, Result: out}
+}
+
+func errorResponse(id json.RawMessage, err *Error) *Message {
+	if id == nil {
+		// The spec requires an id, null when it couldn't be read
+		id = json.RawMessage("null")
+	}
+	return &Message{JSONRPC: Version, ID: id, Error: err}
+}
+
+// read returns the body of the next message
+func (c *Conn) read() ([]byte, error) {
+	headers, err := textproto.NewReader(c.r).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) && len(headers) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("failed to read headers: %w", err)
+	}
+
+	length, err := strconv.Atoi(strings.TrimSpace(headers.Get("Content-Length")))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length %q", headers.Get("Content-Length"))
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+	return body, nil
+}
+
+// reply writes one message or batch
+func (c *Conn) reply(v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = c.w.Write(body)
+	return err
+}
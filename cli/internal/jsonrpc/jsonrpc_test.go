@@ -0,0 +1,112 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func frame(bodies ...string) string {
+	var b strings.Builder
+	for _, body := range bodies {
+		fmt.Fprintf(&b, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+	return b.String()
+}
+
+// responses reads back the messages a Conn wrote
+func responses(t *testing.T, out *bytes.Buffer) []json.RawMessage {
+	t.Helper()
+	conn := NewConn(out, nil)
+	var messages []json.RawMessage
+	for {
+		body, err := conn.read()
+		if err != nil {
+			break
+		}
+		messages = append(messages, body)
+	}
+	return messages
+}
+
+func echo(ctx context.Context, method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "echo":
+		return params, nil
+	case "fail":
+		return nil, &Error{Code: InvalidParams, Message: "bad params"}
+	case "crash":
+		return nil, fmt.Errorf("boom")
+	}
+	return nil, &Error{Code: MethodNotFound, Message: "method not found: " + method}
+}
+
+func TestServe(t *testing.T) {
+	in := frame(
+		`{"jsonrpc":"2.0","id":1,"method":"echo","params":{"a":1}}`,
+		`{"jsonrpc":"2.0","method":"echo","params":{}}`,
+		`{"jsonrpc":"2.0","id":"x","method":"fail"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"crash"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"nope"}`,
+		`{"id":5,"method":"echo"}`,
+	)
+	var out bytes.Buffer
+	if err := NewConn(strings.NewReader(in), &out).Serve(context.Background(), echo); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []string{
+		`{"jsonrpc":"2.0","id":1,"result":{"a":1}}`,
+		`{"jsonrpc":"2.0","id":"x","error":{"code":-32602,"message":"bad params"}}`,
+		`{"jsonrpc":"2.0","id":3,"error":{"code":-32603,"message":"boom"}}`,
+		`{"jsonrpc":"2.0","id":4,"error":{"code":-32601,"message":"method not found: nope"}}`,
+		`{"jsonrpc":"2.0","id":5,"error":{"code":-32600,"message":"not a JSON-RPC 2.0 request"}}`,
+	}
+	got := responses(t, &out)
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d responses, got %d: %s", len(expected), len(got), out.String())
+	}
+	for i := range expected {
+		if string(got[i]) != expected[i] {
+			t.Errorf("expected %s, got %s", expected[i], got[i])
+		}
+	}
+}
+
+func TestServeBatch(t *testing.T) {
+	in := frame(`[{"jsonrpc":"2.0","id":1,"method":"echo","params":[1]},{"jsonrpc":"2.0","method":"echo"},{"jsonrpc":"2.0","id":2,"method":"echo","params":[2]}]`)
+	var out bytes.Buffer
+	if err := NewConn(strings.NewReader(in), &out).Serve(context.Background(), echo); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	got := responses(t, &out)
+	expected := `[{"jsonrpc":"2.0","id":1,"result":[1]},{"jsonrpc":"2.0","id":2,"result":[2]}]`
+	if len(got) != 1 || string(got[0]) != expected {
+		t.Errorf("expected %s, got %s", expected, out.String())
+	}
+}
+
+func TestServeParseError(t *testing.T) {
+	var out bytes.Buffer
+	if err := NewConn(strings.NewReader(frame(`{not json`)), &out).Serve(context.Background(), echo); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := responses(t, &out); len(got) != 1 || !strings.Contains(string(got[0]), `"id":null,"error":{"code":-32700`) {
+		t.Errorf("expected a parse error, got %s", out.String())
+	}
+}
+
+func TestNotify(t *testing.T) {
+	var out bytes.Buffer
+	if err := NewConn(strings.NewReader(""), &out).Notify("watch/event", map[string]int{"seq": 1}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := frame(`{"jsonrpc":"2.0","method":"watch/event","params":{"seq":1}}`)
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
@@ -0,0 +1,420 @@
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+	"github.com/pganalyze/pg_query_go/v6/parser"
+)
+
+// Rule IDs
+const (
+	RuleDropTable             = "drop_table"
+	RuleDropColumn            = "drop_column"
+	RuleAlterColumnType       = "alter_column_type"
+	RuleIndexNotConcurrent    = "index_not_concurrent"
+	RuleNotNullWithoutDefault = "not_null_without_default"
+	RuleTableWithoutRLS       = "table_without_rls"
+
+	// RuleSyntaxError is a migration the parser rejects. It can't be
+	// configured: nothing else can be checked.
+	RuleSyntaxError = "syntax_error"
+)
+
+// Levels
+const (
+	LevelError   = "error"   // push refuses to apply the migration
+	LevelWarning = "warning" // reported, push goes ahead
+	LevelOff     = "off"
+)
+
+// DefaultLargeTableRows is the estimated row count from which building an
+// index without CONCURRENTLY is flagged
+const DefaultLargeTableRows = 100000
+
+// Rule is a check and the level it reports at unless a profile overrides it
+type Rule struct {
+	ID          string `json:"id"`
+	Level       string `json:"level"`
+	Description string `json:"description"`
+}
+
+// Rules lists every configurable rule
+var Rules = []Rule{
+	{RuleDropTable, LevelError, "DROP TABLE deletes the table and its data"},
+	{RuleDropColumn, LevelError, "DROP COLUMN deletes the column's data and breaks clients still selecting it"},
+	{RuleAlterColumnType, LevelWarning, "ALTER COLUMN ... TYPE rewrites the table under an exclusive lock, unless the types are binary compatible"},
+	{RuleIndexNotConcurrent, LevelWarning, "CREATE INDEX without CONCURRENTLY blocks writes to a large table while it builds"},
+	{RuleNotNullWithoutDefault, LevelError, "A NOT NULL or primary key column added without a default fails on a table with rows; SET NOT NULL scans the table under an exclusive lock"},
+	{RuleTableWithoutRLS, LevelError, "A table in an exposed schema without row level security can be read and written with the anon key"},
+}
+
+// Source is a migration to lint
+type Source struct {
+	File string // migration filename
+	SQL  string
+}
+
+// Finding is a risky statement. Line and Column are 1-based.
+type Finding struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Rule    string `json:"rule"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// Location returns "file:line:column"
+func (f Finding) Location() string {
+	return fmt.Sprintf("%s:%d:%d", f.File, f.Line, f.Column)
+}
+
+// Options configures a lint run
+type Options struct {
+	Levels         map[string]string // rule ID -> level, overriding the default
+	ExposedSchemas []string          // default: public
+	LargeTableRows int64             // default: DefaultLargeTableRows
+	TableRows      map[string]int64  // estimated rows by "schema.table", nil when unknown
+}
+
+func (o Options) validate() error {
+	for id, level := range o.Levels {
+		if !knownRule(id) {
+			return fmt.Errorf("unknown lint rule %q", id)
+		}
+		if level != LevelError && level != LevelWarning && level != LevelOff {
+			return fmt.Errorf("invalid level %q for lint rule %s: use error, warning or off", level, id)
+		}
+	}
+	return nil
+}
+
+func knownRule(id string) bool {
+	for _, r := range Rules {
+		if r.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (o Options) level(id string) string {
+	if level, ok := o.Levels[id]; ok {
+		return level
+	}
+	for _, r := range Rules {
+		if r.ID == id {
+			return r.Level
+		}
+	}
+	return LevelError
+}
+
+func (o Options) exposed(table string) bool {
+	schemas := o.ExposedSchemas
+	if len(schemas) == 0 {
+		schemas = []string{"public"}
+	}
+	schema, _, _ := strings.Cut(table, ".")
+	for _, s := range schemas {
+		if s == schema {
+			return true
+		}
+	}
+	return false
+}
+
+// Errors counts the findings at error level
+func Errors(findings []Finding) int {
+	n := 0
+	for _, f := range findings {
+		if f.Level == LevelError {
+			n++
+		}
+	}
+	return n
+}
+
+// FailedError is returned when findings at error level stop a push
+type FailedError struct {
+	Findings []Finding
+}
+
+func (e *FailedError) Error() string {
+	var first Finding
+	for _, f := range e.Findings {
+		if f.Level == LevelError {
+			first = f
+			break
+		}
+	}
+	return fmt.Sprintf("%d lint error(s), first at %s: %s", Errors(e.Findings), first.Location(), first.Message)
+}
+
+// ErrorCode returns the machine-readable code for --json output
+func (e *FailedError) ErrorCode() string {
+	return "lint_failed"
+}
+
+// linter carries what earlier statements did to later ones: tables created
+// in the same run are new and empty, so most rules don't apply to them
+type linter struct {
+	opts     Options
+	created  map[string]bool
+	rls      map[string]bool
+	creates  map[string]Finding // exposed tables created, where
+	findings []Finding
+}
+
+// Lint checks sources in the order they'd be applied
+func Lint(sources []Source, opts Options) ([]Finding, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if opts.LargeTableRows <= 0 {
+		opts.LargeTableRows = DefaultLargeTableRows
+	}
+
+	l := &linter{opts: opts, created: make(map[string]bool), rls: make(map[string]bool), creates: make(map[string]Finding)}
+	order := make(map[string]int, len(sources))
+	for i, source := range sources {
+		order[source.File] = i
+		l.source(source)
+	}
+
+	for table, at := range l.creates {
+		if !l.rls[table] {
+			l.add(at, RuleTableWithoutRLS, "%s is in an exposed schema without row level security - add 'alter table %s enable row level security' and policies", table, table)
+		}
+	}
+
+	sort.SliceStable(l.findings, func(i, j int) bool {
+		a, b := l.findings[i], l.findings[j]
+		if a.File != b.File {
+			return order[a.File] < order[b.File]
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return l.findings, nil
+}
+
+func (l *linter) source(source Source) {
+	tree, err := pg_query.Parse(source.SQL)
+	if err != nil {
+		at := Finding{File: source.File, Line: 1, Column: 1}
+		message := err.Error()
+		var pgErr *parser.Error
+		if errors.As(err, &pgErr) {
+			message = pgErr.Message
+			if pgErr.Cursorpos > 0 {
+				at = position(source, characterOffset(source.SQL, pgErr.Cursorpos))
+			}
+		}
+		at.Rule, at.Level, at.Message = RuleSyntaxError, LevelError, message
+		l.findings = append(l.findings, at)
+		return
+	}
+
+	for _, raw := range tree.GetStmts() {
+		l.statement(position(source, statementStart(source.SQL, int(raw.StmtLocation))), raw.GetStmt())
+	}
+}
+
+func (l *linter) statement(at Finding, stmt *pg_query.Node) {
+	if drop := stmt.GetDropStmt(); drop != nil && drop.RemoveType == pg_query.ObjectType_OBJECT_TABLE {
+		for _, object := range drop.Objects {
+			table := objectName(object)
+			if l.created[table] {
+				delete(l.created, table)
+				delete(l.creates, table)
+				continue
+			}
+			l.add(at, RuleDropTable, "drop table %s deletes the table and all its data", table)
+		}
+		return
+	}
+
+	if index := stmt.GetIndexStmt(); index != nil {
+		table := qualify(index.Relation)
+		if index.Concurrent || l.created[table] {
+			return
+		}
+		if l.opts.TableRows == nil {
+			l.add(at, RuleIndexNotConcurrent, "creating an index on %s without concurrently blocks writes to it while the index builds", table)
+		} else if rows := l.opts.TableRows[table]; rows >= l.opts.LargeTableRows {
+			l.add(at, RuleIndexNotConcurrent, "creating an index on %s (~%d rows) without concurrently blocks writes to it while the index builds", table, rows)
+		}
+		return
+	}
+
+	if create := stmt.GetCreateStmt(); create != nil {
+		if create.Relation.GetRelpersistence() == "t" {
+			return
+		}
+		table := qualify(create.Relation)
+		l.created[table] = true
+		if l.opts.exposed(table) {
+			l.creates[table] = at
+		}
+		return
+	}
+
+	alter := stmt.GetAlterTableStmt()
+	if alter == nil || alter.Objtype != pg_query.ObjectType_OBJECT_TABLE {
+		return
+	}
+	table := qualify(alter.Relation)
+	for _, node := range alter.Cmds {
+		cmd := node.GetAlterTableCmd()
+		if cmd == nil {
+			continue
+		}
+		switch cmd.Subtype {
+		case pg_query.AlterTableType_AT_EnableRowSecurity:
+			l.rls[table] = true
+		case pg_query.AlterTableType_AT_DisableRowSecurity:
+			l.rls[table] = false
+			if l.opts.exposed(table) {
+				l.add(at, RuleTableWithoutRLS, "disabling row level security on %s lets anyone with the anon key read and write it", table)
+			}
+		}
+		if l.created[table] {
+			continue
+		}
+
+		switch cmd.Subtype {
+		case pg_query.AlterTableType_AT_DropColumn:
+			l.add(at, RuleDropColumn, "dropping %s.%s deletes its data, and clients still selecting it will fail", table, cmd.Name)
+		case pg_query.AlterTableType_AT_AlterColumnType:
+			l.add(at, RuleAlterColumnType, "changing the type of %s.%s rewrites the table under an exclusive lock, unless the types are binary compatible (e.g. varchar to text)", table, cmd.Name)
+		case pg_query.AlterTableType_AT_SetNotNull:
+			l.add(at, RuleNotNullWithoutDefault, "set not null on %s.%s scans the table under an exclusive lock, and fails if any row is null", table, cmd.Name)
+		case pg_query.AlterTableType_AT_AddColumn:
+			column := cmd.Def.GetColumnDef()
+			if column == nil {
+				break
+			}
+			switch notNullWithoutDefault(column) {
+			case pg_query.ConstrType_CONSTR_PRIMARY:
+				l.add(at, RuleNotNullWithoutDefault, "adding %s.%s as the primary key without a default fails if the table has rows, since none of them has a key", table, column.Colname)
+			case pg_query.ConstrType_CONSTR_NOTNULL:
+				l.add(at, RuleNotNullWithoutDefault, "adding %s.%s as not null without a default fails if the table has rows", table, column.Colname)
+			}
+		}
+	}
+}
+
+func (l *linter) add(at Finding, rule, format string, args ...any) {
+	level := l.opts.level(rule)
+	if level == LevelOff {
+		return
+	}
+	at.Rule, at.Level, at.Message = rule, level, fmt.Sprintf(format, args...)
+	l.findings = append(l.findings, at)
+}
+
+// notNullWithoutDefault returns the constraint that makes a new column need
+// a value every existing row lacks: NOT NULL, or PRIMARY KEY, which implies
+// it. Undefined when the column has a default or may be null.
+func notNullWithoutDefault(column *pg_query.ColumnDef) pg_query.ConstrType {
+	var required pg_query.ConstrType
+	for _, node := range column.Constraints {
+		c := node.GetConstraint()
+		if c == nil {
+			continue
+		}
+		switch c.Contype {
+		case pg_query.ConstrType_CONSTR_PRIMARY:
+			required = c.Contype
+		case pg_query.ConstrType_CONSTR_NOTNULL:
+			if required == pg_query.ConstrType_CONSTR_TYPE_UNDEFINED {
+				required = c.Contype
+			}
+		case pg_query.ConstrType_CONSTR_DEFAULT, pg_query.ConstrType_CONSTR_IDENTITY, pg_query.ConstrType_CONSTR_GENERATED:
+			return pg_query.ConstrType_CONSTR_TYPE_UNDEFINED
+		}
+	}
+	return required
+}
+
+// qualify returns "schema.table", in public unless a schema is given
+func qualify(rv *pg_query.RangeVar) string {
+	schema := rv.GetSchemaname()
+	if schema == "" {
+		schema = "public"
+	}
+	return schema + "." + rv.GetRelname()
+}
+
+// objectName qualifies a dropped object's name list
+func objectName(object *pg_query.Node) string {
+	var parts []string
+	for _, item := range object.GetList().GetItems() {
+		parts = append(parts, item.GetString_().GetSval())
+	}
+	if len(parts) == 1 {
+		return "public." + parts[0]
+	}
+	if len(parts) > 2 {
+		parts = parts[len(parts)-2:] // drop the database name
+	}
+	return strings.Join(parts, ".")
+}
+
+// statementStart skips the whitespace and comments the parser includes at
+// the start of a statement
+func statementStart(sql string, offset int) int {
+	for offset < len(sql) {
+		switch {
+		case strings.ContainsRune(" \t\r\n", rune(sql[offset])):
+			offset++
+		case strings.HasPrefix(sql[offset:], "--"):
+			end := strings.IndexByte(sql[offset:], '\n')
+			if end < 0 {
+				return len(sql)
+			}
+			offset += end + 1
+		case strings.HasPrefix(sql[offset:], "/*"):
+			end := strings.Index(sql[offset+2:], "*/")
+			if end < 0 {
+				return len(sql)
+			}
+			offset += end + 4
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+// characterOffset converts a 1-based character position, as the parser
+// reports it, into a byte offset
+func characterOffset(sql string, cursor int) int {
+	offset := 0
+	for i := 1; i < cursor && offset < len(sql); i++ {
+		_, size := utf8.DecodeRuneInString(sql[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// position returns the 1-based line and column of a byte offset
+func position(source Source, offset int) Finding {
+	if offset > len(source.SQL) {
+		offset = len(source.SQL)
+	}
+	before := source.SQL[:offset]
+	line := strings.Count(before, "\n") + 1
+	if i := strings.LastIndexByte(before, '\n'); i >= 0 {
+		before = before[i+1:]
+	}
+	return Finding{File: source.File, Line: line, Column: utf8.RuneCountInString(before) + 1}
+}
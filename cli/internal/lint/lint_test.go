@@ -0,0 +1,180 @@
+package lint
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func rules(findings []Finding) []string {
+	var ids []string
+	for _, f := range findings {
+		ids = append(ids, f.Rule+"@"+f.File)
+	}
+	return ids
+}
+
+func expectRules(t *testing.T, findings []Finding, expected ...string) {
+	t.Helper()
+	got := rules(findings)
+	if len(got) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, got)
+			return
+		}
+	}
+}
+
+func TestLintRules(t *testing.T) {
+	sources := []Source{
+		{File: "1_drops.sql", SQL: "drop table old_logs;\nalter table todos drop column legacy;\n"},
+		{File: "2_changes.sql", SQL: `alter table todos alter column done type integer;
+alter table todos add column owner uuid not null;
+alter table todos add column rank integer not null default 0;
+alter table todos alter column title set not null;
+create index todos_owner on todos (owner);
+create index concurrently todos_rank on todos (rank);
+`},
+	}
+
+	findings, err := Lint(sources, Options{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expectRules(t, findings,
+		RuleDropTable+"@1_drops.sql",
+		RuleDropColumn+"@1_drops.sql",
+		RuleAlterColumnType+"@2_changes.sql",
+		RuleNotNullWithoutDefault+"@2_changes.sql",
+		RuleNotNullWithoutDefault+"@2_changes.sql",
+		RuleIndexNotConcurrent+"@2_changes.sql",
+	)
+
+	drop := findings[1]
+	if drop.Line != 2 || drop.Column != 1 || drop.Level != LevelError {
+		t.Errorf("expected an error at line 2, got %+v", drop)
+	}
+	if drop.Message != "dropping public.todos.legacy deletes its data, and clients still selecting it will fail" {
+		t.Errorf("unexpected message: %s", drop.Message)
+	}
+	if findings[2].Level != LevelWarning {
+		t.Errorf("expected alter_column_type to warn, got %s", findings[2].Level)
+	}
+	if findings[5].Line != 5 {
+		t.Errorf("expected the index at line 5, got %d", findings[5].Line)
+	}
+}
+
+func TestLintPrimaryKeyColumn(t *testing.T) {
+	sources := []Source{{File: "1.sql", SQL: `alter table todos add column id bigint primary key;
+alter table tags add column id bigint not null primary key;
+alter table notes add column id bigint primary key generated always as identity;
+alter table logs add column id uuid primary key default gen_random_uuid();`}}
+
+	findings, err := Lint(sources, Options{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expectRules(t, findings, RuleNotNullWithoutDefault+"@1.sql", RuleNotNullWithoutDefault+"@1.sql")
+	for _, f := range findings {
+		if !strings.Contains(f.Message, "as the primary key without a default") {
+			t.Errorf("expected a primary key message, got %s", f.Message)
+		}
+	}
+}
+
+func TestLintNewTables(t *testing.T) {
+	sources := []Source{
+		{File: "1_create.sql", SQL: "-- todos\ncreate table todos (id bigint primary key);\ncreate table private.jobs (id bigint);\ncreate table tags (id bigint);\ncreate table notes (id bigint);"},
+		{File: "2_alter.sql", SQL: `alter table todos add column owner uuid not null;
+create index todos_owner on todos (owner);
+alter table todos enable row level security;
+drop table notes;`},
+	}
+
+	findings, err := Lint(sources, Options{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// Only tags lacks RLS: todos enables it later, notes is dropped and
+	// private isn't exposed. Changes to tables created in the same run are
+	// safe.
+	expectRules(t, findings, RuleTableWithoutRLS+"@1_create.sql")
+	if findings[0].Line != 4 {
+		t.Errorf("expected the finding at line 4, got %d", findings[0].Line)
+	}
+
+	findings, _ = Lint(sources, Options{ExposedSchemas: []string{"public", "private"}})
+	expectRules(t, findings, RuleTableWithoutRLS+"@1_create.sql", RuleTableWithoutRLS+"@1_create.sql")
+}
+
+func TestLintTableRows(t *testing.T) {
+	sources := []Source{{File: "1_index.sql", SQL: "create index todos_owner on todos (owner);"}}
+
+	findings, _ := Lint(sources, Options{TableRows: map[string]int64{"public.todos": 500}})
+	expectRules(t, findings)
+
+	findings, _ = Lint(sources, Options{TableRows: map[string]int64{"public.todos": 500}, LargeTableRows: 100})
+	expectRules(t, findings, RuleIndexNotConcurrent+"@1_index.sql")
+	if findings[0].Message != "creating an index on public.todos (~500 rows) without concurrently blocks writes to it while the index builds" {
+		t.Errorf("unexpected message: %s", findings[0].Message)
+	}
+}
+
+func TestLintLevels(t *testing.T) {
+	sources := []Source{{File: "1.sql", SQL: "drop table a;\nalter table b drop column c;"}}
+
+	findings, err := Lint(sources, Options{Levels: map[string]string{RuleDropTable: LevelWarning, RuleDropColumn: LevelOff}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expectRules(t, findings, RuleDropTable+"@1.sql")
+	if findings[0].Level != LevelWarning || Errors(findings) != 0 {
+		t.Errorf("expected a warning, got %+v", findings[0])
+	}
+
+	if _, err := Lint(sources, Options{Levels: map[string]string{"drop_everything": LevelOff}}); err == nil {
+		t.Error("expected an error for an unknown rule")
+	}
+	if _, err := Lint(sources, Options{Levels: map[string]string{RuleDropTable: "fatal"}}); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
+
+func TestLintSyntaxError(t *testing.T) {
+	findings, err := Lint([]Source{{File: "1.sql", SQL: "select 1;\ncreate tabel foo ();"}}, Options{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expectRules(t, findings, RuleSyntaxError+"@1.sql")
+	if findings[0].Line != 2 || findings[0].Column != 8 {
+		t.Errorf("expected the error at 2:8, got %s", findings[0].Location())
+	}
+
+	failed := &FailedError{Findings: findings}
+	if failed.Error() != `1 lint error(s), first at 1.sql:2:8: syntax error at or near "tabel"` || failed.ErrorCode() != "lint_failed" {
+		t.Errorf("unexpected error: %v (%s)", failed, failed.ErrorCode())
+	}
+}
+
+// readOnly answers every read-only query with the same rows
+type readOnly string
+
+func (r readOnly) RunReadOnlyQuery(ctx context.Context, query string) (json.RawMessage, error) {
+	return json.RawMessage(r), nil
+}
+
+func TestTableRows(t *testing.T) {
+	db := readOnly(`[{"name": "public.todos", "rows": 1234}, {"name": "public.new", "rows": 0}]`)
+	rows, err := TableRows(context.Background(), db)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if rows["public.todos"] != 1234 || len(rows) != 2 {
+		t.Errorf("unexpected rows: %v", rows)
+	}
+}
@@ -0,0 +1,40 @@
+package lint
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Planner estimates; -1 (never analyzed) reads as 0
+const tableRowsQuery = `select n.nspname || '.' || c.relname as name, greatest(c.reltuples, 0)::float8 as rows
+from pg_class c join pg_namespace n on n.oid = c.relnamespace
+where c.relkind in ('r', 'p') and n.nspname not in ('pg_catalog', 'information_schema')`
+
+// ReadOnlyQuerier runs a query that can't change anything and returns its
+// rows as a JSON array of objects, e.g. a backend.Backend
+type ReadOnlyQuerier interface {
+	RunReadOnlyQuery(ctx context.Context, query string) (json.RawMessage, error)
+}
+
+// TableRows returns the estimated row count of every table on the target,
+// by "schema.table", for Options.TableRows
+func TableRows(ctx context.Context, db ReadOnlyQuerier) (map[string]int64, error) {
+	out, err := db.RunReadOnlyQuery(ctx, tableRowsQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	var tables []struct {
+		Name string  `json:"name"`
+		Rows float64 `json:"rows"`
+	}
+	if err := json.Unmarshal(out, &tables); err != nil {
+		return nil, err
+	}
+
+	rows := make(map[string]int64, len(tables))
+	for _, t := range tables {
+		rows[t.Name] = int64(t.Rows)
+	}
+	return rows, nil
+}
@@ -0,0 +1,269 @@
+package localdb
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/supabase/supabase-dx/cli/internal/api"
+	"github.com/supabase/supabase-dx/cli/internal/migrations"
+)
+
+// DB runs the database operations of push and pull directly against a
+// Postgres connection, for profiles with mode = "local". Migration history
+// is kept in supabase_migrations.schema_migrations, like the Supabase CLI
+// does, so a local stack started by `supabase start` works as-is.
+type DB struct {
+	conn *pgx.Conn
+}
+
+// Connect opens a connection to the database at url
+func Connect(ctx context.Context, url string) (*DB, error) {
+	conn, err := pgx.Connect(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return &DB{conn: conn}, nil
+}
+
+// Describe returns host:port/dbname for a connection string, leaving out
+// credentials so it can be shown
+func Describe(url string) string {
+	config, err := pgx.ParseConfig(url)
+	if err != nil {
+		return "local database"
+	}
+	return fmt.Sprintf("%s:%d/%s", config.Host, config.Port, config.Database)
+}
+
+// Close closes the connection
+func (db *DB) Close(ctx context.Context) error {
+	return db.conn.Close(ctx)
+}
+
+// QueryError is an error reported by Postgres. The message carries the
+// character position of the error, if any, so migrations.ErrorPosition can
+// point at it like it does for Management API errors.
+type QueryError struct {
+	Err *pgconn.PgError
+}
+
+func (e *QueryError) Error() string {
+	msg := fmt.Sprintf("ERROR: %s (SQLSTATE %s)", e.Err.Message, e.Err.Code)
+	if e.Err.Detail != "" {
+		msg += "\nDETAIL: " + e.Err.Detail
+	}
+	if e.Err.Position > 0 {
+		msg += fmt.Sprintf("\nat character %d", e.Err.Position)
+	}
+	return msg
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCode implements the coded error interface used by api.ErrorCode
+func (e *QueryError) ErrorCode() string {
+	return "sql_error"
+}
+
+func queryError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return &QueryError{Err: pgErr}
+	}
+	return err
+}
+
+// =============================================================================
+// Migrations
+// =============================================================================
+
+const createMigrationsTable = `
+create schema if not exists supabase_migrations;
+create table if not exists supabase_migrations.schema_migrations (
+	version text not null primary key,
+	statements text[],
+	name text
+);`
+
+// ListMigrations returns the applied migrations, oldest first. A database
+// that never had migrations applied has none.
+func (db *DB) ListMigrations(ctx context.Context) ([]api.Migration, error) {
+	var exists bool
+	err := db.conn.QueryRow(ctx, "select to_regclass('supabase_migrations.schema_migrations') is not null").Scan(&exists)
+	if err != nil {
+		return nil, queryError(err)
+	}
+	if !exists {
+		return []api.Migration{}, nil
+	}
+
+	rows, err := db.conn.Query(ctx, "select version, coalesce(name, '') from supabase_migrations.schema_migrations order by version")
+	if err != nil {
+		return nil, queryError(err)
+	}
+	applied, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (api.Migration, error) {
+		var m api.Migration
+		err := row.Scan(&m.Version, &m.Name)
+		return m, err
+	})
+	if err != nil {
+		return nil, queryError(err)
+	}
+	return applied, nil
+}
+
+// GetMigration returns an applied migration with its statements
+func (db *DB) GetMigration(ctx context.Context, version string) (*api.MigrationDetail, error) {
+	m := &api.MigrationDetail{}
+	err := db.conn.QueryRow(ctx,
+		"select version, coalesce(name, ''), coalesce(statements, '{}') from supabase_migrations.schema_migrations where version = $1",
+		version).Scan(&m.Version, &m.Name, &m.Statements)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("migration %s not found", version)
+	}
+	if err != nil {
+		return nil, queryError(err)
+	}
+	return m, nil
+}
+
+// ApplyMigration runs a migration and records it in the history, in one
+// transaction. Names in the "<version>_<name>" form pushes use are recorded
+// under that version; anything else gets the current time, as the
+// Management API does.
+func (db *DB) ApplyMigration(ctx context.Context, req api.ApplyMigrationRequest) error {
+	version, name, ok := migrations.ParseFilename(req.Name + ".sql")
+	if !ok {
+		version = time.Now().UTC().Format("20060102150405")
+		name = req.Name
+	}
+
+	tx, err := db.conn.Begin(ctx)
+	if err != nil {
+		return queryError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, createMigrationsTable); err != nil {
+		return queryError(err)
+	}
+	// Exec without arguments uses the simple protocol, which allows a
+	// migration to hold several statements
+	if _, err := tx.Exec(ctx, req.Query); err != nil {
+		return queryError(err)
+	}
+	_, err = tx.Exec(ctx,
+		"insert into supabase_migrations.schema_migrations (version, statements, name) values ($1, $2, $3)",
+		version, []string{req.Query}, name)
+	if err != nil {
+		return queryError(err)
+	}
+
+	return queryError(tx.Commit(ctx))
+}
+
+// =============================================================================
+// Database Queries
+// =============================================================================
+
+// RunQuery runs a SQL query and returns its rows as a JSON array of
+// objects, the same shape the Management API's query endpoint returns
+func (db *DB) RunQuery(ctx context.Context, query string) (json.RawMessage, error) {
+	rows, err := db.conn.Query(ctx, query, pgx.QueryExecModeSimpleProtocol)
+	if err != nil {
+		return nil, queryError(err)
+	}
+	defer rows.Close()
+
+	fields := rows.FieldDescriptions()
+	result := []map[string]any{}
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return nil, queryError(err)
+		}
+		row := make(map[string]any, len(fields))
+		for i, f := range fields {
+			row[f.Name] = jsonValue(values[i])
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(err)
+	}
+
+	out, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RunReadOnlyQuery is RunQuery in a read-only transaction
+func (db *DB) RunReadOnlyQuery(ctx context.Context, query string) (json.RawMessage, error) {
+	tx, err := db.conn.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, queryError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	return db.RunQuery(ctx, query)
+}
+
+// Exec runs one or more SQL statements, discarding any rows
+func (db *DB) Exec(ctx context.Context, sql string) error {
+	_, err := db.conn.Exec(ctx, sql)
+	return queryError(err)
+}
+
+// Scratch creates an empty database on db's server, passes fn a connection
+// to it and drops it afterwards, so nothing fn runs - even a commit - can
+// reach the database db is connected to. Needs the CREATEDB privilege.
+func (db *DB) Scratch(ctx context.Context, fn func(scratch *DB) error) error {
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := "supa_shadow_" + hex.EncodeToString(suffix)
+	ident := pgx.Identifier{name}.Sanitize()
+
+	if _, err := db.conn.Exec(ctx, "create database "+ident); err != nil {
+		return queryError(err)
+	}
+	defer db.conn.Exec(context.Background(), "drop database if exists "+ident+" with (force)")
+
+	config := db.conn.Config().Copy()
+	config.Database = name
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return err
+	}
+	scratch := &DB{conn: conn}
+	defer scratch.Close(context.Background())
+
+	return fn(scratch)
+}
+
+// jsonValue converts a decoded column value into something that marshals
+// the way the Management API renders it
+func jsonValue(v any) any {
+	switch v := v.(type) {
+	case [16]byte:
+		// uuid
+		return fmt.Sprintf("%x-%x-%x-%x-%x", v[0:4], v[4:6], v[6:8], v[8:10], v[10:16])
+	case json.Marshaler:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	}
+	return v
+}
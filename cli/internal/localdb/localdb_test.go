@@ -0,0 +1,191 @@
+package localdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/supabase/supabase-dx/cli/internal/api"
+	"github.com/supabase/supabase-dx/cli/internal/migrations"
+)
+
+// testDB creates a throwaway database on the server at
+// SUPA_TEST_DATABASE_URL and drops it when the test ends
+func testDB(t *testing.T) *DB {
+	url := os.Getenv("SUPA_TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("SUPA_TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	admin, err := pgx.Connect(ctx, url)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	name := fmt.Sprintf("supa_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(ctx, "create database "+name); err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	config, err := pgx.ParseConfig(url)
+	if err != nil {
+		t.Fatalf("failed to parse url: %v", err)
+	}
+	config.Database = name
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		t.Fatalf("failed to connect to %s: %v", name, err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close(ctx)
+		admin.Exec(ctx, "drop database "+name)
+		admin.Close(ctx)
+	})
+	return &DB{conn: conn}
+}
+
+func TestMigrations(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	applied, err := db.ListMigrations(ctx)
+	if err != nil || len(applied) != 0 {
+		t.Fatalf("expected no migrations, got %v %v", applied, err)
+	}
+
+	err = db.ApplyMigration(ctx, api.ApplyMigrationRequest{
+		Name:  "20240101000000_create_todos",
+		Query: "create table todos (id bigint primary key);\ncreate index on todos (id);",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	applied, err = db.ListMigrations(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(applied) != 1 || applied[0].Version != "20240101000000" || applied[0].Name != "create_todos" {
+		t.Errorf("unexpected history %v", applied)
+	}
+
+	detail, err := db.GetMigration(ctx, "20240101000000")
+	if err != nil || len(detail.Statements) != 1 || !strings.HasPrefix(detail.Statements[0], "create table todos") {
+		t.Errorf("unexpected migration %+v %v", detail, err)
+	}
+
+	// A failing migration leaves nothing behind and reports where it failed
+	sql := "create table notes (id bigint);\nselect * from missing;"
+	err = db.ApplyMigration(ctx, api.ApplyMigrationRequest{Name: "20240102000000_broken", Query: sql})
+	var queryErr *QueryError
+	if !errors.As(err, &queryErr) {
+		t.Fatalf("expected a QueryError, got %v", err)
+	}
+	if pos, ok := migrations.ErrorPosition(sql, err.Error()); !ok || pos.Line != 2 {
+		t.Errorf("expected the error on line 2, got %v %v", pos, ok)
+	}
+	if applied, _ := db.ListMigrations(ctx); len(applied) != 1 {
+		t.Errorf("expected the failed migration not to be recorded, got %v", applied)
+	}
+	if _, err := db.RunQuery(ctx, "select * from notes"); err == nil {
+		t.Error("expected the failed migration to be rolled back")
+	}
+}
+
+func TestRunQuery(t *testing.T) {
+	db := testDB(t)
+
+	out, err := db.RunQuery(context.Background(), "select 1 as n, 'a' as s, '00000000-0000-0000-0000-000000000001'::uuid as id")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var rows []map[string]any
+	if err := json.Unmarshal(out, &rows); err != nil {
+		t.Fatalf("expected JSON rows, got %s", out)
+	}
+	if len(rows) != 1 || rows[0]["n"] != float64(1) || rows[0]["s"] != "a" || rows[0]["id"] != "00000000-0000-0000-0000-000000000001" {
+		t.Errorf("unexpected rows %s", out)
+	}
+}
+
+func TestGetTypescriptTypes(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	err := db.ApplyMigration(ctx, api.ApplyMigrationRequest{
+		Name: "20240101000000_init",
+		Query: `create type status as enum ('open', 'done');
+create table todos (id bigint generated always as identity primary key, title text not null, status status not null default 'open');`,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	types, err := db.GetTypescriptTypes(ctx, "public")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, expected := range []string{"id?: never", "title: string", `status?: Database["public"]["Enums"]["status"]`, `status: "open" | "done"`} {
+		if !strings.Contains(types.Types, expected) {
+			t.Errorf("expected types to contain %q, got:\n%s", expected, types.Types)
+		}
+	}
+}
+
+func TestScratch(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	if err := db.Exec(ctx, "create table kept (id int)"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	err := db.Scratch(ctx, func(scratch *DB) error {
+		out, err := scratch.RunQuery(ctx, "select count(*) as n from pg_tables where schemaname = 'public'")
+		if err != nil {
+			return err
+		}
+		if string(out) != `[{"n":0}]` {
+			t.Errorf("expected an empty public schema, got %s", out)
+		}
+		// Committing must not reach db
+		return scratch.Exec(ctx, "begin; drop table if exists kept; create table shadowed (id int); commit;")
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	out, err := db.RunQuery(ctx, "select tablename from pg_tables where schemaname = 'public'")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if string(out) != `[{"tablename":"kept"}]` {
+		t.Errorf("expected the database to be untouched, got %s", out)
+	}
+
+	out, err = db.RunQuery(ctx, "select count(*) as n from pg_database where datname like 'supa_shadow_%'")
+	if err != nil || string(out) != `[{"n":0}]` {
+		t.Errorf("expected the scratch database to be dropped, got %s %v", out, err)
+	}
+}
+
+func TestRunReadOnlyQuery(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	if _, err := db.RunReadOnlyQuery(ctx, "create table t (id int)"); err == nil {
+		t.Error("expected a read-only transaction to refuse DDL")
+	}
+	out, err := db.RunReadOnlyQuery(ctx, "select 1 as n")
+	if err != nil || string(out) != `[{"n":1}]` {
+		t.Errorf("expected one row, got %s %v", out, err)
+	}
+}
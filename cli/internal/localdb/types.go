@@ -0,0 +1,273 @@
+package localdb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/supabase/supabase-dx/cli/internal/api"
+)
+
+// Column is a table or view column, as introspected for type generation
+type Column struct {
+	Schema     string
+	Table      string
+	View       bool
+	Name       string
+	Type       string // element type of arrays, base type of domains
+	TypeSchema string
+	Enum       bool
+	Array      bool
+	Nullable   bool
+	HasDefault bool // defaults and identities may be omitted on insert
+	ReadOnly   bool // generated columns and identities that are always generated
+}
+
+// Enum is an enum type and its labels in sort order
+type Enum struct {
+	Schema string
+	Name   string
+	Values []string
+}
+
+const columnsQuery = `
+select n.nspname, c.relname, c.relkind in ('v', 'm'), a.attname,
+	bt.typname, btn.nspname, bt.typtype = 'e', t.typcategory = 'A',
+	not a.attnotnull, a.atthasdef or a.attidentity in ('a', 'd'), a.attidentity = 'a' or a.attgenerated = 's'
+from pg_attribute a
+join pg_class c on c.oid = a.attrelid
+join pg_namespace n on n.oid = c.relnamespace
+join pg_type t on t.oid = a.atttypid
+join pg_type bt on bt.oid = case
+	when t.typcategory = 'A' then t.typelem
+	when t.typtype = 'd' then t.typbasetype
+	else t.oid end
+join pg_namespace btn on btn.oid = bt.typnamespace
+where n.nspname = any($1)
+	and c.relkind in ('r', 'p', 'v', 'm', 'f')
+	and a.attnum > 0 and not a.attisdropped
+order by n.nspname, c.relname, a.attnum`
+
+const enumsQuery = `
+select n.nspname, t.typname, array_agg(e.enumlabel order by e.enumsortorder)
+from pg_enum e
+join pg_type t on t.oid = e.enumtypid
+join pg_namespace n on n.oid = t.typnamespace
+where n.nspname = any($1)
+group by n.nspname, t.typname
+order by n.nspname, t.typname`
+
+// GetTypescriptTypes generates TypeScript types for the tables, views and
+// enums of a comma-separated list of schemas, in the shape of
+// `supabase gen types typescript`
+func (db *DB) GetTypescriptTypes(ctx context.Context, schemas string) (*api.TypescriptResponse, error) {
+	var names []string
+	for _, s := range strings.Split(schemas, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			names = append(names, s)
+		}
+	}
+	if len(names) == 0 {
+		names = []string{"public"}
+	}
+
+	rows, err := db.conn.Query(ctx, columnsQuery, names)
+	if err != nil {
+		return nil, queryError(err)
+	}
+	columns, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Column, error) {
+		var c Column
+		err := row.Scan(&c.Schema, &c.Table, &c.View, &c.Name, &c.Type, &c.TypeSchema,
+			&c.Enum, &c.Array, &c.Nullable, &c.HasDefault, &c.ReadOnly)
+		return c, err
+	})
+	if err != nil {
+		return nil, queryError(err)
+	}
+
+	rows, err = db.conn.Query(ctx, enumsQuery, names)
+	if err != nil {
+		return nil, queryError(err)
+	}
+	enums, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Enum, error) {
+		var e Enum
+		err := row.Scan(&e.Schema, &e.Name, &e.Values)
+		return e, err
+	})
+	if err != nil {
+		return nil, queryError(err)
+	}
+
+	return &api.TypescriptResponse{Types: RenderTypes(names, columns, enums)}, nil
+}
+
+// RenderTypes renders the Database type for the given schemas. Columns must
+// be ordered by schema, table and position.
+func RenderTypes(schemas []string, columns []Column, enums []Enum) string {
+	included := make(map[string]bool)
+	for _, s := range schemas {
+		included[s] = true
+	}
+
+	w := &typesWriter{}
+	w.line(0, "export type Json =")
+	w.line(1, "| string")
+	w.line(1, "| number")
+	w.line(1, "| boolean")
+	w.line(1, "| null")
+	w.line(1, "| { [key: string]: Json | undefined }")
+	w.line(1, "| Json[]")
+	w.line(0, "")
+	w.line(0, "export type Database = {")
+	for _, schema := range schemas {
+		w.line(1, "%s: {", schema)
+
+		var tables, views [][]Column
+		for _, c := range columns {
+			if c.Schema != schema {
+				continue
+			}
+			group := &tables
+			if c.View {
+				group = &views
+			}
+			if n := len(*group); n > 0 && (*group)[n-1][0].Table == c.Table {
+				(*group)[n-1] = append((*group)[n-1], c)
+			} else {
+				*group = append(*group, []Column{c})
+			}
+		}
+
+		w.line(2, "Tables: {")
+		if len(tables) == 0 {
+			w.line(3, "[_ in never]: never")
+		}
+		for _, table := range tables {
+			w.line(3, "%s: {", table[0].Table)
+			w.columns(4, "Row", table, included, func(c Column) string { return ": " })
+			w.columns(4, "Insert", table, included, func(c Column) string {
+				if c.ReadOnly {
+					return "?: never"
+				}
+				if c.Nullable || c.HasDefault {
+					return "?: "
+				}
+				return ": "
+			})
+			w.columns(4, "Update", table, included, func(c Column) string {
+				if c.ReadOnly {
+					return "?: never"
+				}
+				return "?: "
+			})
+			w.line(4, "Relationships: []")
+			w.line(3, "}")
+		}
+		w.line(2, "}")
+
+		w.line(2, "Views: {")
+		if len(views) == 0 {
+			w.line(3, "[_ in never]: never")
+		}
+		for _, view := range views {
+			w.line(3, "%s: {", view[0].Table)
+			w.columns(4, "Row", view, included, func(c Column) string { return ": " })
+			w.line(4, "Relationships: []")
+			w.line(3, "}")
+		}
+		w.line(2, "}")
+
+		w.line(2, "Functions: {")
+		w.line(3, "[_ in never]: never")
+		w.line(2, "}")
+
+		w.line(2, "Enums: {")
+		found := false
+		for _, e := range enums {
+			if e.Schema != schema {
+				continue
+			}
+			found = true
+			labels := make([]string, len(e.Values))
+			for i, v := range e.Values {
+				labels[i] = fmt.Sprintf("%q", v)
+			}
+			w.line(3, "%s: %s", e.Name, strings.Join(labels, " | "))
+		}
+		if !found {
+			w.line(3, "[_ in never]: never")
+		}
+		w.line(2, "}")
+
+		w.line(2, "CompositeTypes: {")
+		w.line(3, "[_ in never]: never")
+		w.line(2, "}")
+		w.line(1, "}")
+	}
+	w.line(0, "}")
+
+	return w.String()
+}
+
+type typesWriter struct {
+	strings.Builder
+}
+
+func (w *typesWriter) line(indent int, format string, args ...any) {
+	if format != "" {
+		w.WriteString(strings.Repeat("  ", indent))
+		fmt.Fprintf(w, format, args...)
+	}
+	w.WriteString("\n")
+}
+
+// columns writes one of a table's Row, Insert or Update objects. sep returns
+// what goes between a column's name and its type ("?: never" replaces the
+// type entirely).
+func (w *typesWriter) columns(indent int, name string, columns []Column, included map[string]bool, sep func(Column) string) {
+	w.line(indent, "%s: {", name)
+	for _, c := range columns {
+		s := sep(c)
+		if strings.HasSuffix(s, "never") {
+			w.line(indent+1, "%s%s", c.Name, s)
+			continue
+		}
+		t := tsType(c, included)
+		if c.Nullable {
+			t += " | null"
+		}
+		w.line(indent+1, "%s%s%s", c.Name, s, t)
+	}
+	w.line(indent, "}")
+}
+
+// tsType maps a column's Postgres type to TypeScript
+func tsType(c Column, included map[string]bool) string {
+	var t string
+	switch {
+	case c.Enum && included[c.TypeSchema]:
+		t = fmt.Sprintf("Database[%q][\"Enums\"][%q]", c.TypeSchema, c.Type)
+	case c.Enum:
+		t = "string"
+	default:
+		switch c.Type {
+		case "bool":
+			t = "boolean"
+		case "int2", "int4", "int8", "float4", "float8", "numeric", "oid":
+			t = "number"
+		case "json", "jsonb":
+			t = "Json"
+		case "text", "varchar", "bpchar", "char", "name", "citext", "uuid",
+			"date", "time", "timetz", "timestamp", "timestamptz", "interval",
+			"bytea", "inet", "cidr", "macaddr", "money", "tsvector", "xml", "bit", "varbit":
+			t = "string"
+		default:
+			t = "unknown"
+		}
+	}
+	if c.Array {
+		t += "[]"
+	}
+	return t
+}
@@ -0,0 +1,233 @@
+package lsp
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/supabase/supabase-dx/cli/internal/api"
+	"github.com/supabase/supabase-dx/cli/internal/declarative"
+	"github.com/supabase/supabase-dx/cli/internal/migrations"
+)
+
+// Position is a 0-based line and UTF-16 character offset, as LSP counts them
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+type Severity int
+
+const (
+	SeverityError   Severity = 1
+	SeverityWarning Severity = 2
+)
+
+// Diagnostic codes
+const (
+	CodeInvalidFilename  = "invalid_filename"
+	CodeInvalidVersion   = "invalid_version"
+	CodeDuplicateVersion = "duplicate_version"
+	CodeOutOfOrder       = "out_of_order"
+	CodeSyntaxError      = "syntax_error"
+)
+
+type Diagnostic struct {
+	Range    Range    `json:"range"`
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+// Document is a SQL file and its current text
+type Document struct {
+	Path string // relative to the project root
+	Text string
+}
+
+// IsMigration reports whether a path is a file in supabase/migrations
+func IsMigration(path string) bool {
+	return filepath.Dir(path) == migrations.Dir && strings.HasSuffix(path, ".sql")
+}
+
+// IsSchema reports whether a path is a file under supabase/schemas
+func IsSchema(path string) bool {
+	return strings.HasPrefix(path, declarative.Dir+string(filepath.Separator)) && strings.HasSuffix(path, ".sql")
+}
+
+// Check returns the diagnostics of every document, with an entry (possibly
+// empty) for each so stale diagnostics get cleared. remote is the applied
+// migration history, or nil when it isn't known; migrations older than the
+// latest applied one are then not flagged.
+func Check(docs []Document, remote []api.Migration) map[string][]Diagnostic {
+	return check(docs, remote, CheckSQL)
+}
+
+func check(docs []Document, remote []api.Migration, checkSQL func(Document) []Diagnostic) map[string][]Diagnostic {
+	result := make(map[string][]Diagnostic, len(docs))
+	for _, doc := range docs {
+		result[doc.Path] = checkSQL(doc)
+	}
+
+	for path, diagnostics := range checkMigrations(docs, remote) {
+		result[path] = append(diagnostics, result[path]...)
+	}
+	return result
+}
+
+// CheckSQL returns a document's syntax error, if any
+func CheckSQL(doc Document) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	message, cursor := syntaxError(doc.Text)
+	if message == "" {
+		return diagnostics
+	}
+
+	r := Range{Start: offsetPosition(doc.Text, len(doc.Text))}
+	r.End = r.Start
+	if cursor > 0 {
+		start := characterOffset(doc.Text, cursor)
+		r = Range{Start: offsetPosition(doc.Text, start), End: offsetPosition(doc.Text, tokenEnd(doc.Text, start))}
+	}
+	return append(diagnostics, Diagnostic{
+		Range:    r,
+		Severity: SeverityError,
+		Code:     CodeSyntaxError,
+		Source:   "supa",
+		Message:  message,
+	})
+}
+
+// checkMigrations flags migration filenames push would reject or apply in
+// an unexpected order
+func checkMigrations(docs []Document, remote []api.Migration) map[string][]Diagnostic {
+	result := make(map[string][]Diagnostic)
+	flag := func(doc Document, severity Severity, code, format string, args ...any) {
+		result[doc.Path] = append(result[doc.Path], Diagnostic{
+			Range:    firstLine(doc.Text),
+			Severity: severity,
+			Code:     code,
+			Source:   "supa",
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	var files []migrations.File
+	docsByFile := make(map[string]Document)
+	byVersion := make(map[string][]string)
+	for _, doc := range docs {
+		if !IsMigration(doc.Path) {
+			continue
+		}
+		filename := filepath.Base(doc.Path)
+		version, name, ok := migrations.ParseFilename(filename)
+		if !ok {
+			flag(doc, SeverityError, CodeInvalidFilename,
+				"%s is not a migration: filenames start with a version, e.g. 20240101120000_create_users.sql", filename)
+			continue
+		}
+		if _, err := time.Parse("20060102150405", version); err != nil {
+			flag(doc, SeverityWarning, CodeInvalidVersion,
+				"version %s is not a YYYYMMDDHHMMSS timestamp; migrations are applied in version order", version)
+		}
+
+		files = append(files, migrations.File{Filename: filename, Version: version, Name: name})
+		docsByFile[filename] = doc
+		byVersion[version] = append(byVersion[version], filename)
+	}
+
+	for version, filenames := range byVersion {
+		if len(filenames) < 2 {
+			continue
+		}
+		sort.Strings(filenames)
+		for _, filename := range filenames {
+			var others []string
+			for _, other := range filenames {
+				if other != filename {
+					others = append(others, other)
+				}
+			}
+			flag(docsByFile[filename], SeverityError, CodeDuplicateVersion,
+				"version %s is also used by %s", version, strings.Join(others, ", "))
+		}
+	}
+
+	if remote != nil {
+		sort.SliceStable(files, func(i, j int) bool {
+			return files[i].Version < files[j].Version
+		})
+		drift := migrations.Compare(files, remote)
+		if len(drift.Applied) > 0 {
+			latest := drift.Applied[len(drift.Applied)-1]
+			for _, f := range drift.OutOfOrder() {
+				flag(docsByFile[f.Filename], SeverityWarning, CodeOutOfOrder,
+					"older than %s, the latest applied migration; push will apply it out of order", latest.Filename)
+			}
+		}
+	}
+
+	return result
+}
+
+// firstLine is the range diagnostics about a whole file point at
+func firstLine(text string) Range {
+	line, _, _ := strings.Cut(text, "\n")
+	line = strings.TrimSuffix(line, "\r")
+	return Range{End: Position{Character: utf16Len(line)}}
+}
+
+// characterOffset converts a 1-based character position, as the parser
+// reports it, into a byte offset
+func characterOffset(text string, cursor int) int {
+	offset := 0
+	for i := 1; i < cursor && offset < len(text); i++ {
+		_, size := utf8.DecodeRuneInString(text[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// tokenEnd returns the byte offset where the token at offset ends
+func tokenEnd(text string, offset int) int {
+	end := offset
+	for end < len(text) && !strings.ContainsRune(" \t\r\n;,()", rune(text[end])) {
+		end++
+	}
+	if end == offset && end < len(text) && text[end] != '\n' && text[end] != '\r' {
+		// A lone punctuation character
+		end++
+	}
+	return end
+}
+
+// offsetPosition converts a byte offset into an LSP position
+func offsetPosition(text string, offset int) Position {
+	if offset > len(text) {
+		offset = len(text)
+	}
+	before := text[:offset]
+	line := strings.Count(before, "\n")
+	if i := strings.LastIndexByte(before, '\n'); i >= 0 {
+		before = before[i+1:]
+	}
+	return Position{Line: line, Character: utf16Len(before)}
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
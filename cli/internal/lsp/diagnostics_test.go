@@ -0,0 +1,117 @@
+package lsp
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/supabase/supabase-dx/cli/internal/api"
+	"github.com/supabase/supabase-dx/cli/internal/migrations"
+)
+
+func migration(filename, sql string) Document {
+	return Document{Path: filepath.Join(migrations.Dir, filename), Text: sql}
+}
+
+func codes(diagnostics []Diagnostic) []string {
+	var list []string
+	for _, d := range diagnostics {
+		list = append(list, d.Code)
+	}
+	return list
+}
+
+func TestCheckMigrationFilenames(t *testing.T) {
+	docs := []Document{
+		migration("20240101000000_a.sql", "select 1;"),
+		migration("20240201000000_b.sql", "select 1;"),
+		migration("20240201000000_c.sql", "select 1;"),
+		migration("2024_d.sql", "select 1;"),
+		migration("notes.sql", "select 1;"),
+		{Path: filepath.Join("supabase", "schemas", "notes.sql"), Text: "select 1;"},
+	}
+
+	result := Check(docs, nil)
+	expected := map[string][]string{
+		"20240101000000_a.sql": nil,
+		"20240201000000_b.sql": {CodeDuplicateVersion},
+		"20240201000000_c.sql": {CodeDuplicateVersion},
+		"2024_d.sql":           {CodeInvalidVersion},
+		"notes.sql":            {CodeInvalidFilename},
+	}
+	for filename, want := range expected {
+		got := codes(result[filepath.Join(migrations.Dir, filename)])
+		if len(got) != len(want) || (len(want) > 0 && got[0] != want[0]) {
+			t.Errorf("%s: expected %v, got %v", filename, want, got)
+		}
+	}
+	if got := result[filepath.Join("supabase", "schemas", "notes.sql")]; got == nil || len(got) != 0 {
+		t.Errorf("expected no diagnostics for a schema file, got %v", got)
+	}
+
+	dup := result[filepath.Join(migrations.Dir, "20240201000000_b.sql")][0]
+	if dup.Message != "version 20240201000000 is also used by 20240201000000_c.sql" {
+		t.Errorf("unexpected message: %s", dup.Message)
+	}
+	if dup.Range.End != (Position{Character: 9}) {
+		t.Errorf("expected the first line, got %+v", dup.Range)
+	}
+}
+
+func TestCheckOutOfOrder(t *testing.T) {
+	docs := []Document{
+		migration("20240101000000_a.sql", "select 1;"),
+		migration("20240201000000_b.sql", "select 1;"),
+		migration("20240301000000_c.sql", "select 1;"),
+	}
+	remote := []api.Migration{{Version: "20240101000000"}, {Version: "20240301000000"}}
+
+	result := Check(docs, remote)
+	got := result[filepath.Join(migrations.Dir, "20240201000000_b.sql")]
+	if len(got) != 1 || got[0].Code != CodeOutOfOrder {
+		t.Fatalf("expected an out_of_order warning, got %+v", got)
+	}
+	if got[0].Severity != SeverityWarning {
+		t.Errorf("expected a warning, got severity %d", got[0].Severity)
+	}
+
+	// Without a history nothing is known to be applied
+	if got := Check(docs, nil)[filepath.Join(migrations.Dir, "20240201000000_b.sql")]; len(got) != 0 {
+		t.Errorf("expected no diagnostics without a history, got %+v", got)
+	}
+}
+
+func TestCheckSQL(t *testing.T) {
+	if got := CheckSQL(Document{Text: "create table foo ();"}); len(got) != 0 {
+		t.Errorf("expected no diagnostics, got %+v", got)
+	}
+
+	got := CheckSQL(Document{Text: "-- é\ncreate tabel foo ();"})
+	if len(got) != 1 || got[0].Code != CodeSyntaxError {
+		t.Fatalf("expected a syntax error, got %+v", got)
+	}
+	expected := Range{Start: Position{Line: 1, Character: 7}, End: Position{Line: 1, Character: 12}}
+	if got[0].Range != expected {
+		t.Errorf("expected %+v, got %+v", expected, got[0].Range)
+	}
+	if got[0].Message != `syntax error at or near "tabel"` {
+		t.Errorf("unexpected message: %s", got[0].Message)
+	}
+}
+
+func TestOffsetPosition(t *testing.T) {
+	text := "a\n😀b"
+	tests := []struct {
+		offset   int
+		expected Position
+	}{
+		{0, Position{0, 0}},
+		{2, Position{1, 0}},
+		{6, Position{1, 2}}, // the emoji is two UTF-16 units
+		{100, Position{1, 3}},
+	}
+	for _, tt := range tests {
+		if got := offsetPosition(text, tt.offset); got != tt.expected {
+			t.Errorf("offsetPosition(%d): expected %+v, got %+v", tt.offset, tt.expected, got)
+		}
+	}
+}
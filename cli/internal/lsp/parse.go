@@ -0,0 +1,23 @@
+package lsp
+
+import (
+	"errors"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+	"github.com/pganalyze/pg_query_go/v6/parser"
+)
+
+// syntaxError parses sql with the Postgres parser and returns its error
+// message, empty if sql parses, and the 1-based character where parsing
+// failed (0 if unknown)
+func syntaxError(sql string) (message string, cursor int) {
+	_, err := pg_query.Parse(sql)
+	if err == nil {
+		return "", 0
+	}
+	var pgErr *parser.Error
+	if errors.As(err, &pgErr) {
+		return pgErr.Message, pgErr.Cursorpos
+	}
+	return err.Error(), 0
+}
@@ -0,0 +1,329 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/supabase/supabase-dx/cli/internal/api"
+	"github.com/supabase/supabase-dx/cli/internal/declarative"
+	"github.com/supabase/supabase-dx/cli/internal/jsonrpc"
+	"github.com/supabase/supabase-dx/cli/internal/migrations"
+)
+
+// HistoryFunc returns the migrations applied to the target
+type HistoryFunc func(ctx context.Context) ([]api.Migration, error)
+
+// Server publishes diagnostics for the SQL files of a project to a language
+// client. Files are read from disk, or from the client while they're open.
+type Server struct {
+	root    string
+	history HistoryFunc
+	conn    *jsonrpc.Conn
+	exit    context.CancelFunc
+
+	mu        sync.Mutex
+	open      map[string]string    // path -> text of documents open in the client
+	parsed    map[string]parsedDoc // path -> syntax diagnostics of its last text
+	published map[string]bool      // paths with diagnostics in the client
+	remote    []api.Migration      // nil until the history is fetched
+	lastErr   string               // last history failure, logged once
+	pending   chan struct{}        // signals a history refresh
+}
+
+type parsedDoc struct {
+	text        string
+	diagnostics []Diagnostic
+}
+
+// NewServer returns a server for the project at root. history may be nil
+// when there's no target to compare with.
+func NewServer(root string, history HistoryFunc) *Server {
+	return &Server{
+		root:      root,
+		history:   history,
+		open:      make(map[string]string),
+		parsed:    make(map[string]parsedDoc),
+		published: make(map[string]bool),
+		pending:   make(chan struct{}, 1),
+	}
+}
+
+// Serve speaks the Language Server Protocol over r and w until the client
+// sends exit or the stream ends
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	ctx, s.exit = context.WithCancel(ctx)
+	defer s.exit()
+	s.conn = jsonrpc.NewConn(r, w)
+
+	if s.history != nil {
+		go s.refreshLoop(ctx)
+	}
+
+	err := s.conn.Serve(ctx, s.handle)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+type textDocumentItem struct {
+	URI  string `json:"uri"`
+	Text string `json:"text"`
+}
+
+type textDocumentParams struct {
+	TextDocument   textDocumentItem `json:"textDocument"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+func (s *Server) handle(ctx context.Context, method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync": map[string]any{
+					"openClose": true,
+					"change":    1, // full text on every change
+					"save":      map[string]bool{"includeText": false},
+				},
+			},
+			"serverInfo": map[string]string{"name": "supa"},
+		}, nil
+
+	case "initialized", "workspace/didChangeWatchedFiles":
+		s.refresh()
+		s.publish()
+		return nil, nil
+
+	case "textDocument/didOpen", "textDocument/didChange", "textDocument/didSave", "textDocument/didClose":
+		var p textDocumentParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &jsonrpc.Error{Code: jsonrpc.InvalidParams, Message: err.Error()}
+		}
+		path, ok := s.path(p.TextDocument.URI)
+		if !ok {
+			return nil, nil
+		}
+
+		s.mu.Lock()
+		switch method {
+		case "textDocument/didOpen":
+			s.open[path] = p.TextDocument.Text
+		case "textDocument/didChange":
+			if n := len(p.ContentChanges); n > 0 {
+				s.open[path] = p.ContentChanges[n-1].Text
+			}
+		case "textDocument/didClose":
+			delete(s.open, path)
+		}
+		s.mu.Unlock()
+
+		// A saved migration may have been pushed since
+		if method == "textDocument/didSave" && IsMigration(path) {
+			s.refresh()
+		}
+		s.publish()
+		return nil, nil
+
+	case "shutdown":
+		return nil, nil
+
+	case "exit":
+		s.exit()
+		return nil, nil
+	}
+
+	if strings.HasPrefix(method, "$/") {
+		// Optional protocol notifications
+		return nil, nil
+	}
+	return nil, &jsonrpc.Error{Code: jsonrpc.MethodNotFound, Message: "method not found: " + method}
+}
+
+// refresh asks for the migration history to be fetched again
+func (s *Server) refresh() {
+	select {
+	case s.pending <- struct{}{}:
+	default:
+	}
+}
+
+// refreshLoop fetches the history when asked, off the request path since
+// it's a network call
+func (s *Server) refreshLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.pending:
+		}
+
+		remote, err := s.history(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if err != nil {
+			// Keep the last known history; log each distinct failure once
+			message := err.Error()
+			if message != s.lastErr {
+				s.lastErr = message
+				s.conn.Notify("window/logMessage", map[string]any{
+					"type":    2, // warning
+					"message": fmt.Sprintf("supa: failed to fetch the migration history: %v", err),
+				})
+			}
+			s.mu.Unlock()
+			continue
+		}
+		s.lastErr = ""
+		if remote == nil {
+			remote = []api.Migration{}
+		}
+		s.remote = remote
+		s.mu.Unlock()
+
+		s.publish()
+	}
+}
+
+// publish checks every file and sends the diagnostics of each, including
+// empty ones to clear files that are fixed or gone
+func (s *Server) publish() {
+	docs, err := s.documents()
+	if err != nil {
+		s.conn.Notify("window/logMessage", map[string]any{
+			"type":    1, // error
+			"message": fmt.Sprintf("supa: failed to read SQL files: %v", err),
+		})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	diagnostics := check(docs, s.remote, s.checkSQL)
+	for path := range s.published {
+		if _, ok := diagnostics[path]; !ok {
+			diagnostics[path] = []Diagnostic{}
+		}
+	}
+	for path := range s.parsed {
+		if _, ok := diagnostics[path]; !ok {
+			delete(s.parsed, path)
+		}
+	}
+
+	for path, list := range diagnostics {
+		if len(list) == 0 {
+			if !s.published[path] {
+				continue
+			}
+			delete(s.published, path)
+		} else {
+			s.published[path] = true
+		}
+		s.conn.Notify("textDocument/publishDiagnostics", map[string]any{
+			"uri":         s.uri(path),
+			"diagnostics": list,
+		})
+	}
+}
+
+// checkSQL parses a document again only when its text changed. Called with
+// mu held.
+func (s *Server) checkSQL(doc Document) []Diagnostic {
+	if cached, ok := s.parsed[doc.Path]; ok && cached.text == doc.Text {
+		return cached.diagnostics
+	}
+	diagnostics := CheckSQL(doc)
+	s.parsed[doc.Path] = parsedDoc{text: doc.Text, diagnostics: diagnostics}
+	return diagnostics
+}
+
+// documents returns the migration and schema files on disk, with the text
+// the client has for the open ones
+func (s *Server) documents() ([]Document, error) {
+	var docs []Document
+
+	entries, err := os.ReadDir(filepath.Join(s.root, migrations.Dir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		path := filepath.Join(migrations.Dir, entry.Name())
+		content, err := os.ReadFile(filepath.Join(s.root, path))
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, Document{Path: path, Text: string(content)})
+	}
+
+	sources, err := declarative.Load(s.root)
+	if err != nil {
+		return nil, err
+	}
+	for _, source := range sources {
+		docs = append(docs, Document{Path: source.Path, Text: source.SQL})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seen := make(map[string]bool, len(docs))
+	for i, doc := range docs {
+		seen[doc.Path] = true
+		if text, ok := s.open[doc.Path]; ok {
+			docs[i].Text = text
+		}
+	}
+	// Open documents not saved yet
+	for path, text := range s.open {
+		if !seen[path] {
+			docs = append(docs, Document{Path: path, Text: text})
+		}
+	}
+	return docs, nil
+}
+
+// path returns the project-relative path of a file URI, if it's a migration
+// or schema file
+func (s *Server) path(uri string) (string, bool) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return "", false
+	}
+	path := u.Path
+	if runtime.GOOS == "windows" {
+		// file:///C:/project -> C:/project
+		path = strings.TrimPrefix(path, "/")
+	}
+
+	rel, err := filepath.Rel(s.root, filepath.FromSlash(path))
+	if err != nil || !(IsMigration(rel) || IsSchema(rel)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// uri returns the file URI of a project-relative path
+func (s *Server) uri(path string) string {
+	abs := filepath.ToSlash(filepath.Join(s.root, path))
+	if !strings.HasPrefix(abs, "/") {
+		abs = "/" + abs
+	}
+	return (&url.URL{Scheme: "file", Path: abs}).String()
+}
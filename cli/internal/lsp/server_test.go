@@ -0,0 +1,148 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/supabase/supabase-dx/cli/internal/api"
+	"github.com/supabase/supabase-dx/cli/internal/migrations"
+)
+
+// client is the editor's side of a session
+type client struct {
+	t        *testing.T
+	w        io.Writer
+	messages chan published
+	seq      int
+}
+
+func newClient(t *testing.T, w io.Writer, r io.Reader) *client {
+	c := &client{t: t, w: w, messages: make(chan published, 100)}
+	// Read everything, so the server never blocks on a write
+	go func() {
+		defer close(c.messages)
+		br := bufio.NewReader(r)
+		for {
+			headers, err := textproto.NewReader(br).ReadMIMEHeader()
+			if err != nil {
+				return
+			}
+			length, _ := strconv.Atoi(headers.Get("Content-Length"))
+			body := make([]byte, length)
+			if _, err := io.ReadFull(br, body); err != nil {
+				return
+			}
+			var msg published
+			json.Unmarshal(body, &msg)
+			c.messages <- msg
+		}
+	}()
+	return c
+}
+
+func (c *client) send(method string, params any) {
+	c.t.Helper()
+	msg := map[string]any{"jsonrpc": "2.0", "method": method, "params": params}
+	if method == "initialize" || method == "shutdown" {
+		c.seq++
+		msg["id"] = c.seq
+	}
+	body, _ := json.Marshal(msg)
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		c.t.Fatalf("failed to send %s: %v", method, err)
+	}
+}
+
+type published struct {
+	Method string `json:"method"`
+	Params struct {
+		URI         string       `json:"uri"`
+		Diagnostics []Diagnostic `json:"diagnostics"`
+	} `json:"params"`
+}
+
+// next returns the next diagnostics the server publishes for uri
+func (c *client) next(uri string) []Diagnostic {
+	c.t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg, ok := <-c.messages:
+			if !ok {
+				c.t.Fatal("server closed the stream")
+			}
+			if msg.Method == "textDocument/publishDiagnostics" && msg.Params.URI == uri {
+				return msg.Params.Diagnostics
+			}
+		case <-timeout:
+			c.t.Fatalf("no diagnostics published for %s", uri)
+		}
+	}
+}
+
+func TestServer(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, migrations.Dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create migrations dir: %v", err)
+	}
+	for _, name := range []string{"20240101000000_a.sql", "20240201000000_b.sql", "20240301000000_c.sql"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("select 1;"), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	history := func(ctx context.Context) ([]api.Migration, error) {
+		return []api.Migration{{Version: "20240101000000"}, {Version: "20240301000000"}}, nil
+	}
+	server := NewServer(root, history)
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(context.Background(), inR, outW) }()
+
+	c := newClient(t, inW, outR)
+	c.send("initialize", map[string]any{})
+	c.send("initialized", map[string]any{})
+
+	// Published once the history is fetched
+	b := server.uri(filepath.Join(migrations.Dir, "20240201000000_b.sql"))
+	if got := c.next(b); len(got) != 1 || got[0].Code != CodeOutOfOrder {
+		t.Fatalf("expected an out_of_order warning, got %+v", got)
+	}
+
+	// Unsaved edits are checked; fixing them clears the diagnostics
+	a := server.uri(filepath.Join(migrations.Dir, "20240101000000_a.sql"))
+	c.send("textDocument/didOpen", map[string]any{"textDocument": map[string]any{"uri": a, "text": "create tabel foo ();"}})
+	if got := c.next(a); len(got) != 1 || got[0].Code != CodeSyntaxError {
+		t.Fatalf("expected a syntax error, got %+v", got)
+	}
+	c.send("textDocument/didChange", map[string]any{
+		"textDocument":   map[string]any{"uri": a},
+		"contentChanges": []map[string]string{{"text": "create table foo ();"}},
+	})
+	if got := c.next(a); len(got) != 0 {
+		t.Fatalf("expected the diagnostics to be cleared, got %+v", got)
+	}
+
+	c.send("shutdown", nil)
+	c.send("exit", nil)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server didn't exit")
+	}
+}
@@ -0,0 +1,203 @@
+package migrations
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+// LockFile records the checksum of each applied migration, relative to the
+// project root
+var LockFile = filepath.Join("supabase", "migrations.lock")
+
+// Lock maps migration versions to the checksum of the file that was applied
+type Lock struct {
+	Version    int               `json:"version"`
+	Migrations map[string]string `json:"migrations"`
+}
+
+// LoadLock reads <root>/supabase/migrations.lock. A missing file returns an
+// empty lock.
+func LoadLock(root string) (*Lock, error) {
+	lock := &Lock{Version: 1, Migrations: make(map[string]string)}
+
+	data, err := os.ReadFile(filepath.Join(root, LockFile))
+	if os.IsNotExist(err) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", LockFile, err)
+	}
+
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", LockFile, err)
+	}
+	if lock.Migrations == nil {
+		lock.Migrations = make(map[string]string)
+	}
+	return lock, nil
+}
+
+// Save writes the lock to <root>/supabase/migrations.lock
+func (l *Lock) Save(root string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	path := filepath.Join(root, LockFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Checksum returns the content hash recorded for a migration. Line endings
+// are normalized so a checkout with autocrlf doesn't look like an edit.
+func Checksum(content []byte) string {
+	content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// ChecksumFile hashes a local migration file
+func ChecksumFile(root string, f File) (string, error) {
+	content, err := os.ReadFile(filepath.Join(root, Dir, f.Filename))
+	if err != nil {
+		return "", err
+	}
+	return Checksum(content), nil
+}
+
+// Tampered is an applied migration whose file no longer matches the lock
+type Tampered struct {
+	Version  string `json:"version"`
+	File     string `json:"file"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// Verify checks applied migrations against the lock. Migrations without a
+// lock entry (applied before the lockfile existed) are returned in
+// unrecorded, for Baseline: the local file can't be trusted as their
+// baseline, since it may have been edited already.
+func (l *Lock) Verify(root string, applied []File) (tampered []Tampered, unrecorded []File, err error) {
+	for _, f := range applied {
+		expected, ok := l.Migrations[f.Version]
+		if !ok {
+			unrecorded = append(unrecorded, f)
+			continue
+		}
+
+		sum, err := ChecksumFile(root, f)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to hash %s: %w", f.Filename, err)
+		}
+		if expected != sum {
+			tampered = append(tampered, Tampered{
+				Version:  f.Version,
+				File:     f.Filename,
+				Expected: expected,
+				Actual:   sum,
+			})
+		}
+	}
+
+	sortTampered(tampered)
+	return tampered, unrecorded, nil
+}
+
+// Baseline records lock entries for unrecorded migrations whose file still
+// matches the statements the remote ran for it, as returned by statements.
+// Whitespace, comments and statement separators outside string literals
+// are ignored. Files that don't match are reported as tampered; migrations
+// the remote kept no statements for are left unrecorded. added counts the
+// new entries.
+func (l *Lock) Baseline(root string, unrecorded []File, statements func(f File) ([]string, error)) (tampered []Tampered, added int, err error) {
+	for _, f := range unrecorded {
+		content, err := os.ReadFile(filepath.Join(root, Dir, f.Filename))
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to hash %s: %w", f.Filename, err)
+		}
+		remote, err := statements(f)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to fetch migration %s: %w", f.Version, err)
+		}
+		if len(remote) == 0 {
+			continue
+		}
+
+		applied := normalizeSQL(strings.Join(remote, ";"))
+		if normalizeSQL(string(content)) != applied {
+			tampered = append(tampered, Tampered{
+				Version:  f.Version,
+				File:     f.Filename,
+				Expected: Checksum([]byte(applied)),
+				Actual:   Checksum(content),
+			})
+			continue
+		}
+		l.Migrations[f.Version] = Checksum(content)
+		added++
+	}
+
+	sortTampered(tampered)
+	return tampered, added, nil
+}
+
+// normalizeSQL reduces SQL to its tokens, one space apart, dropping the
+// comments, whitespace and semicolons that differ between a file and the
+// statements it was split into. String literals and dollar-quoted bodies are
+// kept as written. SQL that can't be tokenized is only trimmed.
+func normalizeSQL(sql string) string {
+	scan, err := pg_query.Scan(sql)
+	if err != nil {
+		return strings.TrimSpace(sql)
+	}
+
+	var b strings.Builder
+	for _, token := range scan.Tokens {
+		switch token.Token {
+		case pg_query.Token_ASCII_59, pg_query.Token_SQL_COMMENT, pg_query.Token_C_COMMENT:
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(sql[token.Start:token.End])
+	}
+	return b.String()
+}
+
+func sortTampered(tampered []Tampered) {
+	sort.Slice(tampered, func(i, j int) bool {
+		return tampered[i].Version < tampered[j].Version
+	})
+}
+
+// TamperedError is returned when applied migrations were edited afterwards
+type TamperedError struct {
+	Tampered []Tampered
+}
+
+func (e *TamperedError) Error() string {
+	versions := make([]string, len(e.Tampered))
+	for i, t := range e.Tampered {
+		versions[i] = t.Version
+	}
+	return fmt.Sprintf("%d applied migration(s) changed since they were applied: %s", len(e.Tampered), strings.Join(versions, ", "))
+}
+
+// ErrorCode returns the machine-readable code for --json output
+func (e *TamperedError) ErrorCode() string {
+	return "migration_tampered"
+}
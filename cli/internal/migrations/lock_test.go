@@ -0,0 +1,136 @@
+package migrations
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChecksumNormalizesLineEndings(t *testing.T) {
+	if Checksum([]byte("select 1;\r\nselect 2;\r\n")) != Checksum([]byte("select 1;\nselect 2;\n")) {
+		t.Error("expected CRLF and LF content to hash the same")
+	}
+	if Checksum([]byte("select 1;")) == Checksum([]byte("select 2;")) {
+		t.Error("expected different content to hash differently")
+	}
+}
+
+func TestLockVerify(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, Dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create migrations dir: %v", err)
+	}
+
+	a := File{Filename: "20240101000000_a.sql", Version: "20240101000000", Name: "a"}
+	write := func(content string) {
+		if err := os.WriteFile(filepath.Join(dir, a.Filename), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write migration: %v", err)
+		}
+	}
+	write("create table a ();")
+
+	lock, err := LoadLock(root)
+	if err != nil {
+		t.Fatalf("expected empty lock, got %v", err)
+	}
+
+	// Without an entry, the migration is left for Baseline
+	tampered, unrecorded, err := lock.Verify(root, []File{a})
+	if err != nil || len(tampered) != 0 || len(unrecorded) != 1 {
+		t.Fatalf("expected %s to be unrecorded, got %v %v %v", a.Version, tampered, unrecorded, err)
+	}
+	lock.Migrations[a.Version] = Checksum([]byte("create table a ();"))
+	if err := lock.Save(root); err != nil {
+		t.Fatalf("failed to save lock: %v", err)
+	}
+
+	// Edit the applied migration
+	write("create table a (id int);")
+
+	lock, err = LoadLock(root)
+	if err != nil {
+		t.Fatalf("failed to load lock: %v", err)
+	}
+	tampered, unrecorded, err = lock.Verify(root, []File{a})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(unrecorded) != 0 || len(tampered) != 1 || tampered[0].Version != a.Version {
+		t.Errorf("expected %s to be tampered, got %+v", a.Version, tampered)
+	}
+
+	tamperErr := &TamperedError{Tampered: tampered}
+	if tamperErr.ErrorCode() != "migration_tampered" {
+		t.Errorf("expected migration_tampered, got %q", tamperErr.ErrorCode())
+	}
+}
+
+func TestLockBaseline(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, Dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create migrations dir: %v", err)
+	}
+
+	files := []File{
+		{Filename: "20240101000000_a.sql", Version: "20240101000000", Name: "a"},
+		{Filename: "20240102000000_b.sql", Version: "20240102000000", Name: "b"},
+		{Filename: "20240103000000_c.sql", Version: "20240103000000", Name: "c"},
+	}
+	contents := []string{
+		"create table a ();\ncreate table a2 ();\n",
+		"create table b (id int);\n", // edited after it was applied
+		"create table c ();\n",
+	}
+	for i, f := range files {
+		if err := os.WriteFile(filepath.Join(dir, f.Filename), []byte(contents[i]), 0644); err != nil {
+			t.Fatalf("failed to write migration: %v", err)
+		}
+	}
+	remote := map[string][]string{
+		"20240101000000": {"create table a ()", "create table a2 ()"},
+		"20240102000000": {"create table b ();"},
+	}
+
+	lock, _ := LoadLock(root)
+	tampered, added, err := lock.Baseline(root, files, func(f File) ([]string, error) {
+		return remote[f.Version], nil
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if added != 1 || lock.Migrations["20240101000000"] != Checksum([]byte(contents[0])) {
+		t.Errorf("expected a to be recorded from its file, got %d %v", added, lock.Migrations)
+	}
+	if len(tampered) != 1 || tampered[0].Version != "20240102000000" {
+		t.Errorf("expected b to be tampered, got %+v", tampered)
+	}
+	if _, ok := lock.Migrations["20240103000000"]; ok {
+		t.Error("expected c, with no remote statements, to stay unrecorded")
+	}
+}
+
+func TestNormalizeSQL(t *testing.T) {
+	same := [][2]string{
+		{"create table a ();\ncreate table a2 ();\n", "create table a ()\ncreate  table a2 ()"},
+		{"-- seed\ninsert into t values ('a b');", "insert into t values ('a b')"},
+		{"create function f() returns int as $$ select 1 $$ language sql;", "create function f() returns int\nas $$ select 1 $$\nlanguage sql"},
+	}
+	for _, pair := range same {
+		if normalizeSQL(pair[0]) != normalizeSQL(pair[1]) {
+			t.Errorf("expected %q and %q to match", pair[0], pair[1])
+		}
+	}
+
+	different := [][2]string{
+		{"insert into t values ('a b');", "insert into t values ('ab');"},
+		{"insert into t values ('a;b');", "insert into t values ('ab');"},
+		{"create function f() returns text as $$ select 'a  b' $$ language sql;", "create function f() returns text as $$ select 'a b' $$ language sql;"},
+	}
+	for _, pair := range different {
+		if normalizeSQL(pair[0]) == normalizeSQL(pair[1]) {
+			t.Errorf("expected %q and %q to differ", pair[0], pair[1])
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package migrations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/supabase/supabase-dx/cli/internal/api"
+)
+
+// Dir is the migrations directory, relative to the project root
+var Dir = filepath.Join("supabase", "migrations")
+
+// File is a local migration file (e.g. "20240101120000_create_users.sql")
+type File struct {
+	Filename string `json:"file"`
+	Version  string `json:"version"`
+	Name     string `json:"name,omitempty"`
+}
+
+// BaseName returns the filename without the .sql extension
+func (f File) BaseName() string {
+	return strings.TrimSuffix(f.Filename, ".sql")
+}
+
+// ParseFilename splits a migration filename into its timestamp version and
+// name. ok is false when the file doesn't start with a numeric version.
+func ParseFilename(filename string) (version, name string, ok bool) {
+	if !strings.HasSuffix(filename, ".sql") {
+		return "", "", false
+	}
+	base := strings.TrimSuffix(filename, ".sql")
+
+	version, name, _ = strings.Cut(base, "_")
+	if version == "" {
+		return "", "", false
+	}
+	for _, r := range version {
+		if r < '0' || r > '9' {
+			return "", "", false
+		}
+	}
+	return version, name, true
+}
+
+// List returns the migrations in <root>/supabase/migrations sorted by
+// version. Files without a valid version are returned in invalid. A missing
+// directory is not an error.
+func List(root string) (files []File, invalid []string, err error) {
+	entries, err := os.ReadDir(filepath.Join(root, Dir))
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		version, name, ok := ParseFilename(entry.Name())
+		if !ok {
+			invalid = append(invalid, entry.Name())
+			continue
+		}
+		files = append(files, File{Filename: entry.Name(), Version: version, Name: name})
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Version < files[j].Version
+	})
+
+	return files, invalid, nil
+}
+
+// Drift compares local migration files with the remote migration history
+type Drift struct {
+	Applied  []File          `json:"applied"`  // local files already applied remotely
+	Pending  []File          `json:"pending"`  // local files not yet applied
+	Orphaned []api.Migration `json:"orphaned"` // remote migrations with no local file
+
+	// Remote is the remote migration each applied file matched, by file
+	// version. Its version is the server's, which may differ.
+	Remote map[string]api.Migration `json:"-"`
+}
+
+// InSync reports whether local and remote histories match
+func (d *Drift) InSync() bool {
+	return len(d.Pending) == 0 && len(d.Orphaned) == 0
+}
+
+// Diverged reports whether the remote has migrations with no local file.
+// Pushing on top of a diverged history would hide those changes.
+func (d *Drift) Diverged() bool {
+	return len(d.Orphaned) > 0
+}
+
+// OutOfOrder returns pending migrations older than the newest applied one.
+// They can still be applied, but won't run in filename order.
+func (d *Drift) OutOfOrder() []File {
+	if len(d.Applied) == 0 {
+		return nil
+	}
+	latest := d.Applied[len(d.Applied)-1].Version
+
+	var files []File
+	for _, f := range d.Pending {
+		if f.Version < latest {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// DivergedError is returned when the remote history has migrations that
+// don't exist locally
+type DivergedError struct {
+	Orphaned []api.Migration
+}
+
+func (e *DivergedError) Error() string {
+	versions := make([]string, len(e.Orphaned))
+	for i, m := range e.Orphaned {
+		versions[i] = m.Version
+	}
+	return fmt.Sprintf("remote has %d migration(s) missing locally: %s", len(e.Orphaned), strings.Join(versions, ", "))
+}
+
+// ErrorCode returns the machine-readable code for --json output
+func (e *DivergedError) ErrorCode() string {
+	return "diverged_history"
+}
+
+// Check returns a *DivergedError if the histories have diverged
+func (d *Drift) Check() error {
+	if d.Diverged() {
+		return &DivergedError{Orphaned: d.Orphaned}
+	}
+	return nil
+}
+
+// Compare matches local files to remote migrations. A remote migration
+// matches a file when their versions are equal, or when the remote name is
+// the file's base name (the API assigns its own version on apply, so pushes
+// record the full "<version>_<name>" as the name).
+func Compare(local []File, remote []api.Migration) *Drift {
+	drift := &Drift{Applied: []File{}, Pending: []File{}, Orphaned: []api.Migration{}, Remote: make(map[string]api.Migration)}
+
+	matched := make(map[int]bool)
+	for _, f := range local {
+		found := false
+		for i, m := range remote {
+			if matched[i] {
+				continue
+			}
+			if Matches(f, m) {
+				matched[i] = true
+				found = true
+				drift.Remote[f.Version] = m
+				break
+			}
+		}
+		if found {
+			drift.Applied = append(drift.Applied, f)
+		} else {
+			drift.Pending = append(drift.Pending, f)
+		}
+	}
+
+	for i, m := range remote {
+		if !matched[i] {
+			drift.Orphaned = append(drift.Orphaned, m)
+		}
+	}
+
+	return drift
+}
+
+// Matches reports whether a remote migration corresponds to a local file
+func Matches(f File, m api.Migration) bool {
+	return m.Version == f.Version || (m.Name != "" && m.Name == f.BaseName())
+}
@@ -0,0 +1,132 @@
+package migrations
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/supabase/supabase-dx/cli/internal/api"
+)
+
+func TestParseFilename(t *testing.T) {
+	tests := []struct {
+		filename string
+		version  string
+		name     string
+		ok       bool
+	}{
+		{"20240101120000_create_users.sql", "20240101120000", "create_users", true},
+		{"20240101120000.sql", "20240101120000", "", true},
+		{"create_users.sql", "", "", false},
+		{"2024a_create.sql", "", "", false},
+		{"20240101120000_create_users.txt", "", "", false},
+	}
+
+	for _, tt := range tests {
+		version, name, ok := ParseFilename(tt.filename)
+		if version != tt.version || name != tt.name || ok != tt.ok {
+			t.Errorf("ParseFilename(%q) = %q, %q, %v; expected %q, %q, %v",
+				tt.filename, version, name, ok, tt.version, tt.name, tt.ok)
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	root := t.TempDir()
+
+	// Missing directory is not an error
+	files, _, err := List(root)
+	if err != nil || len(files) != 0 {
+		t.Fatalf("expected no files and no error, got %v %v", files, err)
+	}
+
+	dir := filepath.Join(root, Dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create migrations dir: %v", err)
+	}
+	for _, name := range []string{"20240201000000_b.sql", "20240101000000_a.sql", "notes.sql", "README.md"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("select 1;"), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	files, invalid, err := List(root)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(files) != 2 || files[0].Version != "20240101000000" || files[1].Name != "b" {
+		t.Errorf("unexpected files: %+v", files)
+	}
+	if len(invalid) != 1 || invalid[0] != "notes.sql" {
+		t.Errorf("expected notes.sql to be invalid, got %v", invalid)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	local := []File{
+		{Filename: "20240101000000_a.sql", Version: "20240101000000", Name: "a"},
+		{Filename: "20240201000000_b.sql", Version: "20240201000000", Name: "b"},
+		{Filename: "20240301000000_c.sql", Version: "20240301000000", Name: "c"},
+	}
+	remote := []api.Migration{
+		{Version: "20240101000000", Name: "a"},
+		// Applied through the API, which assigned its own version
+		{Version: "20240215093000", Name: "20240201000000_b"},
+		{Version: "20231201000000", Name: "dashboard_change"},
+	}
+
+	drift := Compare(local, remote)
+
+	if len(drift.Applied) != 2 {
+		t.Errorf("expected 2 applied, got %+v", drift.Applied)
+	}
+	if len(drift.Pending) != 1 || drift.Pending[0].Name != "c" {
+		t.Errorf("expected c to be pending, got %+v", drift.Pending)
+	}
+	if len(drift.Orphaned) != 1 || drift.Orphaned[0].Name != "dashboard_change" {
+		t.Errorf("expected dashboard_change to be orphaned, got %+v", drift.Orphaned)
+	}
+	if drift.InSync() {
+		t.Error("expected drift")
+	}
+	if !drift.Diverged() {
+		t.Error("expected orphaned remote migration to count as diverged")
+	}
+}
+
+func TestOutOfOrder(t *testing.T) {
+	a := File{Filename: "20240101000000_a.sql", Version: "20240101000000"}
+	b := File{Filename: "20240201000000_b.sql", Version: "20240201000000"}
+
+	// Appending newer migrations is fine
+	drift := Compare([]File{a, b}, []api.Migration{{Version: a.Version}})
+	if len(drift.OutOfOrder()) != 0 {
+		t.Error("expected appending a newer migration to be in order")
+	}
+
+	// A pending migration older than the newest applied one is out of order
+	drift = Compare([]File{a, b}, []api.Migration{{Version: b.Version}})
+	if files := drift.OutOfOrder(); len(files) != 1 || files[0].Version != a.Version {
+		t.Errorf("expected a to be out of order, got %+v", files)
+	}
+	if drift.Diverged() {
+		t.Error("expected out-of-order migration not to count as diverged")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	drift := Compare(nil, []api.Migration{{Version: "20240101000000"}})
+
+	err := drift.Check()
+	divErr, ok := err.(*DivergedError)
+	if !ok {
+		t.Fatalf("expected *DivergedError, got %v", err)
+	}
+	if divErr.ErrorCode() != "diverged_history" {
+		t.Errorf("expected diverged_history, got %q", divErr.ErrorCode())
+	}
+
+	if err := Compare(nil, nil).Check(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package migrations
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Position is a 1-based location in a migration file. Column is 0 when only
+// the line is known.
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column,omitempty"`
+}
+
+var (
+	// Postgres error context: "LINE 3: select foo bar\n               ^"
+	lineRe     = regexp.MustCompile(`LINE (\d+): (.*)\n(\s*)\^`)
+	lineOnlyRe = regexp.MustCompile(`LINE (\d+):`)
+	// Character offsets: "at character 42", "position": "42", position: 42
+	charRe = regexp.MustCompile(`(?i)(?:at character |"?position"?\s*[:=]\s*"?)(\d+)`)
+)
+
+// ErrorPosition extracts where in sql a Postgres error occurred, from an
+// error message that carries either a "LINE n:" excerpt or a character
+// offset. ok is false if the message has neither.
+func ErrorPosition(sql, message string) (pos Position, ok bool) {
+	if m := charRe.FindStringSubmatch(message); m != nil {
+		if offset, err := strconv.Atoi(m[1]); err == nil && offset > 0 {
+			return offsetToPosition(sql, offset), true
+		}
+	}
+
+	if m := lineRe.FindStringSubmatch(message); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		pos = Position{Line: line}
+		// Postgres elides long lines with "..."; the caret is only
+		// meaningful when the excerpt starts at the beginning of the line
+		if !strings.HasPrefix(m[2], "...") {
+			pos.Column = len(m[3]) - len("LINE "+m[1]+": ") + 1
+			if pos.Column < 1 {
+				pos.Column = 0
+			}
+		}
+		return pos, true
+	}
+
+	if m := lineOnlyRe.FindStringSubmatch(message); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return Position{Line: line}, true
+	}
+
+	return Position{}, false
+}
+
+// offsetToPosition converts a 1-based character offset (as reported by
+// Postgres) into a line and column
+func offsetToPosition(sql string, offset int) Position {
+	pos := Position{Line: 1, Column: 1}
+	for i := 1; i < offset && len(sql) > 0; i++ {
+		r, size := utf8.DecodeRuneInString(sql)
+		sql = sql[size:]
+		if r == '\n' {
+			pos.Line++
+			pos.Column = 1
+		} else {
+			pos.Column++
+		}
+	}
+	return pos
+}
@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"testing"
+)
+
+func TestErrorPosition(t *testing.T) {
+	sql := "create table a (\n  id int,\n  name txet\n);"
+
+	tests := []struct {
+		name    string
+		message string
+		want    Position
+		ok      bool
+	}{
+		{
+			name:    "character offset",
+			message: `ERROR: type "txet" does not exist at character 35`,
+			want:    Position{Line: 3, Column: 8},
+			ok:      true,
+		},
+		{
+			name:    "json position",
+			message: `{"message": "type \"txet\" does not exist", "position": "35"}`,
+			want:    Position{Line: 3, Column: 8},
+			ok:      true,
+		},
+		{
+			name:    "line excerpt with caret",
+			message: "ERROR:  42704: type \"txet\" does not exist\nLINE 3:   name txet\n               ^",
+			want:    Position{Line: 3, Column: 8},
+			ok:      true,
+		},
+		{
+			name:    "elided line excerpt",
+			message: "syntax error\nLINE 12: ...very long line\n                 ^",
+			want:    Position{Line: 12},
+			ok:      true,
+		},
+		{
+			name:    "no position",
+			message: "permission denied for schema auth",
+			ok:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		got, ok := ErrorPosition(sql, tt.message)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("%s: expected %+v %v, got %+v %v", tt.name, tt.want, tt.ok, got, ok)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package secrets
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/supabase/supabase-dx/cli/internal/api"
+)
+
+// Plan actions
+const (
+	ActionAdd    = "add"
+	ActionUpdate = "update"
+	ActionRemove = "remove"
+)
+
+// Change is one difference between the local env file and the remote
+// secrets. Values are only ever exposed masked.
+type Change struct {
+	Name   string `json:"name"`
+	Action string `json:"action"`          // add, update, remove
+	Masked string `json:"value,omitempty"` // masked local value (add, update), see Mask
+
+	value string
+}
+
+// Plan is the set of changes that makes the remote secrets match the file
+type Plan struct {
+	File      string   `json:"file"`
+	Changes   []Change `json:"changes"`
+	Unchanged []string `json:"unchanged"`
+}
+
+// Find returns the env file to sync for a profile: the configured path
+// (relative to the supabase directory) if set, otherwise
+// supabase/.env.<profile>, falling back to supabase/.env. Returns "" if no
+// file exists.
+func Find(root, profileName, configured string) string {
+	if configured != "" {
+		p := configured
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(root, "supabase", filepath.FromSlash(path.Clean(configured)))
+		}
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+		return ""
+	}
+
+	candidates := []string{filepath.Join(root, "supabase", ".env")}
+	if profileName != "" {
+		candidates = append([]string{filepath.Join(root, "supabase", ".env."+profileName)}, candidates...)
+	}
+	for _, p := range candidates {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return ""
+}
+
+// IsReserved reports whether a secret is managed by Supabase itself
+// (SUPABASE_URL, SUPABASE_ANON_KEY, ...) and can't be set by users
+func IsReserved(name string) bool {
+	return strings.HasPrefix(name, "SUPABASE_")
+}
+
+// Digest returns the digest the Management API reports in place of a
+// secret's value
+func Digest(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// Mask hides a secret value behind a short prefix of its digest, so values
+// can still be told apart without revealing any of them
+func Mask(value string) string {
+	return "sha256:" + Digest(value)[:8]
+}
+
+// Diff compares local values with the remote secrets. Remote secrets are
+// listed with their value digests; reserved names are ignored on both sides.
+func Diff(file string, local map[string]string, remote []api.Secret) *Plan {
+	plan := &Plan{File: file, Changes: []Change{}, Unchanged: []string{}}
+
+	digests := make(map[string]string)
+	for _, s := range remote {
+		if !IsReserved(s.Name) {
+			digests[s.Name] = s.Value
+		}
+	}
+
+	names := make([]string, 0, len(local))
+	for name := range local {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if IsReserved(name) {
+			continue
+		}
+		value := local[name]
+		digest, ok := digests[name]
+		delete(digests, name)
+
+		switch {
+		case !ok:
+			plan.Changes = append(plan.Changes, Change{Name: name, Action: ActionAdd, Masked: Mask(value), value: value})
+		case !strings.EqualFold(digest, Digest(value)):
+			plan.Changes = append(plan.Changes, Change{Name: name, Action: ActionUpdate, Masked: Mask(value), value: value})
+		default:
+			plan.Unchanged = append(plan.Unchanged, name)
+		}
+	}
+
+	var removed []string
+	for name := range digests {
+		removed = append(removed, name)
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		plan.Changes = append(plan.Changes, Change{Name: name, Action: ActionRemove})
+	}
+
+	return plan
+}
+
+// Count returns the number of changes with the given action
+func (p *Plan) Count(action string) int {
+	n := 0
+	for _, c := range p.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// Pending reports whether applying the plan would change anything.
+// Removals only count when pruning.
+func (p *Plan) Pending(prune bool) bool {
+	return p.Count(ActionAdd)+p.Count(ActionUpdate) > 0 || (prune && p.Count(ActionRemove) > 0)
+}
+
+// Store is where secrets are set and deleted (see backend.Backend)
+type Store interface {
+	CreateSecrets(ctx context.Context, secrets []api.Secret) error
+	DeleteSecrets(ctx context.Context, names []string) error
+}
+
+// Apply sets added and updated secrets, and deletes remote-only secrets when
+// prune is set. Returns the number of secrets set and deleted.
+func (p *Plan) Apply(ctx context.Context, store Store, prune bool) (set, deleted int, err error) {
+	var upserts []api.Secret
+	var removals []string
+	for _, c := range p.Changes {
+		switch c.Action {
+		case ActionAdd, ActionUpdate:
+			upserts = append(upserts, api.Secret{Name: c.Name, Value: c.value})
+		case ActionRemove:
+			removals = append(removals, c.Name)
+		}
+	}
+
+	if len(upserts) > 0 {
+		if err := store.CreateSecrets(ctx, upserts); err != nil {
+			return 0, 0, err
+		}
+		set = len(upserts)
+	}
+
+	if prune && len(removals) > 0 {
+		if err := store.DeleteSecrets(ctx, removals); err != nil {
+			return set, 0, err
+		}
+		deleted = len(removals)
+	}
+
+	return set, deleted, nil
+}
@@ -0,0 +1,135 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/supabase/supabase-dx/cli/internal/api"
+)
+
+func TestDiff(t *testing.T) {
+	local := map[string]string{
+		"NEW_KEY":      "new-value",
+		"CHANGED_KEY":  "changed-value",
+		"SAME_KEY":     "same-value",
+		"SUPABASE_URL": "ignored",
+	}
+	remote := []api.Secret{
+		{Name: "CHANGED_KEY", Value: Digest("old-value")},
+		{Name: "SAME_KEY", Value: Digest("same-value")},
+		{Name: "OLD_KEY", Value: Digest("x")},
+		{Name: "SUPABASE_ANON_KEY", Value: Digest("y")},
+	}
+
+	plan := Diff("supabase/.env", local, remote)
+
+	expected := []Change{
+		{Name: "CHANGED_KEY", Action: ActionUpdate, Masked: "sha256:" + Digest("changed-value")[:8]},
+		{Name: "NEW_KEY", Action: ActionAdd, Masked: "sha256:" + Digest("new-value")[:8]},
+		{Name: "OLD_KEY", Action: ActionRemove},
+	}
+	if len(plan.Changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %+v", len(expected), plan.Changes)
+	}
+	for i, c := range expected {
+		got := plan.Changes[i]
+		if got.Name != c.Name || got.Action != c.Action || got.Masked != c.Masked {
+			t.Errorf("expected change %+v, got %+v", c, got)
+		}
+	}
+	if len(plan.Unchanged) != 1 || plan.Unchanged[0] != "SAME_KEY" {
+		t.Errorf("expected SAME_KEY unchanged, got %v", plan.Unchanged)
+	}
+
+	if !plan.Pending(false) {
+		t.Error("expected pending changes")
+	}
+
+	// Values never leak into JSON
+	out, _ := json.Marshal(plan)
+	if strings.Contains(string(out), "new") || strings.Contains(string(out), "changed-") {
+		t.Errorf("expected masked values in JSON, got %s", out)
+	}
+}
+
+func TestPendingOnlyRemovals(t *testing.T) {
+	plan := Diff("supabase/.env", map[string]string{}, []api.Secret{{Name: "OLD_KEY", Value: Digest("x")}})
+	if plan.Pending(false) {
+		t.Error("expected removals to be ignored without prune")
+	}
+	if !plan.Pending(true) {
+		t.Error("expected removals to count with prune")
+	}
+}
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "supabase"), 0755)
+
+	if got := Find(root, "staging", ""); got != "" {
+		t.Errorf("expected no file, got %s", got)
+	}
+
+	os.WriteFile(filepath.Join(root, "supabase", ".env"), []byte("A=1"), 0644)
+	if got := Find(root, "staging", ""); got != filepath.Join(root, "supabase", ".env") {
+		t.Errorf("expected fallback to .env, got %s", got)
+	}
+
+	os.WriteFile(filepath.Join(root, "supabase", ".env.staging"), []byte("A=1"), 0644)
+	if got := Find(root, "staging", ""); got != filepath.Join(root, "supabase", ".env.staging") {
+		t.Errorf("expected profile env file, got %s", got)
+	}
+
+	os.WriteFile(filepath.Join(root, "secrets.env"), []byte("A=1"), 0644)
+	if got := Find(root, "staging", "../secrets.env"); got != filepath.Join(root, "secrets.env") {
+		t.Errorf("expected configured path, got %s", got)
+	}
+	if got := Find(root, "staging", "missing.env"); got != "" {
+		t.Errorf("expected missing configured file to return empty, got %s", got)
+	}
+}
+
+// recordingStore records the secrets it's asked to set and delete
+type recordingStore struct {
+	created []api.Secret
+	deleted []string
+}
+
+func (s *recordingStore) CreateSecrets(ctx context.Context, secrets []api.Secret) error {
+	s.created = append(s.created, secrets...)
+	return nil
+}
+
+func (s *recordingStore) DeleteSecrets(ctx context.Context, names []string) error {
+	s.deleted = append(s.deleted, names...)
+	return nil
+}
+
+func TestApply(t *testing.T) {
+	store := &recordingStore{}
+
+	plan := Diff("supabase/.env", map[string]string{"NEW_KEY": "v"}, []api.Secret{{Name: "OLD_KEY", Value: Digest("x")}})
+
+	set, removed, err := plan.Apply(context.Background(), store, false)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if set != 1 || removed != 0 || store.deleted != nil {
+		t.Errorf("expected 1 set and no deletes without prune, got %d %d %v", set, removed, store.deleted)
+	}
+	if len(store.created) != 1 || store.created[0].Name != "NEW_KEY" || store.created[0].Value != "v" {
+		t.Errorf("expected NEW_KEY to be created with its real value, got %+v", store.created)
+	}
+
+	_, removed, err = plan.Apply(context.Background(), store, true)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if removed != 1 || len(store.deleted) != 1 || store.deleted[0] != "OLD_KEY" {
+		t.Errorf("expected OLD_KEY to be pruned, got %d %v", removed, store.deleted)
+	}
+}
@@ -269,6 +269,45 @@ func NewBranchesTable(data [][]string) table.Model {
 	return t
 }
 
+// TableModel shows a table until the user quits. Selected returns the row
+// under the cursor when the user pressed enter.
+type TableModel struct {
+	table    table.Model
+	selected table.Row
+}
+
+func NewTable(t table.Model) TableModel {
+	return TableModel{table: t}
+}
+
+func (m TableModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m TableModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "ctrl+c", "q", "esc":
+			return m, tea.Quit
+		case "enter":
+			m.selected = m.table.SelectedRow()
+			return m, tea.Quit
+		}
+	}
+
+	var cmd tea.Cmd
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+func (m TableModel) View() string {
+	return tableStyle.Render(m.table.View()) + "\n" + dimStyle.Render("↑/↓ to move, q to quit") + "\n"
+}
+
+func (m TableModel) Selected() table.Row {
+	return m.selected
+}
+
 // =============================================================================
 // Progress Model
 // =============================================================================
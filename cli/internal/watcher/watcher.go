@@ -0,0 +1,319 @@
+package watcher
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/supabase/supabase-dx/cli/internal/declarative"
+	"github.com/supabase/supabase-dx/cli/internal/functions"
+	"github.com/supabase/supabase-dx/cli/internal/migrations"
+)
+
+// DefaultDebounce is how long the project has to be quiet before changes
+// are reported. Editors and git write several files, or the same file
+// several times, for one save or checkout.
+const DefaultDebounce = 300 * time.Millisecond
+
+// Kind is what a change in the project affects
+type Kind string
+
+const (
+	Head       Kind = "head"       // .git/HEAD: the branch may have changed
+	Migrations Kind = "migrations" // supabase/migrations
+	Schemas    Kind = "schemas"    // supabase/schemas
+	Functions  Kind = "functions"  // supabase/functions
+)
+
+// order is the order the changes of one batch are reported in: a new
+// branch can mean a new target for everything after it
+var order = []Kind{Head, Migrations, Schemas, Functions}
+
+// dirs are the watched trees and what a change in them affects
+var dirs = []struct {
+	path string
+	kind Kind
+}{
+	{migrations.Dir, Migrations},
+	{declarative.Dir, Schemas},
+	{functions.Dir, Functions},
+}
+
+// Change is the changes of one kind since the project was last quiet
+type Change struct {
+	Kind  Kind
+	Paths []string // relative to the project root, sorted
+}
+
+// Slugs returns the functions among slugs that a Functions change touches
+func (c Change) Slugs(slugs []string) []string {
+	touched := make(map[string]bool)
+	for _, p := range c.Paths {
+		rel, err := filepath.Rel(functions.Dir, p)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		first := strings.SplitN(filepath.ToSlash(rel), "/", 2)[0]
+		touched[first] = true
+	}
+
+	// Anything that isn't a function directory - _shared, an import map -
+	// is bundled with every function
+	for name := range touched {
+		if !contains(slugs, name) {
+			return slugs
+		}
+	}
+
+	var result []string
+	for _, slug := range slugs {
+		if touched[slug] {
+			result = append(result, slug)
+		}
+	}
+	return result
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// classify returns the kind of change a path relative to the project root
+// belongs to. Hidden files and editor backups are ignored.
+func classify(rel string) (Kind, bool) {
+	base := filepath.Base(rel)
+	if strings.HasPrefix(base, ".") || strings.HasSuffix(base, "~") || strings.HasSuffix(base, ".swp") {
+		return "", false
+	}
+	for _, dir := range dirs {
+		if rel == dir.path || strings.HasPrefix(rel, dir.path+string(filepath.Separator)) {
+			return dir.kind, true
+		}
+	}
+	return "", false
+}
+
+// Watcher reports debounced changes to a project's git HEAD, migrations,
+// schema files and functions. Directories are watched recursively,
+// including ones created later.
+type Watcher struct {
+	// Changes receives the changes of each kind once the project has been
+	// quiet for the debounce period. It's closed by Close.
+	Changes <-chan Change
+	// Errors receives errors from the underlying watcher
+	Errors <-chan error
+
+	root     string
+	head     string // absolute path of HEAD, which may be outside root in a worktree
+	debounce time.Duration
+	fs       *fsnotify.Watcher
+	changes  chan Change
+	errors   chan error
+}
+
+// New starts watching the project at root. HEAD isn't watched outside a
+// git repository, and missing supabase directories are picked up when
+// they're created.
+func New(root string, debounce time.Duration) (*Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		root:     root,
+		debounce: debounce,
+		fs:       fsw,
+		changes:  make(chan Change),
+		errors:   make(chan error, 1),
+	}
+	w.Changes = w.changes
+	w.Errors = w.errors
+
+	// git replaces HEAD by renaming HEAD.lock over it, which ends a watch
+	// on the file itself, so watch the directory it's in
+	if dir := gitDir(root); dir != "" {
+		if err := fsw.Add(dir); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			fsw.Close()
+			return nil, err
+		}
+		w.head = filepath.Join(dir, "HEAD")
+	}
+
+	// The supabase directory itself tells us when a watched tree appears
+	if err := fsw.Add(filepath.Join(root, "supabase")); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		fsw.Close()
+		return nil, err
+	}
+	for _, dir := range dirs {
+		if err := w.addTree(filepath.Join(root, dir.path)); err != nil {
+			fsw.Close()
+			return nil, err
+		}
+	}
+
+	go w.run()
+	return w, nil
+}
+
+// Close stops watching
+func (w *Watcher) Close() error {
+	return w.fs.Close()
+}
+
+// gitDir returns the git directory of the repository rooted at root, or ""
+// if there is none. In a worktree .git is a file pointing to it.
+func gitDir(root string) string {
+	dotGit := filepath.Join(root, ".git")
+	info, err := os.Stat(dotGit)
+	if err != nil {
+		return ""
+	}
+	if info.IsDir() {
+		return dotGit
+	}
+
+	content, err := os.ReadFile(dotGit)
+	if err != nil {
+		return ""
+	}
+	dir, ok := strings.CutPrefix(strings.TrimSpace(string(content)), "gitdir: ")
+	if !ok {
+		return ""
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(root, dir)
+	}
+	return dir
+}
+
+// addTree watches a directory and everything below it. A missing directory
+// isn't an error.
+func (w *Watcher) addTree(dir string) error {
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		return w.fs.Add(path)
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (w *Watcher) run() {
+	defer close(w.changes)
+
+	pending := make(map[Kind]map[string]bool)
+	var ready []Change
+
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+
+	for {
+		// Only offer a change when there's one ready
+		var out chan Change
+		var next Change
+		if len(ready) > 0 {
+			out = w.changes
+			next = ready[0]
+		}
+
+		select {
+		case event, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			kind, rel, ok := w.event(event)
+			if !ok {
+				continue
+			}
+			if pending[kind] == nil {
+				pending[kind] = make(map[string]bool)
+			}
+			pending[kind][rel] = true
+			timer.Reset(w.debounce)
+
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return
+			}
+			select {
+			case w.errors <- err:
+			default:
+				// Still unread - one is enough to report
+			}
+
+		case <-timer.C:
+			for _, kind := range order {
+				paths := pending[kind]
+				if len(paths) == 0 {
+					continue
+				}
+				change := Change{Kind: kind}
+				for p := range paths {
+					change.Paths = append(change.Paths, p)
+				}
+				sort.Strings(change.Paths)
+				ready = append(ready, change)
+			}
+			pending = make(map[Kind]map[string]bool)
+
+		case out <- next:
+			ready = ready[1:]
+		}
+	}
+}
+
+// event classifies a filesystem event, watching directories as they're
+// created
+func (w *Watcher) event(event fsnotify.Event) (Kind, string, bool) {
+	// Touching or re-permissioning a file changes nothing we act on
+	if event.Op == fsnotify.Chmod {
+		return "", "", false
+	}
+	if event.Name == w.head {
+		return Head, filepath.Join(".git", "HEAD"), true
+	}
+
+	rel, err := filepath.Rel(w.root, event.Name)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", "", false
+	}
+	kind, ok := classify(rel)
+	if !ok {
+		return "", "", false
+	}
+
+	if event.Has(fsnotify.Create) {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			// Files written before the watch was added are reported with
+			// the directory
+			if err := w.addTree(event.Name); err != nil {
+				select {
+				case w.errors <- err:
+				default:
+				}
+			}
+		}
+	}
+	// Removed directories drop out of the watch list on their own
+	return kind, rel, true
+}
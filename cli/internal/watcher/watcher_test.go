@@ -0,0 +1,124 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		path string
+		kind Kind
+		ok   bool
+	}{
+		{"supabase/migrations/20240101000000_init.sql", Migrations, true},
+		{"supabase/schemas/tables/todos.sql", Schemas, true},
+		{"supabase/functions/hello/index.ts", Functions, true},
+		{"supabase/functions", Functions, true},
+		{"supabase/functions/hello/.index.ts.swp", "", false},
+		{"supabase/functions/hello/index.ts~", "", false},
+		{"supabase/config.toml", "", false},
+		{"supabase/migrations_old/x.sql", "", false},
+		{"src/app.ts", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			kind, ok := classify(filepath.FromSlash(tt.path))
+			if kind != tt.kind || ok != tt.ok {
+				t.Errorf("classify(%s) = %q, %v, expected %q, %v", tt.path, kind, ok, tt.kind, tt.ok)
+			}
+		})
+	}
+}
+
+func TestSlugs(t *testing.T) {
+	slugs := []string{"hello", "webhook"}
+	change := func(paths ...string) Change {
+		for i, p := range paths {
+			paths[i] = filepath.FromSlash(p)
+		}
+		return Change{Kind: Functions, Paths: paths}
+	}
+
+	if got := change("supabase/functions/hello/index.ts").Slugs(slugs); strings.Join(got, ",") != "hello" {
+		t.Errorf("expected [hello], got %v", got)
+	}
+	if got := change("supabase/functions/_shared/cors.ts").Slugs(slugs); strings.Join(got, ",") != "hello,webhook" {
+		t.Errorf("expected every function for a _shared change, got %v", got)
+	}
+	if got := change("supabase/functions/import_map.json").Slugs(slugs); len(got) != 2 {
+		t.Errorf("expected every function for an import map change, got %v", got)
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "supabase", "migrations"), 0755); err != nil {
+		t.Fatalf("failed to create migrations dir: %v", err)
+	}
+
+	w, err := New(root, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer w.Close()
+
+	next := func() Change {
+		select {
+		case change := <-w.Changes:
+			return change
+		case err := <-w.Errors:
+			t.Fatalf("expected no error, got %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a change")
+		}
+		return Change{}
+	}
+
+	// Several writes in quick succession are reported once
+	for _, name := range []string{"20240101000000_a.sql", "20240102000000_b.sql"} {
+		if err := os.WriteFile(filepath.Join(root, "supabase", "migrations", name), []byte("select 1;"), 0644); err != nil {
+			t.Fatalf("failed to write migration: %v", err)
+		}
+	}
+	change := next()
+	if change.Kind != Migrations || len(change.Paths) != 2 {
+		t.Errorf("expected one migrations change with 2 paths, got %+v", change)
+	}
+
+	// Trees created after the watch started are picked up
+	fnDir := filepath.Join(root, "supabase", "functions", "hello")
+	if err := os.MkdirAll(fnDir, 0755); err != nil {
+		t.Fatalf("failed to create function dir: %v", err)
+	}
+	if change := next(); change.Kind != Functions {
+		t.Errorf("expected a functions change, got %+v", change)
+	}
+	if err := os.WriteFile(filepath.Join(fnDir, "index.ts"), []byte("export {}"), 0644); err != nil {
+		t.Fatalf("failed to write function: %v", err)
+	}
+	change = next()
+	if change.Kind != Functions || strings.Join(change.Slugs([]string{"hello"}), ",") != "hello" {
+		t.Errorf("expected a change to hello, got %+v", change)
+	}
+}
+
+func TestGitDir(t *testing.T) {
+	root := t.TempDir()
+	if dir := gitDir(root); dir != "" {
+		t.Errorf("expected no git dir, got %s", dir)
+	}
+
+	// Worktrees point to their git dir
+	if err := os.WriteFile(filepath.Join(root, ".git"), []byte("gitdir: ../main/.git/worktrees/feature\n"), 0644); err != nil {
+		t.Fatalf("failed to write .git: %v", err)
+	}
+	expected := filepath.Join(filepath.Dir(root), "main", ".git", "worktrees", "feature")
+	if dir := gitDir(root); dir != expected {
+		t.Errorf("expected %s, got %s", expected, dir)
+	}
+}
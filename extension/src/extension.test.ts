@@ -164,24 +164,32 @@ describe("Extension", () => {
   describe("Watch Events", () => {
     it("should parse types_updated event", () => {
       const event = {
-        event: "types_updated",
-        path: "/project/supabase/types/database.ts",
+        v: 1,
+        type: "types_updated",
+        ts: "2024-01-02T03:04:05Z",
+        seq: 3,
+        payload: { path: "/project/supabase/types/database.ts" },
       };
 
-      expect(event.event).toBe("types_updated");
-      expect(event.path).toContain("database.ts");
+      expect(event.type).toBe("types_updated");
+      expect(event.payload.path).toContain("database.ts");
     });
 
     it("should parse profile_changed event", () => {
       const event = {
-        event: "profile_changed",
-        branch: "feature/auth",
-        profile: "local",
-        project_ref: "abcdefghijklmnopqrst",
+        v: 1,
+        type: "profile_changed",
+        ts: "2024-01-02T03:04:05Z",
+        seq: 4,
+        payload: {
+          branch: "feature/auth",
+          profile: "local",
+          project_ref: "abcdefghijklmnopqrst",
+        },
       };
 
-      expect(event.event).toBe("profile_changed");
-      expect(event.profile).toBe("local");
+      expect(event.type).toBe("profile_changed");
+      expect(event.payload.profile).toBe("local");
     });
   });
 });
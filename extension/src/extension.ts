@@ -246,21 +246,31 @@ function stopWatch(): void {
   }
 }
 
+// Watch events follow cli/events-schema/events.schema.json:
+// { v, type, ts, seq, payload }, one per line
 function handleWatchEvent(event: any): void {
   outputChannel.appendLine(JSON.stringify(event));
 
-  switch (event.event) {
+  const payload = event.payload ?? {};
+  switch (event.type) {
     case "types_updated":
       vscode.window.showInformationMessage("TypeScript types updated");
       break;
     case "profile_changed":
       vscode.window.showInformationMessage(
-        `Switched to profile: ${event.profile}`,
+        `Switched to profile: ${payload.profile}`,
       );
       updateStatus();
       break;
     case "branch_changed":
-      statusBarItem.text = `$(git-branch) ${event.branch}`;
+      statusBarItem.text = `$(git-branch) ${payload.branch}`;
+      break;
+    case "auto_push":
+      if (payload.status !== "success") {
+        vscode.window.showWarningMessage(
+          `Auto-push ${payload.status}: ${payload.message}`,
+        );
+      }
       break;
   }
 }